@@ -0,0 +1,409 @@
+package main
+
+import (
+	"encoding/json" // json.Unmarshal()
+	"fmt"           // Printf()
+	"io/ioutil"     // ioutil.ReadAll()
+	"net"           // net.Dial()
+	"os"            // os.Stderr
+	"os/signal"     // signal.Stop()
+	"runtime"       // runtime.Version()
+	"sort"          // sort.Strings()
+	"strings"       // strings.Join()
+	"time"          // time.Duration
+
+	"github.com/spf13/pflag"
+	"k8s.io/klog"
+
+	"github.com/openshift/openshift-tuned/pkg/process"
+	"github.com/openshift/openshift-tuned/pkg/tuned"
+)
+
+// Types
+// command is an openshift-tuned subcommand.
+type command struct {
+	name string
+	// arguments synopsis
+	args string
+	// one-line description
+	short string
+	// flags adds the options of the command to its flag set
+	flags func(fs *pflag.FlagSet)
+	run   func(fs *pflag.FlagSet) int
+}
+
+// Global variables
+var (
+	commands []command
+)
+
+// Functions
+func init() {
+	// Initialized here to break the commands -> completionCmd -> commands initialization loop
+	commands = []command{
+		{"run", "[options] [NODE]", "run and reload tuned on the node (default)", flagsRun, runCmd},
+		{"wait", "[--for STATE] [--timeout DURATION]", "wait for the running " + programName + " to reach a state, e.g. Stable", waitFlags, waitCmd},
+		{"status", "[--timeout DURATION]", "print the status of the running " + programName, statusFlags, statusCmd},
+		{"stop", "[--timeout DURATION] [--no-rollback]", "stop tuned and roll back the node-level tuning", stopFlags, stopCmd},
+		{"recommend", "[--explain]", "print the profile recommended by the recommend.d rules", recommendFlags, recommendCmd},
+		{"lint", "<file|dir|configmap.yaml>", "check tuned profiles like the extraction does, e.g. in CI", lintFlags, lintCmd},
+		{"render", "--profiles FILE [--labels FILE] [--profile NAME] [-o DIR]", "print the profiles and recommendation an extraction would result in", renderFlags, renderCmd},
+		{"effective", "[-o json|yaml] [PROFILE]", "print the effective settings of a tuned profile (default: the active one)", effectiveFlags, effectiveCmd},
+		{"must-gather", "[-o FILE] [--timeout DURATION]", "collect the state of " + programName + " and tuned into a tarball for support cases", mustGatherFlags, mustGatherCmd},
+		{"version", "", "print the " + programName + " and tuned versions", flagsExec, versionCmd},
+		{"completion", "bash", "print a shell completion script", nil, completionCmd},
+	}
+}
+
+func commandLookup(name string) *command {
+	for i := range commands {
+		if commands[i].name == name {
+			return &commands[i]
+		}
+	}
+	return nil
+}
+
+// commandFlagSet returns the flag set with the options of command c.
+func commandFlagSet(c *command) *pflag.FlagSet {
+	fs := pflag.NewFlagSet(c.name, pflag.ContinueOnError)
+	flagsLog(fs)
+	if c.flags != nil {
+		c.flags(fs)
+	}
+	fs.Usage = func() {
+		if c.name == "run" {
+			fmt.Fprintf(os.Stderr, "Usage: %s [command] [options] [args]\n", programName)
+			fmt.Fprintf(os.Stderr, "Example: %s run b1.lan\n\n", programName)
+			commandsUsage()
+			fmt.Fprintf(os.Stderr, "Options of run, see \"%s COMMAND --help\" for the options of the other commands:\n", programName)
+			fmt.Fprintf(os.Stderr, "%s", fs.FlagUsages())
+			exitCodesUsage()
+			return
+		}
+		fmt.Fprintf(os.Stderr, "Usage: %s %s\n", programName, strings.TrimSpace(c.name+" "+c.args))
+		fmt.Fprintf(os.Stderr, "%s\n\nOptions:\n%s", c.short, fs.FlagUsages())
+	}
+	return fs
+}
+
+// argsNormalize turns the long options of fs given with a single dash, e.g.
+// -watch-file, into the --watch-file form pflag expects.  Single-letter
+// options, e.g. -v, and the arguments after -- are left as they are.
+func argsNormalize(fs *pflag.FlagSet, args []string) []string {
+	normalized := make([]string, 0, len(args))
+	for i, arg := range args {
+		if arg == "--" {
+			return append(normalized, args[i:]...)
+		}
+		if len(arg) > 2 && arg[0] == '-' && arg[1] != '-' {
+			name := strings.SplitN(arg[1:], "=", 2)[0]
+			if len(name) > 1 && fs.Lookup(name) != nil {
+				arg = "-" + arg
+			}
+		}
+		normalized = append(normalized, arg)
+	}
+	return normalized
+}
+
+// commandsUsage prints the list of subcommands.
+func commandsUsage() {
+	fmt.Fprintf(os.Stderr, "Commands:\n")
+	for _, c := range commands {
+		fmt.Fprintf(os.Stderr, "  %-32s %s\n", strings.TrimSpace(c.name+" "+c.args), c.short)
+	}
+	fmt.Fprintf(os.Stderr, "\n")
+}
+
+// exitCodesUsage prints the exit codes of the "run" subcommand.
+func exitCodesUsage() {
+	fmt.Fprintf(os.Stderr, "\nExit codes:\n")
+	for _, ec := range []struct {
+		code int
+		desc string
+	}{
+		{tuned.ExitOK, "terminated on request"},
+		{tuned.ExitFailure, "an unexpected error"},
+		{tuned.ExitConfig, "invalid options, configuration file, kubeconfig or node name"},
+		{tuned.ExitAPIUnreachable, "the apiserver could not be reached"},
+		{tuned.ExitTunedMissing, "the tuned binaries are missing"},
+		{tuned.ExitRunDir, "the runtime files could not be written"},
+		{tuned.ExitCapabilities, "required capabilities are missing"},
+	} {
+		fmt.Fprintf(os.Stderr, "  %d  %s\n", ec.code, ec.desc)
+	}
+}
+
+// commandRun runs the subcommand in args[0] with the options following it.
+// Without a subcommand, the daemon is run; its options may precede "run" then,
+// e.g. "-v 2 run NODE".
+func commandRun(args []string) int {
+	c := commandLookup("run")
+	if len(args) > 0 {
+		if sub := commandLookup(args[0]); sub != nil {
+			c, args = sub, args[1:]
+		}
+	}
+	fs := commandFlagSet(c)
+	if err := fs.Parse(argsNormalize(fs, args)); err != nil {
+		if err == pflag.ErrHelp {
+			return tuned.ExitOK
+		}
+		fmt.Fprintf(os.Stderr, "%s\nSee %s %s --help.\n", err.Error(), programName, c.name)
+		return tuned.ExitConfig
+	}
+	return c.run(fs)
+}
+
+// nodeNameGet returns the name of the node to manage: the positional NODE argument,
+// the --node-name option or the NODE_NAME environment variable, in this order.
+func nodeNameGet(args []string) (string, error) {
+	if len(args) > 1 {
+		return "", fmt.Errorf("too many arguments: %s", strings.Join(args, " "))
+	}
+	if len(args) == 1 {
+		return args[0], nil
+	}
+	if len(nodeName) == 0 {
+		return "", fmt.Errorf("node name not specified, use --node-name or the NODE_NAME environment variable")
+	}
+	return nodeName, nil
+}
+
+// runCmd implements the "run" subcommand.
+func runCmd(fs *pflag.FlagSet) int {
+	args := fs.Args()
+	if len(args) > 0 && args[0] == "run" {
+		args = args[1:]
+	} else if len(args) > 0 && commandLookup(args[0]) != nil {
+		fmt.Fprintf(os.Stderr, "the options of %s %s must follow the command, see %s %s --help\n", programName, args[0], programName, args[0])
+		return tuned.ExitConfig
+	}
+	if boolVersion {
+		fmt.Fprintf(os.Stderr, "%s %s\n", programName, version)
+		return tuned.ExitOK
+	}
+
+	daemonFlags = fs
+	if err := configInit(); err != nil {
+		klog.Errorf("%s", err.Error())
+		return tuned.ExitConfig
+	}
+	opts.Flags = map[string]string{}
+	fs.VisitAll(func(f *pflag.Flag) {
+		opts.Flags[f.Name] = f.Value.String()
+	})
+	opts.ConfigFile = configFile
+	opts.OnConfigChange = configReload
+	opts.OnOperandConfigChange = operandConfigReload
+
+	name, err := nodeNameGet(args)
+	if err != nil && opts.Standalone && len(args) == 0 {
+		// The node name only labels logs and the status without the API
+		name, err = os.Hostname()
+	}
+	if err != nil {
+		klog.Errorf("%s", err.Error())
+		fs.Usage()
+		return tuned.ExitConfig
+	}
+	opts.NodeName = name
+
+	if boolCheckCapabilities {
+		if err := tuned.CapabilitiesCheck(); err != nil {
+			klog.Errorf("%s", err.Error())
+			return tuned.ExitCapabilities
+		}
+		if err := tuned.CapabilitiesDrop(); err != nil {
+			// Not fatal, openshift-tuned just runs with more privileges than needed
+			klog.Warningf("%s", err.Error())
+		}
+	}
+
+	operandConfigInit()
+	c := tuned.New(opts)
+
+	ctx, sigs := signalHandler()
+	err = c.Run(ctx)
+	signal.Stop(sigs)
+	if err != nil {
+		klog.Errorf("terminating: %s", err.Error())
+		return tuned.ExitCode(err)
+	}
+	return tuned.ExitOK
+}
+
+// sockRequest sends command to the control socket of the running openshift-tuned
+// and returns its response.
+func sockRequest(command string, timeout time.Duration) (string, error) {
+	response, err := sockRequestRaw(command, timeout)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(response)), nil
+}
+
+// sockRequestRaw sends command via the control socket and returns the response as is.
+func sockRequestRaw(command string, timeout time.Duration) ([]byte, error) {
+	conn, err := net.DialTimeout("unix", opts.Socket, timeout)
+	if err != nil {
+		return nil, fmt.Errorf("cannot connect to %q: %v", opts.Socket, err)
+	}
+	defer conn.Close()
+
+	if err = conn.SetDeadline(time.Now().Add(timeout)); err != nil {
+		return nil, err
+	}
+	if _, err = conn.Write([]byte(command)); err != nil {
+		return nil, fmt.Errorf("cannot send %q via %q: %v", command, opts.Socket, err)
+	}
+	response, err := ioutil.ReadAll(conn)
+	if err != nil && len(response) == 0 {
+		return nil, fmt.Errorf("no response to %q via %q: %v", command, opts.Socket, err)
+	}
+	return response, nil
+}
+
+// statusFlags adds the options of the "status" subcommand to fs.
+func statusFlags(fs *pflag.FlagSet) {
+	flagsSocket(fs)
+	fs.Duration("timeout", 10*time.Second, "time to wait for a response")
+}
+
+// statusCmd implements the "status" subcommand.
+func statusCmd(fs *pflag.FlagSet) int {
+	timeout, _ := fs.GetDuration("timeout")
+
+	response, err := sockRequest("status", timeout)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err.Error())
+		return 1
+	}
+	fmt.Println(response)
+	return 0
+}
+
+// stopFlags adds the options of the "stop" subcommand to fs.
+func stopFlags(fs *pflag.FlagSet) {
+	flagsSocket(fs)
+	fs.Duration("timeout", 10*time.Second, "time to wait for tuned to stop and roll back the tuning")
+	fs.Bool("no-rollback", false, "leave the node-level tuning in place")
+}
+
+// stopCmd implements the "stop" subcommand.
+func stopCmd(fs *pflag.FlagSet) int {
+	timeout, _ := fs.GetDuration("timeout")
+	noRollback, _ := fs.GetBool("no-rollback")
+
+	command, ack := "stop", tuned.SockAckStop
+	if noRollback {
+		command, ack = "stop-norollback", tuned.SockAckStopNoRollback
+	}
+	response, err := sockRequest(command, timeout)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err.Error())
+		return 1
+	}
+	if response != ack {
+		fmt.Fprintf(os.Stderr, "%s stop response: %s\n", programName, response)
+		return 1
+	}
+	return 0
+}
+
+// waitFlags adds the options of the "wait" subcommand to fs.
+func waitFlags(fs *pflag.FlagSet) {
+	flagsSocket(fs)
+	fs.String("for", "Stable", "state to wait for, e.g. Stable, Degraded or Applying")
+	fs.Duration("timeout", 5*time.Minute, "time to wait for the state")
+	fs.Duration("interval", 2*time.Second, "period of checking the state")
+}
+
+// waitCmd implements the "wait" subcommand.  The daemon may still be starting,
+// so a control socket which cannot be reached yet is waited for as well.
+func waitCmd(fs *pflag.FlagSet) int {
+	state, _ := fs.GetString("for")
+	timeout, _ := fs.GetDuration("timeout")
+	interval, _ := fs.GetDuration("interval")
+	if fs.NArg() > 0 {
+		fs.Usage()
+		return tuned.ExitConfig
+	}
+
+	deadline := time.Now().Add(timeout)
+	for {
+		var status struct {
+			State string `json:"state"`
+		}
+		response, err := sockRequest("status", 10*time.Second)
+		if err == nil {
+			err = json.Unmarshal([]byte(response), &status)
+		}
+		if err == nil && status.State == state {
+			return 0
+		}
+		if time.Now().Add(interval).After(deadline) {
+			last := status.State
+			if err != nil {
+				last = err.Error()
+			}
+			fmt.Fprintf(os.Stderr, "%s did not reach state %s within %v, last: %s\n", programName, state, timeout, last)
+			return 1
+		}
+		time.Sleep(interval)
+	}
+}
+
+// versionCmd implements the "version" subcommand.
+func versionCmd(fs *pflag.FlagSet) int {
+	fmt.Printf("%s %s\n", programName, version)
+	if len(gitCommit) > 0 {
+		fmt.Printf("git commit %s\n", gitCommit)
+	}
+	fmt.Printf("go %s\n", runtime.Version())
+	if v, err := process.TunedVersion(opts.ExecTimeout); err == nil {
+		fmt.Printf("tuned %s\n", v)
+	}
+	return 0
+}
+
+// completionCmd implements the "completion" subcommand.  The commands are
+// completed first, then the options of the command given.
+func completionCmd(fs *pflag.FlagSet) int {
+	var names []string
+
+	if fs.NArg() != 1 || fs.Arg(0) != "bash" {
+		fs.Usage()
+		return tuned.ExitConfig
+	}
+	for _, c := range commands {
+		names = append(names, c.name)
+	}
+	options := func(c *command) string {
+		var options []string
+		commandFlagSet(c).VisitAll(func(f *pflag.Flag) {
+			options = append(options, "--"+f.Name)
+		})
+		sort.Strings(options)
+		return strings.Join(options, " ")
+	}
+
+	fn := "_" + strings.Replace(programName, "-", "_", -1)
+	fmt.Printf("%s() {\n", fn)
+	fmt.Printf("  local cur=${COMP_WORDS[COMP_CWORD]} words\n")
+	fmt.Printf("  case ${COMP_WORDS[1]} in\n")
+	for i := range commands {
+		fmt.Printf("  %s) words=%q ;;\n", commands[i].name, options(&commands[i]))
+	}
+	// Without a command, the options are those of run
+	fmt.Printf("  *) words=%q ;;\n", options(commandLookup("run")))
+	fmt.Printf("  esac\n")
+	fmt.Printf("  if [ $COMP_CWORD -eq 1 ]; then\n")
+	fmt.Printf("    words=\"%s $words\"\n", strings.Join(names, " "))
+	fmt.Printf("  fi\n")
+	fmt.Printf("  COMPREPLY=( $(compgen -W \"$words\" -- \"$cur\") )\n")
+	fmt.Printf("}\n")
+	fmt.Printf("complete -F %s %s\n", fn, programName)
+	return 0
+}
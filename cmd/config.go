@@ -0,0 +1,260 @@
+package main
+
+import (
+	"fmt"       // Errorf()
+	"io/ioutil" // ioutil.ReadFile()
+	"reflect"   // DeepEqual()
+	"strings"   // strings.Split()
+
+	"github.com/spf13/pflag"
+	"gopkg.in/yaml.v2"
+	"k8s.io/klog"
+
+	"github.com/openshift/openshift-tuned/pkg/tuned"
+)
+
+// Constants
+const (
+	configFileUsage = "YAML configuration file with option names as keys, e.g. /etc/openshift-tuned/config.yaml; it is watched and changes of --v, --vmodule, --feature-gates, --attach-interval, --watch-file, --watch-quiescence, --profile-source, --configmap, --tuned-profiles-configmap, --reload-verify-timeout, --reload-failures-max, --revert-delay and --partial-reload take effect without a restart"
+)
+
+// Global variables
+var (
+	// daemonFlags are the options of the "run" command the configuration sets
+	daemonFlags *pflag.FlagSet
+	// configCmdline holds the flags set on the command line; these take precedence
+	// over the configuration file
+	configCmdline = map[string]bool{}
+	// configApplied is the configuration file content last applied
+	configApplied map[string]interface{}
+	// configOperandApplied is the OperandConfig content last applied
+	configOperandApplied map[string]interface{}
+	// configHotReloadable lists the options that take effect without a restart:
+	// the klog ones and those tuned.Controller applies on its event loop, see
+	// tuned.Options.OnConfigChange.  The others need a restart, as they are read by other
+	// goroutines (e.g. retry-*, exec-timeout, notify, hook), set up the node or
+	// the process once (e.g. the directories, socket, api-port, kubeconfig,
+	// standalone, handoff, audit-log, tuned-*) or select the operand config
+	// itself (operand-config).  Keep the --config help in sync.
+	configHotReloadable = map[string]bool{
+		"v":                        true,
+		"vmodule":                  true,
+		"feature-gates":            true,
+		"attach-interval":          true,
+		"watch-file":               true,
+		"watch-quiescence":         true,
+		"profile-source":           true,
+		"configmap":                true,
+		"tuned-profiles-configmap": true,
+		"reload-verify-timeout":    true,
+		"reload-failures-max":      true,
+		"revert-delay":             true,
+		"partial-reload":           true,
+	}
+	// Flags, see flagsRun()
+	configFile string
+)
+
+// Functions
+// configLoad reads the configuration file path.  The keys are names of the
+// command-line options, the values are scalars or lists for repeatable options.
+func configLoad(path string) (map[string]interface{}, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read configuration file %q: %v", path, err)
+	}
+	return configParse(data, fmt.Sprintf("configuration file %q", path))
+}
+
+// configParse parses configuration data read from origin.
+func configParse(data []byte, origin string) (map[string]interface{}, error) {
+	cfg := map[string]interface{}{}
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %v", origin, err)
+	}
+	for name := range cfg {
+		if daemonFlags.Lookup(name) == nil {
+			return nil, fmt.Errorf("unknown option %q in %s", name, origin)
+		}
+	}
+	return cfg, nil
+}
+
+// configOptionSet sets option name to value; a list replaces the values of a
+// repeatable option.
+func configOptionSet(name string, value interface{}) error {
+	if values, ok := value.([]interface{}); ok {
+		if a, ok := daemonFlags.Lookup(name).Value.(*arrayFlags); ok {
+			*a = nil
+		}
+		for _, v := range values {
+			if err := daemonFlags.Set(name, fmt.Sprint(v)); err != nil {
+				return fmt.Errorf("failed to set option %q to %q: %v", name, v, err)
+			}
+		}
+		return nil
+	}
+	if err := daemonFlags.Set(name, fmt.Sprint(value)); err != nil {
+		return fmt.Errorf("failed to set option %q to %q: %v", name, value, err)
+	}
+	return nil
+}
+
+// configOptionReset sets option name back to its default.
+func configOptionReset(name string) error {
+	f := daemonFlags.Lookup(name)
+	if a, ok := f.Value.(*arrayFlags); ok {
+		*a = nil
+		if len(f.DefValue) == 0 {
+			return nil
+		}
+		values := []interface{}{}
+		for _, v := range strings.Split(f.DefValue, ",") {
+			values = append(values, v)
+		}
+		return configOptionSet(name, values)
+	}
+	return configOptionSet(name, f.DefValue)
+}
+
+// configInit applies the configuration file at startup.  Options set on the
+// command line are left untouched.
+func configInit() error {
+	if len(configFile) == 0 {
+		return nil
+	}
+	daemonFlags.Visit(func(f *pflag.Flag) {
+		configCmdline[f.Name] = true
+	})
+
+	cfg, err := configLoad(configFile)
+	if err != nil {
+		return err
+	}
+	for name, value := range cfg {
+		if configCmdline[name] {
+			continue
+		}
+		if err = configOptionSet(name, value); err != nil {
+			return err
+		}
+	}
+	configApplied = cfg
+
+	return nil
+}
+
+// configReload re-reads the configuration file and applies the options which
+// changed and do not require a restart.  It returns the options for the
+// tuned.Controller to apply, see Options.OnConfigChange.
+func configReload() tuned.Options {
+	cfg, err := configLoad(configFile)
+	if err != nil {
+		klog.Errorf("%s", err.Error())
+		return opts
+	}
+	if reflect.DeepEqual(cfg, configApplied) {
+		return opts
+	}
+	klog.Infof("configuration file %q changed", configFile)
+
+	configChangesApply(cfg, configApplied, configCmdline)
+	configApplied = cfg
+	return opts
+}
+
+// configChangesApply applies the options of cfg which changed since applied and
+// do not require a restart; those removed from cfg are set back to their
+// defaults.  Options in skip are left untouched.
+func configChangesApply(cfg, applied map[string]interface{}, skip map[string]bool) {
+	for name, value := range cfg {
+		if skip[name] || reflect.DeepEqual(value, applied[name]) {
+			continue
+		}
+		if !configHotReloadable[name] {
+			klog.Warningf("option %q changed, restart %s for the change to take effect", name, programName)
+			continue
+		}
+		if err := configOptionSet(name, value); err != nil {
+			klog.Errorf("%s", err.Error())
+			continue
+		}
+		klog.Infof("option %q set to %v", name, value)
+	}
+	for name := range applied {
+		if _, ok := cfg[name]; ok || skip[name] {
+			continue
+		}
+		if !configHotReloadable[name] {
+			klog.Warningf("option %q removed, restart %s for the change to take effect", name, programName)
+			continue
+		}
+		if err := configOptionReset(name); err != nil {
+			klog.Errorf("%s", err.Error())
+			continue
+		}
+		klog.Infof("option %q set back to its default", name)
+	}
+}
+
+// configOperandSkip returns the options the OperandConfig must not change: those
+// set on the command line or in the configuration file.
+func configOperandSkip() map[string]bool {
+	skip := map[string]bool{}
+	for name := range configCmdline {
+		skip[name] = true
+	}
+	for name := range configApplied {
+		skip[name] = true
+	}
+	return skip
+}
+
+// operandConfigInit applies the OperandConfig of the cluster at startup.  The
+// cluster may be unreachable, so failures are not fatal.
+func operandConfigInit() {
+	if opts.Standalone || len(opts.OperandConfigMap) == 0 {
+		return
+	}
+	data, err := tuned.OperandConfigGet(opts)
+	if err != nil {
+		klog.Warningf("not applying the OperandConfig: %v", err)
+		return
+	}
+	cfg, err := configParse([]byte(data), "OperandConfig")
+	if err != nil {
+		klog.Errorf("%s", err.Error())
+		return
+	}
+	skip := configOperandSkip()
+	for name, value := range cfg {
+		if skip[name] {
+			continue
+		}
+		if err = configOptionSet(name, value); err != nil {
+			klog.Errorf("%s", err.Error())
+			continue
+		}
+		opts.Flags[name] = daemonFlags.Lookup(name).Value.String()
+	}
+	configOperandApplied = cfg
+}
+
+// operandConfigReload applies the options of the OperandConfig data which changed
+// and do not require a restart.  It returns the options for the tuned.Controller
+// to apply, see Options.OnOperandConfigChange.
+func operandConfigReload(data string) tuned.Options {
+	cfg, err := configParse([]byte(data), "OperandConfig")
+	if err != nil {
+		klog.Errorf("%s", err.Error())
+		return opts
+	}
+	if reflect.DeepEqual(cfg, configOperandApplied) {
+		return opts
+	}
+	klog.Infof("OperandConfig changed")
+
+	configChangesApply(cfg, configOperandApplied, configOperandSkip())
+	configOperandApplied = cfg
+	return opts
+}
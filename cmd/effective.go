@@ -0,0 +1,51 @@
+package main
+
+import (
+	"encoding/json" // json.MarshalIndent()
+	"fmt"           // Println()
+	"os"            // os.Stderr
+
+	"github.com/spf13/pflag"
+	"gopkg.in/yaml.v2"
+
+	"github.com/openshift/openshift-tuned/pkg/tuned"
+)
+
+// Functions
+// effectiveFlags adds the options of the "effective" subcommand to fs.
+func effectiveFlags(fs *pflag.FlagSet) {
+	flagsProfiles(fs)
+	flagsActiveProfile(fs)
+	flagsExec(fs)
+	fs.StringP("output", "o", "json", "output format, json or yaml")
+}
+
+// effectiveCmd implements the "effective" subcommand.
+func effectiveCmd(fs *pflag.FlagSet) int {
+	var (
+		data []byte
+		err  error
+	)
+
+	output, _ := fs.GetString("output")
+	e := tuned.New(opts).EffectiveProfile(fs.Arg(0))
+	switch output {
+	case "json":
+		data, err = json.MarshalIndent(e, "", "  ")
+		data = append(data, '\n')
+	case "yaml":
+		data, err = yaml.Marshal(e)
+	default:
+		fmt.Fprintf(os.Stderr, "unknown output format %q, expected json or yaml\n", output)
+		return 1
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err.Error())
+		return 1
+	}
+	fmt.Print(string(data))
+	if len(e.Error) > 0 {
+		return 1
+	}
+	return 0
+}
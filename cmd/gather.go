@@ -0,0 +1,64 @@
+package main
+
+import (
+	"bytes"     // bytes.HasPrefix()
+	"fmt"       // Printf()
+	"io/ioutil" // ioutil.WriteFile()
+	"os"        // os.Stderr
+	"strings"   // strings.TrimSpace()
+	"time"      // time.Now()
+
+	"github.com/spf13/pflag"
+)
+
+// Constants
+const (
+	// magic number of the gzip format
+	gzipMagic = "\x1f\x8b"
+)
+
+// Functions
+// mustGatherFlags adds the options of the "must-gather" subcommand to fs.
+func mustGatherFlags(fs *pflag.FlagSet) {
+	flagsSocket(fs)
+	flagsNode(fs)
+	fs.StringP("output", "o", "", "file to write the tarball to, - for stdout; defaults to "+programName+"-must-gather-NODE-TIME.tar.gz")
+	fs.Duration("timeout", 30*time.Second, "time to wait for the tarball")
+}
+
+// mustGatherCmd implements the "must-gather" subcommand.
+func mustGatherCmd(fs *pflag.FlagSet) int {
+	output, _ := fs.GetString("output")
+	timeout, _ := fs.GetDuration("timeout")
+
+	data, err := sockRequestRaw("must-gather", timeout)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err.Error())
+		return 1
+	}
+	if !bytes.HasPrefix(data, []byte(gzipMagic)) {
+		fmt.Fprintf(os.Stderr, "%s must-gather response: %s\n", programName, strings.TrimSpace(string(data)))
+		return 1
+	}
+
+	if output == "-" {
+		if _, err := os.Stdout.Write(data); err != nil {
+			fmt.Fprintf(os.Stderr, "%s\n", err.Error())
+			return 1
+		}
+		return 0
+	}
+	if len(output) == 0 {
+		name := nodeName
+		if len(name) == 0 {
+			name, _ = os.Hostname()
+		}
+		output = fmt.Sprintf("%s-must-gather-%s-%s.tar.gz", programName, name, time.Now().UTC().Format("20060102T150405Z"))
+	}
+	if err := ioutil.WriteFile(output, data, 0600); err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err.Error())
+		return 1
+	}
+	fmt.Println(output)
+	return 0
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"           // Println()
+	"io/ioutil"     // ioutil.ReadFile()
+	"os"            // os.Stat()
+	"path/filepath" // filepath.Ext()
+
+	"github.com/spf13/pflag"
+
+	"github.com/openshift/openshift-tuned/pkg/profile"
+	"github.com/openshift/openshift-tuned/pkg/tuned"
+)
+
+// Functions
+// lintProfilesRead reads the tuned profiles to lint from path: a tuned profiles
+// ConfigMap file (*.yaml), a directory of <name>/tuned.conf profiles, a profile
+// directory or a tuned.conf file.
+func lintProfilesRead(path string) (map[string]string, error) {
+	switch filepath.Ext(path) {
+	case ".yaml", ".yml":
+		return profile.ConfigMapRead(path)
+	}
+	fi, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if fi.IsDir() {
+		if _, err := os.Stat(filepath.Join(path, profile.ConfFile)); err != nil {
+			s := profile.DirSource{Dir: path}
+			return s.Profiles()
+		}
+		path = filepath.Join(path, profile.ConfFile)
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	name := filepath.Base(filepath.Dir(path))
+	profiles := map[string]string{name: string(data)}
+	if sig, err := ioutil.ReadFile(path + profile.SignatureSuffix); err == nil {
+		profiles[name+profile.SignatureSuffix] = string(sig)
+	}
+	return profiles, nil
+}
+
+// lintFlags adds the options of the "lint" subcommand to fs.
+func lintFlags(fs *pflag.FlagSet) {
+	flagsProfiles(fs)
+	flagsSigning(fs)
+}
+
+// lintCmd implements the "lint" subcommand.
+func lintCmd(fs *pflag.FlagSet) int {
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return tuned.ExitConfig
+	}
+	profiles, err := lintProfilesRead(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err.Error())
+		return 1
+	}
+	if len(profiles) == 0 {
+		fmt.Fprintf(os.Stderr, "no tuned profiles found in %q\n", fs.Arg(0))
+		return 1
+	}
+
+	problems, err := tuned.New(opts).Lint(profiles)
+	for _, p := range problems {
+		fmt.Println(p)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err.Error())
+		return 1
+	}
+	return 0
+}
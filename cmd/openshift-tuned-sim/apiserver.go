@@ -0,0 +1,492 @@
+package main
+
+import (
+	"encoding/json" // json.Marshal()
+	"fmt"           // Sprintf()
+	"io/ioutil"     // ioutil.ReadAll()
+	"math/rand"     // rand.Float64()
+	"net/http"      // http.Handler
+	"sort"          // sort.Strings()
+	"strconv"       // strconv.ParseInt()
+	"strings"       // strings.Split()
+	"sync"          // sync.Mutex
+	"time"          // time.Duration
+
+	"k8s.io/apimachinery/pkg/fields"
+)
+
+// Types
+// object is an API object as stored by apiServer, i.e. decoded JSON.
+type object map[string]interface{}
+
+// objectKey identifies an object; namespace is empty for cluster-scoped resources.
+type objectKey struct {
+	resource  string
+	namespace string
+	name      string
+}
+
+// objectKind is the apiVersion and kind of the objects of a resource.
+type objectKind struct {
+	apiVersion string
+	kind       string
+}
+
+// storedEvent is a change of an object, replayed to watches.
+type storedEvent struct {
+	key  objectKey
+	rv   int64
+	Type string          `json:"type"`
+	Data json.RawMessage `json:"object"`
+}
+
+// apiServer is a minimal in-memory Kubernetes apiserver serving the resources
+// openshift-tuned uses: get, list, watch, create, update and merge patch, with
+// metadata.name field selectors.  It counts the requests it serves and fails a
+// fraction errorRate of them to exercise the retries of the clients.
+type apiServer struct {
+	errorRate float64
+
+	mu      sync.Mutex
+	rv      int64
+	objects map[objectKey][]byte
+	events  []storedEvent
+	// closed and replaced on every change, wakes up the watches
+	changed chan struct{}
+	// "verb resource" -> number of requests
+	requests   map[string]int64
+	failed     int64
+	watches    int
+	watchesMax int
+}
+
+// Global variables
+var (
+	apiKinds = map[string]objectKind{
+		"nodes":      {"v1", "Node"},
+		"configmaps": {"v1", "ConfigMap"},
+		"events":     {"v1", "Event"},
+		"profiles":   {"tuned.openshift.io/v1", "Profile"},
+		"tuneds":     {"tuned.openshift.io/v1", "Tuned"},
+	}
+)
+
+// Constants
+const (
+	watchTimeoutDefault = 5 * time.Minute
+)
+
+// Functions
+func newAPIServer(errorRate float64) *apiServer {
+	return &apiServer{
+		errorRate: errorRate,
+		objects:   map[objectKey][]byte{},
+		changed:   make(chan struct{}),
+		requests:  map[string]int64{},
+	}
+}
+
+// apiPathParse splits an API path, e.g. /api/v1/nodes/n1 or
+// /apis/tuned.openshift.io/v1/namespaces/ns/profiles, into the object key.  The
+// name is empty for collections.  The status subresource is the object itself.
+func apiPathParse(path string) (objectKey, bool) {
+	var key objectKey
+
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	switch {
+	case len(parts) > 2 && parts[0] == "api":
+		parts = parts[2:]
+	case len(parts) > 3 && parts[0] == "apis":
+		parts = parts[3:]
+	default:
+		return key, false
+	}
+	if len(parts) > 2 && parts[0] == "namespaces" {
+		key.namespace, parts = parts[1], parts[2:]
+	}
+	if len(parts) == 3 && parts[2] == "status" {
+		parts = parts[:2]
+	}
+	switch len(parts) {
+	case 2:
+		key.name = parts[1]
+		fallthrough
+	case 1:
+		key.resource = strings.ToLower(parts[0])
+	default:
+		return key, false
+	}
+	_, ok := apiKinds[key.resource]
+	return key, ok
+}
+
+// objectMeta returns the metadata of o, creating it if needed.
+func objectMeta(o object) map[string]interface{} {
+	meta, ok := o["metadata"].(map[string]interface{})
+	if !ok {
+		meta = map[string]interface{}{}
+		o["metadata"] = meta
+	}
+	return meta
+}
+
+// mergePatch applies the JSON merge patch (RFC 7386) patch to dst.
+func mergePatch(dst, patch map[string]interface{}) {
+	for k, v := range patch {
+		switch v := v.(type) {
+		case nil:
+			delete(dst, k)
+		case map[string]interface{}:
+			m, ok := dst[k].(map[string]interface{})
+			if !ok {
+				m = map[string]interface{}{}
+				dst[k] = m
+			}
+			mergePatch(m, v)
+		default:
+			dst[k] = v
+		}
+	}
+}
+
+// status writes a metav1.Status error response, so that the clients recognize
+// e.g. NotFound errors.
+func status(w http.ResponseWriter, code int, reason, format string, args ...interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "Status",
+		"status":     "Failure",
+		"reason":     reason,
+		"code":       code,
+		"message":    fmt.Sprintf(format, args...),
+	})
+}
+
+// set stores o under key and records the change for the watches; a nil o deletes
+// the object.  Must be called with s.mu held.
+func (s *apiServer) set(key objectKey, o object) ([]byte, error) {
+	eventType := "ADDED"
+	old, exists := s.objects[key]
+	if exists {
+		eventType = "MODIFIED"
+	}
+	s.rv++
+	if o == nil {
+		if !exists {
+			return nil, nil
+		}
+		delete(s.objects, key)
+		s.events = append(s.events, storedEvent{key: key, rv: s.rv, Type: "DELETED", Data: old})
+		s.wake()
+		return nil, nil
+	}
+
+	kind := apiKinds[key.resource]
+	o["apiVersion"], o["kind"] = kind.apiVersion, kind.kind
+	meta := objectMeta(o)
+	meta["name"] = key.name
+	if len(key.namespace) > 0 {
+		meta["namespace"] = key.namespace
+	}
+	meta["resourceVersion"] = strconv.FormatInt(s.rv, 10)
+	if _, ok := meta["uid"]; !ok {
+		meta["uid"] = fmt.Sprintf("sim-%d", s.rv)
+		meta["creationTimestamp"] = time.Now().UTC().Format(time.RFC3339)
+	}
+	data, err := json.Marshal(o)
+	if err != nil {
+		return nil, err
+	}
+	s.objects[key] = data
+	s.events = append(s.events, storedEvent{key: key, rv: s.rv, Type: eventType, Data: data})
+	s.wake()
+	return data, nil
+}
+
+// wake wakes up the watches.  Must be called with s.mu held.
+func (s *apiServer) wake() {
+	close(s.changed)
+	s.changed = make(chan struct{})
+}
+
+// Set creates or replaces the object of resource namespace/name with v, e.g. a
+// *corev1.Node.
+func (s *apiServer) Set(resource, namespace, name string, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	o := object{}
+	if err := json.Unmarshal(data, &o); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err = s.set(objectKey{resource: resource, namespace: namespace, name: name}, o)
+	return err
+}
+
+// Get decodes the object of resource namespace/name into v.
+func (s *apiServer) Get(resource, namespace, name string, v interface{}) error {
+	s.mu.Lock()
+	data, ok := s.objects[objectKey{resource: resource, namespace: namespace, name: name}]
+	s.mu.Unlock()
+	if !ok {
+		return fmt.Errorf("%s %s/%s not found", resource, namespace, name)
+	}
+	return json.Unmarshal(data, v)
+}
+
+// count records a request.
+func (s *apiServer) count(verb, resource string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests[verb+" "+resource]++
+}
+
+// inject returns true if the request should fail, see s.errorRate.
+func (s *apiServer) inject() bool {
+	if s.errorRate <= 0 || rand.Float64() >= s.errorRate {
+		return false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failed++
+	return true
+}
+
+// selected returns true if the object key matches the field selector sel.
+func selected(key objectKey, sel fields.Selector) bool {
+	return sel.Matches(fields.Set{"metadata.name": key.name, "metadata.namespace": key.namespace})
+}
+
+func (s *apiServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	key, ok := apiPathParse(r.URL.Path)
+	if !ok {
+		status(w, http.StatusNotFound, "NotFound", "unknown path %q", r.URL.Path)
+		return
+	}
+	q := r.URL.Query()
+	sel, err := fields.ParseSelector(q.Get("fieldSelector"))
+	if err != nil {
+		status(w, http.StatusBadRequest, "BadRequest", "%v", err)
+		return
+	}
+
+	verb := ""
+	switch {
+	case r.Method == http.MethodGet && len(key.name) > 0:
+		verb = "get"
+	case r.Method == http.MethodGet && (q.Get("watch") == "true" || q.Get("watch") == "1"):
+		verb = "watch"
+	case r.Method == http.MethodGet:
+		verb = "list"
+	case r.Method == http.MethodPost:
+		verb = "create"
+	case r.Method == http.MethodPut && len(key.name) > 0:
+		verb = "update"
+	case r.Method == http.MethodPatch && len(key.name) > 0:
+		verb = "patch"
+	default:
+		status(w, http.StatusMethodNotAllowed, "MethodNotAllowed", "%s %s not supported", r.Method, r.URL.Path)
+		return
+	}
+	s.count(verb, key.resource)
+	if s.inject() {
+		status(w, http.StatusInternalServerError, "InternalError", "injected failure")
+		return
+	}
+
+	switch verb {
+	case "get":
+		s.get(w, key)
+	case "list":
+		s.list(w, key, sel)
+	case "watch":
+		s.watch(w, r, key, sel)
+	default:
+		s.write(w, r, verb, key)
+	}
+}
+
+func (s *apiServer) get(w http.ResponseWriter, key objectKey) {
+	s.mu.Lock()
+	data, ok := s.objects[key]
+	s.mu.Unlock()
+	if !ok {
+		status(w, http.StatusNotFound, "NotFound", "%s %q not found", key.resource, key.name)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(data)
+}
+
+func (s *apiServer) list(w http.ResponseWriter, key objectKey, sel fields.Selector) {
+	var (
+		keys  []string
+		items = map[string]json.RawMessage{}
+	)
+
+	s.mu.Lock()
+	for k, data := range s.objects {
+		if k.resource != key.resource || (len(key.namespace) > 0 && k.namespace != key.namespace) || !selected(k, sel) {
+			continue
+		}
+		id := k.namespace + "/" + k.name
+		keys = append(keys, id)
+		items[id] = data
+	}
+	rv := s.rv
+	s.mu.Unlock()
+
+	sort.Strings(keys)
+	list := []json.RawMessage{}
+	for _, id := range keys {
+		list = append(list, items[id])
+	}
+	kind := apiKinds[key.resource]
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"apiVersion": kind.apiVersion,
+		"kind":       kind.kind + "List",
+		"metadata":   map[string]interface{}{"resourceVersion": strconv.FormatInt(rv, 10)},
+		"items":      list,
+	})
+}
+
+// watch streams the changes of the objects matching key and sel after the
+// resourceVersion of the request until the timeoutSeconds of the request pass or
+// the client goes away.
+func (s *apiServer) watch(w http.ResponseWriter, r *http.Request, key objectKey, sel fields.Selector) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		status(w, http.StatusInternalServerError, "InternalError", "streaming not supported")
+		return
+	}
+	rv, _ := strconv.ParseInt(r.URL.Query().Get("resourceVersion"), 10, 64)
+	timeout := watchTimeoutDefault
+	if t, err := strconv.Atoi(r.URL.Query().Get("timeoutSeconds")); err == nil && t > 0 {
+		timeout = time.Duration(t) * time.Second
+	}
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	s.mu.Lock()
+	s.watches++
+	if s.watches > s.watchesMax {
+		s.watchesMax = s.watches
+	}
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		s.watches--
+		s.mu.Unlock()
+	}()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+	enc := json.NewEncoder(w)
+	for {
+		var events []storedEvent
+
+		s.mu.Lock()
+		i := sort.Search(len(s.events), func(i int) bool { return s.events[i].rv > rv })
+		for _, e := range s.events[i:] {
+			if e.key.resource == key.resource && (len(key.namespace) == 0 || e.key.namespace == key.namespace) && selected(e.key, sel) {
+				events = append(events, e)
+			}
+		}
+		rv = s.rv
+		changed := s.changed
+		s.mu.Unlock()
+
+		for _, e := range events {
+			if err := enc.Encode(e); err != nil {
+				return
+			}
+		}
+		if len(events) > 0 {
+			flusher.Flush()
+		}
+
+		select {
+		case <-changed:
+		case <-timer.C:
+			return
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// write creates, updates or merge-patches an object.
+func (s *apiServer) write(w http.ResponseWriter, r *http.Request, verb string, key objectKey) {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		status(w, http.StatusBadRequest, "BadRequest", "%v", err)
+		return
+	}
+	o := object{}
+	if err := json.Unmarshal(body, &o); err != nil {
+		status(w, http.StatusBadRequest, "BadRequest", "%v", err)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	code := http.StatusOK
+	switch verb {
+	case "create":
+		meta := objectMeta(o)
+		name, _ := meta["name"].(string)
+		if generateName, _ := meta["generateName"].(string); len(name) == 0 {
+			name = fmt.Sprintf("%s%d", generateName, s.rv+1)
+		}
+		key.name = name
+		if _, ok := s.objects[key]; ok {
+			status(w, http.StatusConflict, "AlreadyExists", "%s %q already exists", key.resource, key.name)
+			return
+		}
+		code = http.StatusCreated
+	case "update", "patch":
+		old, ok := s.objects[key]
+		if !ok {
+			status(w, http.StatusNotFound, "NotFound", "%s %q not found", key.resource, key.name)
+			return
+		}
+		if verb == "patch" {
+			patch := o
+			o = object{}
+			if err := json.Unmarshal(old, &o); err != nil {
+				status(w, http.StatusInternalServerError, "InternalError", "%v", err)
+				return
+			}
+			mergePatch(o, patch)
+		}
+	}
+	data, err := s.set(key, o)
+	if err != nil {
+		status(w, http.StatusInternalServerError, "InternalError", "%v", err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	w.Write(data)
+}
+
+// stats returns the numbers of requests by "verb resource", of the failures
+// injected and the maximum number of concurrent watches.
+func (s *apiServer) stats() (map[string]int64, int64, int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	requests := map[string]int64{}
+	for k, v := range s.requests {
+		requests[k] = v
+	}
+	return requests, s.failed, s.watchesMax
+}
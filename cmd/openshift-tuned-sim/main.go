@@ -0,0 +1,303 @@
+// openshift-tuned-sim runs many in-process openshift-tuned instances, each
+// managing a simulated node with a mock tuned, against an in-memory apiserver.
+// It relabels the nodes and changes the rendered Tuned like the operator would,
+// and reports the apiserver load the instances cause and whether they converged
+// on the requested tuned profiles.
+package main
+
+import (
+	"context"           // context.WithCancel()
+	"flag"              // command-line options parsing
+	"fmt"               // Printf()
+	"io/ioutil"         // ioutil.TempDir()
+	"math/rand"         // rand.Intn()
+	"net/http/httptest" // httptest.NewServer()
+	"os"                // os.Exit()
+	"path/filepath"     // filepath.Join()
+	"sort"              // sort.Strings()
+	"strconv"           // strconv.Atoi()
+	"strings"           // strings.TrimSpace()
+	"sync"              // sync.WaitGroup
+	"time"              // time.Duration
+
+	tunedv1 "github.com/openshift/cluster-node-tuning-operator/pkg/apis/tuned/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/rest"
+	"k8s.io/klog"
+
+	"github.com/openshift/openshift-tuned/pkg/tuned"
+)
+
+// Types
+// simNode is a simulated node managed by an in-process openshift-tuned.
+type simNode struct {
+	name              string
+	activeProfileFile string
+	// the tuned profile requested by the Profile of the node
+	profile string
+	ctrl    *tuned.Controller
+}
+
+// Constants
+const (
+	programName      = "openshift-tuned-sim"
+	operandNamespace = "openshift-cluster-node-tuning-operator"
+	// node label selecting the tuned profile, see profileName()
+	profileLabel = "tuned.openshift.io/sim-profile"
+)
+
+// Global variables
+var (
+	nodeCount     = flag.Int("nodes", 10, "number of simulated nodes")
+	profileCount  = flag.Int("profiles", 3, "number of tuned profiles the nodes are labeled for")
+	duration      = flag.Duration("duration", time.Minute, "time to run the label and Tuned churn for")
+	labelChurn    = flag.Duration("label-churn", 2*time.Second, "period of relabeling a random node for another tuned profile; 0 disables it")
+	tunedChurn    = flag.Duration("tuned-churn", 20*time.Second, "period of changing the tuned profiles of the rendered Tuned, which all nodes extract; 0 disables it")
+	startSpread   = flag.Duration("start-spread", 5*time.Second, "start the instances at random times within this period, like a DaemonSet rollout")
+	settle        = flag.Duration("settle", 30*time.Second, "time to wait after the churn for the nodes to converge on the requested profiles")
+	errorRate     = flag.Float64("error-rate", 0, "fraction of the apiserver requests to fail, from 0 to 1")
+	retryInitial  = flag.Duration("retry-initial", tuned.DefaultOptions().RetryInitial, "period of retrying the event loop after the first error")
+	retryMax      = flag.Duration("retry-max", tuned.DefaultOptions().RetryMax, "maximum period of retrying the event loop")
+	retryJitter   = flag.Float64("retry-jitter", tuned.DefaultOptions().RetryJitter, "fraction to randomize every retry period by")
+	nodeCondition = flag.Bool("node-condition", false, "make the instances publish the TuningReady node condition")
+	workDir       = flag.String("dir", "", "directory for the files of the simulated nodes and the log; a temporary directory removed on exit if empty")
+	seed          = flag.Int64("seed", 0, "seed of the churn randomness; 0 uses the current time")
+	tunedRevision = 0
+)
+
+// Functions
+func profileName(i int) string {
+	return fmt.Sprintf("sim-%d", i)
+}
+
+// renderedTuned returns the rendered Tuned with the tuned profiles the nodes are
+// labeled for; every revision changes the profile content.
+func renderedTuned(revision int) *tunedv1.Tuned {
+	t := &tunedv1.Tuned{
+		ObjectMeta: metav1.ObjectMeta{Name: tunedv1.TunedRenderedResourceName, Namespace: operandNamespace},
+	}
+	for i := 0; i < *profileCount; i++ {
+		name := profileName(i)
+		data := fmt.Sprintf("[main]\nsummary=%s profile %d, revision %d\n", programName, i, revision)
+		t.Spec.Profile = append(t.Spec.Profile, tunedv1.TunedProfile{Name: &name, Data: &data})
+	}
+	return t
+}
+
+// nodeLabel labels node n for tuned profile i and updates its Profile like the
+// operator would.
+func nodeLabel(api *apiServer, n *simNode, i int) error {
+	node := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: n.name, Labels: map[string]string{profileLabel: strconv.Itoa(i)}},
+	}
+	if err := api.Set("nodes", "", n.name, node); err != nil {
+		return err
+	}
+	n.profile = profileName(i)
+	p := &tunedv1.Profile{
+		ObjectMeta: metav1.ObjectMeta{Name: n.name, Namespace: operandNamespace},
+		Spec:       tunedv1.ProfileSpec{Config: tunedv1.ProfileConfig{TunedProfile: n.profile}},
+	}
+	return api.Set("profiles", operandNamespace, n.name, p)
+}
+
+// nodeNew creates the directories of node name below dir and its openshift-tuned.
+func nodeNew(dir, name string, kubeConfig *rest.Config) (*simNode, error) {
+	dir = filepath.Join(dir, name)
+	opts := tuned.DefaultOptions()
+	opts.NodeName = name
+	opts.MockTuned = true
+	opts.ProfilesDir = filepath.Join(dir, "etc")
+	opts.SystemProfilesDir = filepath.Join(dir, "sys")
+	opts.ActiveProfileFile = filepath.Join(opts.ProfilesDir, "active_profile")
+	opts.RunDir = filepath.Join(dir, "run")
+	opts.Socket = filepath.Join(opts.RunDir, "openshift-tuned.sock")
+	opts.SupportConfigMap = false
+	opts.OperandConfigMap = ""
+	opts.AuditLog = ""
+	// Do not touch the host
+	opts.RealtimeGating = false
+	opts.RetryInitial = *retryInitial
+	opts.RetryMax = *retryMax
+	opts.RetryJitter = *retryJitter
+	opts.NodeCondition = *nodeCondition
+	opts.Version = programName
+
+	for _, d := range []string{opts.ProfilesDir, filepath.Join(opts.SystemProfilesDir, "recommend.d"), opts.RunDir} {
+		if err := os.MkdirAll(d, 0700); err != nil {
+			return nil, err
+		}
+	}
+	return &simNode{
+		name:              name,
+		activeProfileFile: opts.ActiveProfileFile,
+		ctrl:              tuned.New(opts, tuned.WithKubeConfig(kubeConfig)),
+	}, nil
+}
+
+// converged returns the number of nodes whose mock tuned applied the requested
+// tuned profile.
+func converged(nodes []*simNode) int {
+	n := 0
+	for _, node := range nodes {
+		data, err := ioutil.ReadFile(node.activeProfileFile)
+		if err == nil && strings.TrimSpace(string(data)) == node.profile {
+			n++
+		}
+	}
+	return n
+}
+
+func report(api *apiServer, nodes []*simNode, elapsed time.Duration, relabels int) {
+	requests, failed, watchesMax := api.stats()
+
+	var (
+		keys  []string
+		total int64
+	)
+	for k, v := range requests {
+		keys = append(keys, k)
+		total += v
+	}
+	sort.Strings(keys)
+	fmt.Printf("nodes: %d, elapsed: %v, relabels: %d, Tuned revisions: %d\n", len(nodes), elapsed.Round(time.Second), relabels, tunedRevision)
+	fmt.Printf("apiserver requests: %d (%.1f/s, %.2f/s per node), injected failures: %d, concurrent watches: %d max\n",
+		total, float64(total)/elapsed.Seconds(), float64(total)/elapsed.Seconds()/float64(len(nodes)), failed, watchesMax)
+	for _, k := range keys {
+		fmt.Printf("  %-20s %8d\n", k, requests[k])
+	}
+	fmt.Printf("converged: %d/%d nodes\n", converged(nodes), len(nodes))
+}
+
+func run() error {
+	if *nodeCount < 1 || *profileCount < 1 {
+		return fmt.Errorf("-nodes and -profiles must be at least 1")
+	}
+	if *errorRate < 0 || *errorRate > 1 {
+		return fmt.Errorf("-error-rate must be from 0 to 1")
+	}
+	if *seed == 0 {
+		*seed = time.Now().UnixNano()
+	}
+	rand.Seed(*seed)
+
+	dir := *workDir
+	if len(dir) == 0 {
+		var err error
+		if dir, err = ioutil.TempDir("", programName); err != nil {
+			return err
+		}
+		defer os.RemoveAll(dir)
+	}
+	if flag.Lookup("log_file").Value.String() == "" {
+		flag.Set("log_file", filepath.Join(dir, programName+".log"))
+	}
+	fmt.Printf("seed: %d, instance logs: %s\n", *seed, flag.Lookup("log_file").Value.String())
+
+	api := newAPIServer(*errorRate)
+	hs := httptest.NewServer(api)
+	defer hs.Close()
+	kubeConfig := &rest.Config{Host: hs.URL}
+
+	if err := api.Set("tuneds", operandNamespace, tunedv1.TunedRenderedResourceName, renderedTuned(tunedRevision)); err != nil {
+		return err
+	}
+	var nodes []*simNode
+	for i := 0; i < *nodeCount; i++ {
+		n, err := nodeNew(dir, fmt.Sprintf("sim-node-%03d", i), kubeConfig)
+		if err != nil {
+			return err
+		}
+		if err := nodeLabel(api, n, rand.Intn(*profileCount)); err != nil {
+			return err
+		}
+		nodes = append(nodes, n)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var wg sync.WaitGroup
+	for _, n := range nodes {
+		wg.Add(1)
+		go func(n *simNode, delay time.Duration) {
+			defer wg.Done()
+			select {
+			case <-time.After(delay):
+			case <-ctx.Done():
+				return
+			}
+			if err := n.ctrl.Run(ctx); err != nil {
+				klog.Errorf("%s: %v", n.name, err)
+			}
+		}(n, time.Duration(rand.Int63n(int64(*startSpread)+1)))
+	}
+
+	start := time.Now()
+	var labelC, tunedC <-chan time.Time
+	if *labelChurn > 0 {
+		t := time.NewTicker(*labelChurn)
+		defer t.Stop()
+		labelC = t.C
+	}
+	if *tunedChurn > 0 {
+		t := time.NewTicker(*tunedChurn)
+		defer t.Stop()
+		tunedC = t.C
+	}
+	relabels := 0
+	end := time.After(*duration)
+churn:
+	for {
+		select {
+		case <-labelC:
+			if err := nodeLabel(api, nodes[rand.Intn(len(nodes))], rand.Intn(*profileCount)); err != nil {
+				return err
+			}
+			relabels++
+		case <-tunedC:
+			tunedRevision++
+			if err := api.Set("tuneds", operandNamespace, tunedv1.TunedRenderedResourceName, renderedTuned(tunedRevision)); err != nil {
+				return err
+			}
+		case <-end:
+			break churn
+		}
+	}
+
+	deadline := time.Now().Add(*settle)
+	for converged(nodes) < len(nodes) && time.Now().Before(deadline) {
+		time.Sleep(500 * time.Millisecond)
+	}
+	elapsed := time.Since(start)
+	report(api, nodes, elapsed, relabels)
+
+	cancel()
+	wg.Wait()
+	klog.Flush()
+
+	if converged(nodes) < len(nodes) {
+		return fmt.Errorf("not all nodes converged within %v after the churn", *settle)
+	}
+	return nil
+}
+
+func main() {
+	klog.InitFlags(nil)
+	// The instances log to the log file, keep the report readable
+	flag.Set("logtostderr", "false")
+	flag.Set("stderrthreshold", "FATAL")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s [options]\n\n", programName)
+		fmt.Fprintf(os.Stderr, "Runs -nodes in-process openshift-tuned instances against an in-memory apiserver\n")
+		fmt.Fprintf(os.Stderr, "with synthetic node label and Tuned churn, and reports the apiserver load.\n\n")
+		fmt.Fprintf(os.Stderr, "Options:\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if err := run(); err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", programName, err)
+		os.Exit(1)
+	}
+}
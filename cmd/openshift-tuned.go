@@ -1,96 +1,40 @@
 package main
 
 import (
-	"bufio"         // scanner
-	"bytes"         // bytes.Buffer
-	"flag"          // command-line options parsing
-	"fmt"           // Printf()
-	"io/ioutil"     // ioutil.ReadFile()
-	"math"          // math.Pow()
-	"net"           // net.Conn
-	"os"            // os.Exit(), os.Signal, os.Stderr, ...
-	"os/exec"       // os.Exec()
-	"os/signal"     // signal.Notify()
-	"os/user"       // user.Current()
-	"path/filepath" // filepath.Join()
-	"reflect"       // DeepEqual()
-	"strconv"       // strconv
-	"strings"       // strings.Join()
-	"syscall"       // syscall.SIGHUP, ...
-	"time"          // time.Second, ...
-
-	"k8s.io/apimachinery/pkg/fields"
-	"k8s.io/client-go/rest"
-	"k8s.io/client-go/tools/cache"
-	"k8s.io/client-go/tools/clientcmd"
+	"context"   // context.WithCancel()
+	"flag"      // flag.CommandLine
+	"os"        // os.Exit(), os.Signal, os.Stderr, ...
+	"os/signal" // signal.Notify()
+	"strings"   // strings.Join()
+	"syscall"   // syscall.SIGHUP, ...
+
+	"github.com/spf13/pflag"
 	"k8s.io/klog"
 
-	"github.com/fsnotify/fsnotify"
-	"gopkg.in/yaml.v2"
-
-	tunedv1 "github.com/openshift/cluster-node-tuning-operator/pkg/apis/tuned/v1"
-	tunedclientset "github.com/openshift/cluster-node-tuning-operator/pkg/generated/clientset/versioned"
+	"github.com/openshift/openshift-tuned/pkg/tuned"
 )
 
 // Types
 type arrayFlags []string
 
-type sockAccepted struct {
-	conn net.Conn
-	err  error
-}
-
-type tunedState struct {
-	change struct {
-		// did profile change?
-		profile bool
-		// did the "rendered" tuned object change?
-		rendered bool
-		// did tuned profiles/recommend config change on the filesystem?
-		cfg bool
-	}
-}
-
 // Constants
 const (
-	operandNamespace       = "openshift-cluster-node-tuning-operator"
-	profileExtractInterval = 1
-	programName            = "openshift-tuned"
-	tunedActiveProfileFile = "/etc/tuned/active_profile"
-	tunedProfilesConfigMap = "/var/lib/tuned/profiles-data/tuned-profiles.yaml"
-	tunedProfilesDir       = "/etc/tuned"
-	tunedRecommendDir      = tunedProfilesDir + "/recommend.d"
-	tunedRecommendFile     = tunedRecommendDir + "/" + "50-openshift.conf"
-	openshiftTunedRunDir   = "/run/" + programName
-	openshiftTunedPidFile  = openshiftTunedRunDir + "/" + programName + ".pid"
-	openshiftTunedSocket   = "/var/lib/tuned/openshift-tuned.sock"
-	supportCM              = true // remove when dropping support for tuned-profiles ConfigMap
+	programName = "openshift-tuned"
 )
 
 // Global variables
 var (
-	done               = make(chan bool, 1)
-	tunedExit          = make(chan bool, 1)
 	terminationSignals = []os.Signal{syscall.SIGHUP, syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT}
-	fileWatch          arrayFlags
 	version            string // programName version
-	cmd                *exec.Cmd
-	// Flags
-	boolVersion = flag.Bool("version", false, "show program version and exit")
+	gitCommit          string // git commit programName was built from
+	opts               = tuned.DefaultOptions()
+	// Flags, see flagsRun()
+	boolVersion           bool
+	boolCheckCapabilities bool
+	nodeName              string
 )
 
 // Functions
-func mkdir(dir string) error {
-	if _, err := os.Stat(dir); os.IsNotExist(err) {
-		err = os.MkdirAll(dir, os.ModePerm)
-		if err != nil {
-			return err
-		}
-	}
-
-	return nil
-}
-
 func (a *arrayFlags) String() string {
 	return strings.Join(*a, ",")
 }
@@ -100,692 +44,129 @@ func (a *arrayFlags) Set(value string) error {
 	return nil
 }
 
-func parseCmdOpts() {
-	klog.InitFlags(nil)
-	flag.Usage = func() {
-		fmt.Fprintf(os.Stderr, "Usage: %s [options] <NODE>\n", programName)
-		fmt.Fprintf(os.Stderr, "Example: %s b1.lan\n\n", programName)
-		fmt.Fprintf(os.Stderr, "Options:\n")
-
-		flag.PrintDefaults()
-	}
-
-	flag.Var(&fileWatch, "watch-file", "Files/directories to watch for changes.")
-	flag.Parse()
-}
-
-func signalHandler() chan os.Signal {
+func (a *arrayFlags) Type() string {
+	return "stringArray"
+}
+
+// flagsLog adds the klog options to fs; every command has them.
+func flagsLog(fs *pflag.FlagSet) {
+	fs.AddGoFlagSet(flag.CommandLine)
+}
+
+// flagsSocket adds the options of the commands using the control socket to fs.
+func flagsSocket(fs *pflag.FlagSet) {
+	fs.StringVar(&opts.Socket, "socket", opts.Socket, "control socket path; a path starting with @ is in the abstract socket namespace")
+}
+
+// flagsNode adds the options selecting the node to fs.
+func flagsNode(fs *pflag.FlagSet) {
+	fs.StringVar(&nodeName, "node-name", os.Getenv("NODE_NAME"), "name of the node to manage; defaults to the NODE_NAME environment variable")
+}
+
+// flagsProfiles adds the options of the commands reading tuned profiles to fs.
+func flagsProfiles(fs *pflag.FlagSet) {
+	fs.StringVar(&opts.ProfilesDir, "tuned-profiles-dir", opts.ProfilesDir, "directory to extract tuned profiles to")
+	fs.StringVar(&opts.SystemProfilesDir, "tuned-system-profiles-dir", opts.SystemProfilesDir, "directory with the profiles shipped with tuned")
+}
+
+// flagsActiveProfile adds the options of the commands reading the tuned active
+// profile to fs.
+func flagsActiveProfile(fs *pflag.FlagSet) {
+	fs.StringVar(&opts.ActiveProfileFile, "tuned-active-profile-file", opts.ActiveProfileFile, "tuned active profile file")
+	fs.StringVar(&opts.ActiveProfileSource, "tuned-active-profile-source", opts.ActiveProfileSource, "where to read the tuned active profile from: file (tuned-active-profile-file) or tuned-adm (requires tuned with D-Bus)")
+}
+
+// flagsSigning adds the options of the commands verifying tuned profile
+// signatures to fs.
+func flagsSigning(fs *pflag.FlagSet) {
+	fs.BoolVar(&opts.RequireSignedProfiles, "require-signed-profiles", opts.RequireSignedProfiles, "refuse to extract unsigned or tampered tuned profiles")
+	fs.StringVar(&opts.ProfileSigningKey, "profile-signing-key", opts.ProfileSigningKey, "PEM-encoded public key (RSA or ECDSA) to verify tuned profile signatures with")
+}
+
+// flagsExec adds the options of the commands running the tuned binaries to fs.
+func flagsExec(fs *pflag.FlagSet) {
+	fs.DurationVar(&opts.ExecTimeout, "exec-timeout", opts.ExecTimeout, "time limit of the tuned-adm, tuned --version and systemctl commands; 0 disables the limit")
+}
+
+// flagsRun adds the options of the daemon, i.e. the "run" command, to fs.
+func flagsRun(fs *pflag.FlagSet) {
+	flagsSocket(fs)
+	flagsNode(fs)
+	flagsProfiles(fs)
+	flagsActiveProfile(fs)
+	flagsSigning(fs)
+	flagsExec(fs)
+	fs.BoolVar(&boolVersion, "version", false, "show program version and exit")
+	fs.BoolVar(&boolCheckCapabilities, "check-capabilities", true, "fail at startup if required capabilities are missing and drop the capabilities not required")
+	fs.StringVar(&configFile, "config", "", configFileUsage)
+	fs.Var((*arrayFlags)(&opts.WatchFiles), "watch-file", "Files/directories to watch for changes, path[=action]; action is extract (default), reload, variables or hook:<hook>")
+	fs.DurationVar(&opts.WatchQuiescence, "watch-quiescence", opts.WatchQuiescence, "time without filesystem events to wait for before extracting changed tuned profiles")
+	fs.StringVar(&opts.ProfilesConfigMap, "tuned-profiles-configmap", opts.ProfilesConfigMap, "tuned profiles ConfigMap file")
+	fs.StringVar(&opts.RunDir, "run-dir", opts.RunDir, "runtime directory for the "+programName+" pid file")
+	fs.StringVar(&opts.KubeConfig, "kubeconfig", opts.KubeConfig, "kubeconfig file; defaults to the KUBECONFIG environment variable, the in-cluster config or $HOME/.kube/config")
+	fs.IntVar(&opts.APIPort, "api-port", opts.APIPort, "port to serve the HTTP API on; 0 disables the API")
+	// remove when dropping support for tuned-profiles ConfigMap
+	fs.BoolVar(&opts.SupportConfigMap, "configmap", opts.SupportConfigMap, "extract tuned profiles from the tuned-profiles ConfigMap file too; if false, only the rendered Tuned object is used")
+	fs.DurationVar(&opts.ReloadVerifyTimeout, "reload-verify-timeout", opts.ReloadVerifyTimeout, "time for tuned to apply a profile after a reload")
+	fs.IntVar(&opts.ReloadFailuresMax, "reload-failures-max", opts.ReloadFailuresMax, "fall back to the last known-good profile after this many failed reloads of the same profile content")
+	fs.BoolVar(&opts.RealtimeGating, "realtime-gating", opts.RealtimeGating, "refuse to apply realtime profiles on a non-realtime kernel")
+	fs.DurationVar(&opts.RevertDelay, "revert-delay", opts.RevertDelay, "delay switching back to the previously requested tuned profile; the switch is dropped if the current profile is requested again within the delay")
+	fs.BoolVar(&opts.PartialReload, "partial-reload", opts.PartialReload, "write changed sysctls directly instead of reloading tuned when only sysctl values of the active profile changed")
+	fs.StringVar(&opts.AuditLog, "audit-log", opts.AuditLog, "file recording the actions taken on the node as JSON lines; empty disables the audit log")
+	fs.IntVar(&opts.AuditLogMaxSize, "audit-log-max-size", opts.AuditLogMaxSize, "size in MiB at which the audit log is rotated; 0 disables the rotation")
+	fs.IntVar(&opts.AuditLogBackups, "audit-log-backups", opts.AuditLogBackups, "number of rotated audit log files kept")
+	fs.IntVar(&opts.HistorySize, "history-size", opts.HistorySize, "number of reload events kept in the reload history; 0 disables the history")
+	fs.StringVar(&opts.OTLPEndpoint, "otlp-endpoint", opts.OTLPEndpoint, "OTLP/HTTP endpoint to export reconcile traces to, e.g. http://otel-collector:4318/v1/traces; empty disables tracing")
+	fs.Var((*arrayFlags)(&opts.ProfileSources), "profile-source", "additional source of tuned profiles, kind:location, e.g. dir:/etc/tuned-extra or https://mirror/profiles.tgz#sha256=...; may be repeated")
+	fs.Var((*arrayFlags)(&opts.Hooks), "hook", "hook to run after tuned applied a profile, builtin:irq-repin, builtin:irqbalance-banned-cpus or exec:<path>; may be repeated, hooks run in the given order")
+	fs.Var((*arrayFlags)(&opts.ForwardSignals), "forward-signal", "signals to forward to tuned, e.g. SIGUSR1,SIGUSR2; may be repeated")
+	fs.BoolVar(&opts.Subreaper, "subreaper", opts.Subreaper, "reap the processes orphaned by tuned even when not running as PID 1")
+	fs.DurationVar(&opts.WatchdogTimeout, "watchdog-timeout", opts.WatchdogTimeout, "fail /healthz if the event loop does not tick for this long; 0 disables the watchdog")
+	fs.Var((*arrayFlags)(&opts.Notifiers), "notify", "inform of tuned profile changes and apply failures: webhook:<url> (JSON POST), exec:<path> or events (Kubernetes Events of the node); may be repeated")
+	fs.BoolVar(&opts.NoRollbackOnExit, "no-rollback-on-exit", opts.NoRollbackOnExit, "leave the node-level tuning in place when "+programName+" exits on a termination signal")
+	fs.StringVar(&opts.DrainAction, "drain-action", opts.DrainAction, "while the node is cordoned: defer to defer tuned reloads or profile:<name> to switch to a maintenance profile; empty ignores cordoning")
+	fs.BoolVar(&opts.NodeCondition, "node-condition", opts.NodeCondition, "publish the TuningReady node condition reflecting whether the recommended profile is applied without errors")
+	fs.StringVar(&opts.DegradedTaint, "degraded-taint", opts.DegradedTaint, "taint key[=value]:effect to put on the node while the tuning is Degraded, e.g. tuned.openshift.io/degraded:NoSchedule; removed once Stable")
+	fs.BoolVar(&opts.NFDFacts, "nfd-facts", opts.NFDFacts, "write the Node Feature Discovery labels of the node to <run-dir>/nfd/<label> for recommend.d rules to match")
+	fs.Var((*arrayFlags)(&opts.NFDVariables), "nfd-variable", "map a node label to a tuned profile variable in <run-dir>/nfd-variables.conf, label=variable; may be repeated")
+	fs.StringVar(&opts.MaintenanceWindow, "maintenance-window", opts.MaintenanceWindow, "cron-like schedule and duration of the window disruptive tuned reloads are executed in, e.g. \"0 2 * * 6 4h\"; the tuned.openshift.io/maintenance-window node annotation overrides it")
+	fs.BoolVar(&opts.CanaryRollback, "canary-rollback", opts.CanaryRollback, "fall back to the last known-good configuration if the canary probes (\"# probe:\" comments of tuned.conf) of an applied profile fail")
+	fs.DurationVar(&opts.RetryInitial, "retry-initial", opts.RetryInitial, "period of retrying the event loop after the first error")
+	fs.DurationVar(&opts.RetryMax, "retry-max", opts.RetryMax, "maximum period of retrying the event loop")
+	fs.Float64Var(&opts.RetryFactor, "retry-factor", opts.RetryFactor, "factor the retry period grows by after every error, at least 1")
+	fs.Float64Var(&opts.RetryJitter, "retry-jitter", opts.RetryJitter, "fraction to randomize every retry period by, from 0 (none) to less than 1")
+	fs.Var((*arrayFlags)(&opts.SocketAllow), "socket-allow", "principal allowed to use the control socket besides root, uid:<n> or gid:<n>; may be repeated")
+	fs.StringVar(&opts.OperandConfigMap, "operand-config", opts.OperandConfigMap, "name of the ConfigMap in the operand namespace with the configuration the operator manages, the config.yaml key in the --config format; it is watched and its changes take effect like those of --config; empty disables it")
+	fs.BoolVar(&opts.Handoff, "handoff", opts.Handoff, "take over the tuned run by the "+programName+" instance listening on the control socket instead of starting tuned; needs hostPID and both pods running, e.g. with maxSurge")
+	fs.BoolVar(&opts.Standalone, "standalone", opts.Standalone, "do not access the Kubernetes API, select the tuned profile by the local recommend.d rules and labels files only")
+	fs.DurationVar(&opts.AttachInterval, "attach-interval", opts.AttachInterval, "with --standalone, period of checking whether the apiserver is reachable to switch to the Profile of the node; 0 stays standalone")
+	fs.StringVar(&opts.FeatureGates, "feature-gates", opts.FeatureGates, "enable or disable subsystems, e.g. RecommendCache=false,CanaryProbes=true; see the featureGates of the /version API for the known features")
+	fs.IntVar(&opts.TunedStdoutVerbosity, "tuned-stdout-v", opts.TunedStdoutVerbosity, "log verbosity (--v) at which the DEBUG and INFO lines tuned writes to stdout are logged")
+	fs.IntVar(&opts.TunedStderrVerbosity, "tuned-stderr-v", opts.TunedStderrVerbosity, "log verbosity (--v) at which the DEBUG and INFO lines tuned writes to stderr are logged")
+	fs.IntVar(&opts.TunedMaxLineSize, "tuned-max-line-size", opts.TunedMaxLineSize, "maximum length in bytes of a line tuned logs; longer lines, e.g. of huge tracebacks, are truncated")
+	fs.StringVar(&opts.TunedOutputLog, "tuned-output-log", opts.TunedOutputLog, "file to capture the tuned stdout and stderr in, e.g. /var/log/tuned/tuned-output.log; only WARNING and more severe lines are logged then; empty disables the capture")
+	fs.IntVar(&opts.TunedOutputLogMaxSize, "tuned-output-log-max-size", opts.TunedOutputLogMaxSize, "size in MiB at which the tuned output log is rotated; 0 disables the rotation")
+	fs.IntVar(&opts.TunedOutputLogBackups, "tuned-output-log-backups", opts.TunedOutputLogBackups, "number of rotated tuned output log files kept")
+	fs.BoolVar(&opts.MockTuned, "mock-tuned", opts.MockTuned, "run an in-process tuned stub instead of /usr/sbin/tuned (for testing)")
+}
+
+// signalHandler returns a context which is cancelled on a termination signal.
+func signalHandler() (context.Context, chan os.Signal) {
+	ctx, cancel := context.WithCancel(context.Background())
 	sigs := make(chan os.Signal, 1)
 	signal.Notify(sigs, terminationSignals...)
 	go func() {
 		sig := <-sigs
 		klog.V(1).Infof("received signal: %v", sig)
-		done <- true
-	}()
-	return sigs
-}
-
-func newUnixListener(addr string) (net.Listener, error) {
-	if err := os.Remove(addr); err != nil && !os.IsNotExist(err) {
-		return nil, err
-	}
-	l, err := net.Listen("unix", addr)
-	if err != nil {
-		return nil, err
-	}
-	return l, nil
-}
-
-// getConfig creates a *rest.Config for talking to a Kubernetes apiserver.
-//
-// Config precedence
-//
-// * KUBECONFIG environment variable pointing at a file
-// * In-cluster config if running in cluster
-// * $HOME/.kube/config if exists
-func getConfig() (*rest.Config, error) {
-	configFromFlags := func(kubeConfig string) (*rest.Config, error) {
-		if _, err := os.Stat(kubeConfig); err != nil {
-			return nil, fmt.Errorf("cannot stat kubeconfig %q", kubeConfig)
-		}
-		return clientcmd.BuildConfigFromFlags("", kubeConfig)
-	}
-
-	// If an env variable is specified with the config location, use that
-	kubeConfig := os.Getenv("KUBECONFIG")
-	if len(kubeConfig) > 0 {
-		return configFromFlags(kubeConfig)
-	}
-	// If no explicit location, try the in-cluster config
-	if c, err := rest.InClusterConfig(); err == nil {
-		return c, nil
-	}
-	// If no in-cluster config, try the default location in the user's home directory
-	if usr, err := user.Current(); err == nil {
-		kubeConfig := filepath.Join(usr.HomeDir, ".kube", "config")
-		return configFromFlags(kubeConfig)
-	}
-
-	return nil, fmt.Errorf("could not locate a kubeconfig")
-}
-
-func disableSystemTuned() {
-	var (
-		stdout bytes.Buffer
-		stderr bytes.Buffer
-	)
-	klog.V(1).Infof("disabling system tuned...")
-	cmd := exec.Command("/usr/bin/systemctl", "disable", "tuned", "--now")
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
-	err := cmd.Run()
-	if err != nil {
-		klog.V(1).Infof("failed to disable system tuned: %s", stderr.String()) // do not use log.Printf(), tuned has its own timestamping
-	}
-}
-
-// This function is for backward-compatibility with older versions of NTO, it will be removed.
-func profilesExtractCM() error {
-	klog.Infof("extracting tuned profiles from %s", tunedProfilesConfigMap)
-
-	tunedProfilesYaml, err := ioutil.ReadFile(tunedProfilesConfigMap)
-	if err != nil {
-		// This error is no longer fatal since we support profiles in the "rendered" Tuned object;
-		// the file may simply not exist when running the latest NTO
-		return nil
-	}
-
-	mProfiles := make(map[string]string)
-
-	err = yaml.Unmarshal(tunedProfilesYaml, &mProfiles)
-	if err != nil {
-		return fmt.Errorf("failed to parse tuned profiles ConfigMap file %q: %v", tunedProfilesConfigMap, err)
-	}
-
-	for key, value := range mProfiles {
-		profileDir := fmt.Sprintf("%s/%s", tunedProfilesDir, key)
-		profileFile := fmt.Sprintf("%s/%s", profileDir, "tuned.conf")
-
-		if err = mkdir(profileDir); err != nil {
-			return fmt.Errorf("failed to create tuned profile directory %q: %v", profileDir, err)
-		}
-
-		f, err := os.Create(profileFile)
-		if err != nil {
-			return fmt.Errorf("failed to create tuned profile file %q: %v", profileFile, err)
-		}
-		defer f.Close()
-		if _, err = f.WriteString(value); err != nil {
-			return fmt.Errorf("failed to write tuned profile file %q: %v", profileFile, err)
-		}
-	}
-	return nil
-}
-
-func profilesExtract(profiles []tunedv1.TunedProfile) error {
-	klog.Infof("extracting tuned profiles")
-
-	for index, profile := range profiles {
-		if profile.Name == nil {
-			klog.Warningf("profilesExtract(): profile name missing for profile %v", index)
-			continue
-		}
-		if profile.Data == nil {
-			klog.Warningf("profilesExtract(): profile data missing for profile %v", index)
-			continue
-		}
-		profileDir := fmt.Sprintf("%s/%s", tunedProfilesDir, *profile.Name)
-		profileFile := fmt.Sprintf("%s/%s", profileDir, "tuned.conf")
-
-		if err := mkdir(profileDir); err != nil {
-			return fmt.Errorf("failed to create tuned profile directory %q: %v", profileDir, err)
-		}
-
-		f, err := os.Create(profileFile)
-		if err != nil {
-			return fmt.Errorf("failed to create tuned profile file %q: %v", profileFile, err)
-		}
-		defer f.Close()
-		if _, err = f.WriteString(*profile.Data); err != nil {
-			return fmt.Errorf("failed to write tuned profile file %q: %v", profileFile, err)
-		}
-	}
-
-	return nil
-}
-
-func openshiftTunedPidFileWrite() error {
-	if err := mkdir(openshiftTunedRunDir); err != nil {
-		return fmt.Errorf("failed to create %s run directory %q: %v", programName, openshiftTunedRunDir, err)
-	}
-	f, err := os.Create(openshiftTunedPidFile)
-	if err != nil {
-		return fmt.Errorf("failed to create %s pid file %q: %v", programName, openshiftTunedPidFile, err)
-	}
-	defer f.Close()
-	if _, err = f.WriteString(strconv.Itoa(os.Getpid())); err != nil {
-		return fmt.Errorf("failed to write %s pid file %q: %v", programName, openshiftTunedPidFile, err)
-	}
-	return nil
-}
-
-func tunedRecommendFileWrite(profileName string) error {
-	klog.V(2).Infof("tunedRecommendFileWrite(): %s", profileName)
-	if err := mkdir(tunedRecommendDir); err != nil {
-		return fmt.Errorf("failed to create directory %q: %v", tunedRecommendDir, err)
-	}
-	f, err := os.Create(tunedRecommendFile)
-	if err != nil {
-		return fmt.Errorf("failed to create file %q: %v", tunedRecommendFile, err)
-	}
-	defer f.Close()
-	if _, err = f.WriteString(fmt.Sprintf("[%s]\n%s=.*\n", profileName, tunedRecommendFile)); err != nil {
-		return fmt.Errorf("failed to write file %q: %v", tunedRecommendFile, err)
-	}
-	return nil
-}
-
-func tunedCreateCmd() *exec.Cmd {
-	return exec.Command("/usr/sbin/tuned", "--no-dbus")
-}
-
-func tunedRun() {
-	klog.Infof("starting tuned...")
-
-	defer func() {
-		tunedExit <- true
-	}()
-
-	cmdReader, err := cmd.StderrPipe()
-	if err != nil {
-		klog.Errorf("error creating StderrPipe for tuned: %v", err)
-		return
-	}
-
-	scanner := bufio.NewScanner(cmdReader)
-	go func() {
-		for scanner.Scan() {
-			fmt.Printf("%s\n", scanner.Text())
-		}
-	}()
-
-	err = cmd.Start()
-	if err != nil {
-		klog.Errorf("error starting tuned: %v", err)
-		return
-	}
-
-	err = cmd.Wait()
-	if err != nil {
-		// The command exited with non 0 exit status, e.g. terminated by a signal
-		klog.Errorf("error waiting for tuned: %v", err)
-		return
-	}
-
-	return
-}
-
-func tunedStop(s *sockAccepted) error {
-	if cmd == nil {
-		// Looks like there has been a termination signal prior to starting tuned
-		return nil
-	}
-	if cmd.Process != nil {
-		klog.V(1).Infof("sending TERM to PID %d", cmd.Process.Pid)
-		cmd.Process.Signal(syscall.SIGTERM)
-	} else {
-		// This should never happen
-		return fmt.Errorf("cannot find the tuned process!")
-	}
-	// Wait for tuned process to stop -- this will enable node-level tuning rollback
-	<-tunedExit
-	klog.V(1).Infof("tuned process terminated")
-
-	if s != nil {
-		// This was a socket-initiated shutdown; indicate a successful settings rollback
-		ok := []byte{'o', 'k'}
-		_, err := (*s).conn.Write(ok)
-		if err != nil {
-			return fmt.Errorf("cannot write a response via %q: %v", openshiftTunedSocket, err)
-		}
-	}
-
-	return nil
-}
-
-func tunedReload() error {
-	if cmd == nil {
-		// Tuned hasn't been started by openshift-tuned, start it
-		cmd = tunedCreateCmd()
-		go tunedRun()
-		return nil
-	}
-
-	klog.Infof("reloading tuned...")
-
-	if cmd.Process != nil {
-		klog.Infof("sending HUP to PID %d", cmd.Process.Pid)
-		err := cmd.Process.Signal(syscall.SIGHUP)
-		if err != nil {
-			return fmt.Errorf("error sending SIGHUP to PID %d: %v\n", cmd.Process.Pid, err)
-		}
-	} else {
-		// This should never happen
-		return fmt.Errorf("cannot find the tuned process!")
-	}
-
-	return nil
-}
-
-func getActiveProfile() (string, error) {
-	var responseString = ""
-
-	f, err := os.Open(tunedActiveProfileFile)
-	if err != nil {
-		return "", fmt.Errorf("error opening tuned active profile file %s: %v", tunedActiveProfileFile, err)
-	}
-	defer f.Close()
-
-	var scanner = bufio.NewScanner(f)
-	for scanner.Scan() {
-		responseString = strings.TrimSpace(scanner.Text())
-	}
-
-	return responseString, nil
-}
-
-func getRecommendedProfile() (string, error) {
-	var stdout, stderr bytes.Buffer
-
-	klog.V(1).Infof("getting recommended profile...")
-	cmd := exec.Command("/usr/sbin/tuned-adm", "recommend")
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
-	err := cmd.Run()
-	if err != nil {
-		return "", fmt.Errorf("error getting recommended profile: %v: %v", err, stderr.String())
-	}
-
-	responseString := strings.TrimSpace(stdout.String())
-	return responseString, nil
-}
-
-func timedTunedReloader(tuned *tunedState) (err error) {
-	var reload bool
-
-	// Check whether reload of tuned is really necessary due to a profile change
-	if tuned.change.profile {
-		// Profile changed
-		var activeProfile, recommendedProfile string
-		tuned.change.profile = false
-		if activeProfile, err = getActiveProfile(); err != nil {
-			return err
-		}
-		if recommendedProfile, err = getRecommendedProfile(); err != nil {
-			return err
-		}
-		if activeProfile != recommendedProfile {
-			klog.V(1).Infof("active profile (%s) != recommended profile (%s)", activeProfile, recommendedProfile)
-			recommendedProfileDir := tunedProfilesDir + "/" + recommendedProfile
-			if _, err := os.Stat(recommendedProfileDir); os.IsNotExist(err) {
-				// Workaround for tuned BZ1774645; do not send SIGHUP to tuned if the profile directory doesn't exist
-				klog.V(1).Infof("tuned profile directory %q does not exist", recommendedProfileDir)
-				return nil // retry later on a filesystem event
-			}
-			reload = true
-		} else {
-			klog.V(1).Infof("active and recommended profile (%s) match; profile change will not trigger profile reload", activeProfile)
-		}
-	}
-	if tuned.change.rendered {
-		// The "rendered" tuned object changed
-		tuned.change.rendered = false
-		reload = true
-	}
-
-	// Check tuned profiles file changes
-	if supportCM {
-		// Check tuned profiles file changes
-		if tuned.change.cfg {
-			tuned.change.cfg = false
-			if err = profilesExtractCM(); err != nil {
-				return err
-			}
-			reload = true
-		}
-	}
-	if reload {
-		err = tunedReload()
-	}
-	return err
-}
-
-func getTuned(obj interface{}) (tuned *tunedv1.Tuned, err error) {
-	tuned, ok := obj.(*tunedv1.Tuned)
-	if !ok {
-		return nil, fmt.Errorf("could not convert object to a tuned object: %+v", obj)
-	}
-	return tuned, nil
-}
-
-func getTunedProfile(obj interface{}) (profile *tunedv1.Profile, err error) {
-	profile, ok := obj.(*tunedv1.Profile)
-	if !ok {
-		return nil, fmt.Errorf("could not convert object to a tuned profile object: %+v", obj)
-	}
-	return profile, nil
-}
-
-func profileEventHandler(tuned *tunedState) cache.ResourceEventHandlerFuncs {
-	return cache.ResourceEventHandlerFuncs{
-		AddFunc: func(obj interface{}) {
-			p, err := getTunedProfile(obj)
-			if err != nil {
-				klog.Errorf("%s", err.Error())
-				return
-			}
-			klog.V(1).Infof("profile %q added, tuned profile requested: %s", p.ObjectMeta.Name, p.Spec.Config.TunedProfile)
-			// When moving this call elsewhere, remember it is undesirable to disable system tuned
-			// on nodes that should not be managed by openshift-tuned
-			disableSystemTuned()
-			err = tunedRecommendFileWrite(p.Spec.Config.TunedProfile)
-			if err != nil {
-				klog.Errorf("%s", err.Error())
-				return
-			}
-			tuned.change.profile = true
-		},
-		UpdateFunc: func(objOld, objNew interface{}) {
-			pNew, err := getTunedProfile(objNew)
-			if err != nil {
-				klog.Errorf("%s", err.Error())
-				return
-			}
-			pOld, err := getTunedProfile(objOld)
-			if err != nil {
-				klog.Errorf("%s", err.Error())
-				return
-			}
-			if pNew.Spec.Config.TunedProfile == pOld.Spec.Config.TunedProfile {
-				return
-			}
-			klog.V(1).Infof("profile %q changed, tuned profile requested: %s", pNew.ObjectMeta.Name, pNew.Spec.Config.TunedProfile)
-			err = tunedRecommendFileWrite(pNew.Spec.Config.TunedProfile)
-			if err != nil {
-				klog.Errorf("%s", err.Error())
-				return
-			}
-			tuned.change.profile = true
-		},
-		DeleteFunc: func(obj interface{}) {
-			p, err := getTunedProfile(obj)
-			if err != nil {
-				klog.Errorf("%s", err.Error())
-				return
-			}
-			klog.V(1).Infof("profile %q deleted, keeping the old tuned profile: %s", p.ObjectMeta.Name, p.Spec.Config.TunedProfile)
-		},
-	}
-}
-
-func tunedEventHandler(tuned *tunedState) cache.ResourceEventHandlerFuncs {
-	return cache.ResourceEventHandlerFuncs{
-		AddFunc: func(obj interface{}) {
-			t, err := getTuned(obj)
-			if err != nil {
-				klog.Errorf("%s", err.Error())
-				return
-			}
-			klog.V(1).Infof("tuned %q added", t.ObjectMeta.Name)
-			err = profilesExtract(t.Spec.Profile)
-			if err != nil {
-				klog.Errorf("%s", err.Error())
-				return
-			}
-			tuned.change.rendered = true
-		},
-		UpdateFunc: func(objOld, objNew interface{}) {
-			tNew, err := getTuned(objNew)
-			if err != nil {
-				klog.Errorf("%s", err.Error())
-				return
-			}
-			tOld, err := getTuned(objOld)
-			if err != nil {
-				klog.Errorf("%s", err.Error())
-				return
-			}
-			// TODO: after merging the operator and operand repos, do not check for equality
-			// and extract the profiles here; just enqueue the change and get the new object
-			// from cache
-			if reflect.DeepEqual(tNew.Spec.Profile, tOld.Spec.Profile) {
-				// Profiles in the cluster-wide "rendered" Tuned CR did not change
-				return
-			}
-			klog.V(1).Infof("tuned %q changed", tNew.ObjectMeta.Name)
-			err = profilesExtract(tNew.Spec.Profile)
-			if err != nil {
-				klog.Errorf("%s", err.Error())
-				return
-			}
-			tuned.change.rendered = true
-		},
-		DeleteFunc: func(obj interface{}) {
-			t, err := getTuned(obj)
-			if err != nil {
-				klog.Errorf("%s", err.Error())
-				return
-			}
-			klog.V(1).Infof("tuned %q deleted, keeping the old tuned profile", t.ObjectMeta.Name)
-		},
-	}
-}
-
-func changeWatcher() (err error) {
-	var (
-		tuned     tunedState
-		lStop     bool
-		nodeName  string          = flag.Args()[0]
-		profileFS fields.Selector = fields.SelectorFromSet(fields.Set{"metadata.name": nodeName})
-		tunedFS   fields.Selector = fields.SelectorFromSet(fields.Set{"metadata.name": tunedv1.TunedRenderedResourceName})
-	)
-
-	if supportCM {
-		err = profilesExtractCM()
-		if err != nil {
-			return err
-		}
-	}
-
-	kubeConfig, err := getConfig()
-	if err != nil {
-		return err
-	}
-
-	cs, err := tunedclientset.NewForConfig(kubeConfig)
-	if err != nil {
-		return err
-	}
-
-	// Perform an initial list and start a watch on Profiles in operand namespace
-	profileLW := cache.NewListWatchFromClient(cs.TunedV1().RESTClient(), "Profiles", operandNamespace, profileFS)
-	tunedLW := cache.NewListWatchFromClient(cs.TunedV1().RESTClient(), "Tuneds", operandNamespace, tunedFS)
-
-	stop := make(chan struct{})
-	defer close(stop)
-
-	siProfile := cache.NewSharedInformer(profileLW, &tunedv1.Profile{}, 0)
-	siProfile.AddEventHandler(profileEventHandler(&tuned))
-	go siProfile.Run(stop)
-
-	siTuned := cache.NewSharedInformer(tunedLW, &tunedv1.Tuned{}, 0)
-	siTuned.AddEventHandler(tunedEventHandler(&tuned))
-	go siTuned.Run(stop)
-
-	// Create a ticker to extract new profiles and possibly reload tuned;
-	// this also rate-limits reloads to a maximum of profileExtractInterval reloads/s
-	tickerReload := time.NewTicker(time.Second * time.Duration(profileExtractInterval))
-	defer tickerReload.Stop()
-
-	// Watch for filesystem changes on tuned profiles and recommend.conf file(s)
-	wFs, err := fsnotify.NewWatcher()
-	if err != nil {
-		return fmt.Errorf("failed to create filesystem watcher: %v", err)
-	}
-	defer wFs.Close()
-
-	// Register fsnotify watchers
-	for _, element := range fileWatch {
-		err = wFs.Add(element)
-		if err != nil {
-			return fmt.Errorf("failed to start watching %q: %v", element, err)
-		}
-	}
-
-	l, err := newUnixListener(openshiftTunedSocket)
-	if err != nil {
-		return fmt.Errorf("cannot create %q listener: %v", openshiftTunedSocket, err)
-	}
-	defer func() {
-		lStop = true
-		l.Close()
-	}()
-
-	sockConns := make(chan sockAccepted, 1)
-	go func() {
-		for {
-			conn, err := l.Accept()
-			if lStop {
-				// The listener was closed on the return from mainLoop(); exit the goroutine
-				return
-			}
-			sockConns <- sockAccepted{conn, err}
-		}
+		cancel()
 	}()
-
-	for {
-		select {
-		case <-done:
-			// Termination signal received, stop
-			klog.V(2).Infof("changeWatcher done")
-			if err := tunedStop(nil); err != nil {
-				klog.Errorf("%s", err.Error())
-			}
-			return nil
-
-		case s := <-sockConns:
-			if s.err != nil {
-				return fmt.Errorf("connection accept error: %v", err)
-			}
-
-			buf := make([]byte, len("stop"))
-			nr, _ := s.conn.Read(buf)
-			data := buf[0:nr]
-
-			if string(data) == "stop" {
-				if err := tunedStop(&s); err != nil {
-					klog.Errorf("%s", err.Error())
-				}
-				return nil
-			}
-
-		case <-tunedExit:
-			cmd = nil // cmd.Start() cannot be used more than once
-			return fmt.Errorf("tuned process exitted")
-
-		case fsEvent := <-wFs.Events:
-			klog.V(2).Infof("fsEvent")
-			// Ignore Write and Create events, wait for the removal of the old ConfigMap to trigger reload
-			if fsEvent.Op&fsnotify.Remove == fsnotify.Remove {
-				klog.V(1).Infof("remove event on: %s", fsEvent.Name)
-				tuned.change.cfg = true
-			}
-
-		case err := <-wFs.Errors:
-			return fmt.Errorf("error watching filesystem: %v", err)
-
-		case <-tickerReload.C:
-			klog.V(2).Infof("tickerReload.C")
-			if err := timedTunedReloader(&tuned); err != nil {
-				return err
-			}
-		}
-	}
-
-	return nil
-}
-
-func retryLoop() (err error) {
-	const (
-		errsMax        = 5  // the maximum number of consecutive errors within errsMaxWithinSeconds
-		sleepRetryInit = 10 // the initial retry period [s]
-	)
-	var (
-		errs       int
-		sleepRetry int64 = sleepRetryInit
-		// sum of the series: S_n = x(1)*(q^n-1)/(q-1) + add 60s for each changeWatcher() call
-		errsMaxWithinSeconds int64 = (sleepRetry*int64(math.Pow(2, errsMax)) - sleepRetry) + errsMax*60
-	)
-	errsTimeStart := time.Now().Unix()
-	for {
-		err = changeWatcher()
-		if err == nil {
-			break
-		}
-
-		select {
-		case <-done:
-			return err
-		default:
-		}
-
-		klog.Errorf("%s", err.Error())
-		sleepRetry *= 2
-		klog.V(1).Infof("increased retry period to %d", sleepRetry)
-		if errs++; errs >= errsMax {
-			now := time.Now().Unix()
-			if (now - errsTimeStart) <= errsMaxWithinSeconds {
-				klog.Errorf("seen %d errors in %d seconds (limit was %d), terminating...", errs, now-errsTimeStart, errsMaxWithinSeconds)
-				break
-			}
-			errs = 0
-			sleepRetry = sleepRetryInit
-			errsTimeStart = time.Now().Unix()
-			klog.V(1).Infof("initialized retry period to %d", sleepRetry)
-		}
-
-		select {
-		case <-done:
-			return nil
-		case <-time.After(time.Second * time.Duration(sleepRetry)):
-			continue
-		}
-	}
-	return err
+	return ctx, sigs
 }
 
 func main() {
-	parseCmdOpts()
-
-	if *boolVersion {
-		fmt.Fprintf(os.Stderr, "%s %s\n", programName, version)
-		os.Exit(0)
-	}
-
-	if len(flag.Args()) != 1 {
-		flag.Usage()
-		os.Exit(1)
-	}
-
-	err := openshiftTunedPidFileWrite()
-	if err != nil {
-		panic(err.Error())
-	}
+	klog.InitFlags(nil)
+	opts.Version = version
+	opts.GitCommit = gitCommit
 
-	sigs := signalHandler()
-	err = retryLoop()
-	signal.Stop(sigs)
-	if err != nil {
-		panic(err.Error())
-	}
+	os.Exit(commandRun(os.Args[1:]))
 }
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"fmt" // Println()
+	"os"  // os.Stdout
+
+	"github.com/spf13/pflag"
+
+	"github.com/openshift/openshift-tuned/pkg/recommend"
+	"github.com/openshift/openshift-tuned/pkg/tuned"
+)
+
+// Functions
+// recommendFlags adds the options of the "recommend" subcommand to fs.
+func recommendFlags(fs *pflag.FlagSet) {
+	flagsProfiles(fs)
+	fs.Bool("explain", false, "print every recommend rule evaluated and why it matched or failed")
+}
+
+// recommendCmd implements the "recommend" subcommand.
+func recommendCmd(fs *pflag.FlagSet) int {
+	explain, _ := fs.GetBool("explain")
+
+	re := tuned.New(opts).RecommendExplain()
+	if explain {
+		recommend.ExplainPrint(os.Stdout, re)
+	} else if len(re.Error) == 0 {
+		fmt.Println(re.Profile)
+	}
+	if len(re.Error) > 0 {
+		if !explain {
+			fmt.Fprintf(os.Stderr, "%s\n", re.Error)
+		}
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"fmt"           // Println()
+	"io/ioutil"     // ioutil.TempDir()
+	"os"            // os.RemoveAll()
+	"path/filepath" // filepath.Walk()
+	"strings"       // strings.HasPrefix()
+
+	"github.com/spf13/pflag"
+
+	"github.com/openshift/openshift-tuned/pkg/profile"
+	"github.com/openshift/openshift-tuned/pkg/recommend"
+	"github.com/openshift/openshift-tuned/pkg/tuned"
+)
+
+// Functions
+// renderTreePrint prints the files below dir as if dir were root.
+func renderTreePrint(dir string, root string) error {
+	return filepath.Walk(dir, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if strings.HasPrefix(fi.Name(), ".") && path != dir {
+			// Staging leftovers, not seen by tuned
+			if fi.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		name := filepath.Join(root, rel)
+		if fi.IsDir() {
+			name += "/"
+		}
+		fmt.Println(name)
+		return nil
+	})
+}
+
+// renderFlags adds the options of the "render" subcommand to fs.
+func renderFlags(fs *pflag.FlagSet) {
+	flagsProfiles(fs)
+	flagsSigning(fs)
+	fs.String("profiles", "", "tuned profiles ConfigMap file, e.g. tuned-profiles.yaml")
+	fs.String("labels", "", "node labels file to evaluate the recommend rules with; it stands for the condition files of the same base name and the --labels-path file")
+	fs.String("labels-path", "", "condition file of the recommend rules the --labels file stands for")
+	fs.String("profile", "", "tuned profile requested by the node's Profile object, if any")
+	fs.StringP("output", "o", "", "directory to render to and keep; a temporary directory by default")
+}
+
+// renderCmd implements the "render" subcommand.
+func renderCmd(fs *pflag.FlagSet) int {
+	profiles, _ := fs.GetString("profiles")
+	labels, _ := fs.GetString("labels")
+	labelsPath, _ := fs.GetString("labels-path")
+	requested, _ := fs.GetString("profile")
+	outDir, _ := fs.GetString("output")
+
+	if len(profiles) == 0 {
+		fs.Usage()
+		return tuned.ExitConfig
+	}
+	mProfiles, err := profile.ConfigMapRead(profiles)
+	if err == nil && mProfiles == nil {
+		err = fmt.Errorf("%q not found", profiles)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err.Error())
+		return 1
+	}
+
+	dir := outDir
+	if len(dir) == 0 {
+		if dir, err = ioutil.TempDir("", programName+"-render-"); err != nil {
+			fmt.Fprintf(os.Stderr, "%s\n", err.Error())
+			return 1
+		}
+		defer os.RemoveAll(dir)
+	}
+	read := func(path string) ([]byte, error) {
+		if len(labels) > 0 && (path == labelsPath || filepath.Base(path) == filepath.Base(labels)) {
+			return ioutil.ReadFile(labels)
+		}
+		return ioutil.ReadFile(path)
+	}
+
+	o := opts
+	o.ProfilesDir = dir
+	r, err := tuned.New(o).Render(mProfiles, requested, read)
+	for _, p := range r.Problems {
+		fmt.Fprintln(os.Stderr, p)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err.Error())
+		return 1
+	}
+	if err = renderTreePrint(dir, opts.ProfilesDir); err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err.Error())
+		return 1
+	}
+	fmt.Println()
+	recommend.ExplainPrint(os.Stdout, r.Recommendation)
+	if len(r.Recommendation.Error) > 0 {
+		return 1
+	}
+	return 0
+}
@@ -6,10 +6,12 @@ require (
 	github.com/fsnotify/fsnotify v1.4.7
 	github.com/imdario/mergo v0.3.7 // indirect
 	github.com/openshift/cluster-node-tuning-operator v0.0.0-20191030122009-87849bd2fb06
+	github.com/spf13/pflag v1.0.3
 	golang.org/x/crypto v0.0.0-20190701094942-4def268fd1a4 // indirect
-	golang.org/x/sys v0.0.0-20190712062909-fae7ac547cb7 // indirect
+	golang.org/x/sys v0.0.0-20190712062909-fae7ac547cb7
 	golang.org/x/time v0.0.0-20190308202827-9d24e82272b4 // indirect
 	gopkg.in/yaml.v2 v2.2.4
+	k8s.io/api v0.0.0-20191016110408-35e52d86657a
 	k8s.io/apimachinery v0.0.0-20191004115801-a2eda9f80ab8
 	k8s.io/client-go v0.0.0-20190918160344-1fbdaa4c8d90
 	k8s.io/code-generator v0.0.0-20191029223907-9f431a56fdbc
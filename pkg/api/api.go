@@ -0,0 +1,54 @@
+// Package api serves the openshift-tuned HTTP API.
+package api
+
+import (
+	"encoding/json" // json.NewEncoder()
+	"fmt"           // Sprintf()
+	"net/http"      // http.ServeMux
+
+	"k8s.io/klog"
+)
+
+// Types
+// Server serves the HTTP API endpoints registered with it.
+type Server struct {
+	mux *http.ServeMux
+}
+
+// Functions
+// NewServer creates a Server without any endpoints.
+func NewServer() *Server {
+	return &Server{mux: http.NewServeMux()}
+}
+
+// WriteJSON writes v as a JSON response.
+func WriteJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		klog.Errorf("failed to write API response: %v", err)
+	}
+}
+
+// Handle registers handler for endpoint path.
+func (s *Server) Handle(path string, handler http.HandlerFunc) {
+	s.mux.HandleFunc(path, handler)
+}
+
+// HandleJSON registers endpoint path responding with the JSON encoding of the
+// value returned by get.
+func (s *Server) HandleJSON(path string, get func(r *http.Request) interface{}) {
+	s.mux.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
+		WriteJSON(w, get(r))
+	})
+}
+
+// Serve starts serving the API on port in the background.
+func (s *Server) Serve(port int) {
+	addr := fmt.Sprintf(":%d", port)
+	go func() {
+		klog.Infof("serving the API on %s", addr)
+		if err := http.ListenAndServe(addr, s.mux); err != nil {
+			klog.Errorf("failed to serve the API on %s: %v", addr, err)
+		}
+	}()
+}
@@ -0,0 +1,67 @@
+// Package audit writes an append-only log of the actions openshift-tuned takes on
+// the node, one JSON object per line.
+package audit
+
+import (
+	"encoding/json" // json.Marshal()
+	"fmt"           // Errorf()
+	"time"          // time.Time
+
+	"github.com/openshift/openshift-tuned/pkg/rotate"
+)
+
+// Types
+// Entry is a single action recorded in the audit log.
+type Entry struct {
+	Time time.Time `json:"time"`
+	// Action is what was done, e.g. "extract", "signal" or "socket-command".
+	Action string `json:"action"`
+	// Actor is the identity which requested the action, e.g. "openshift-tuned"
+	// for actions of its own or the peer of a control socket connection.
+	Actor string `json:"actor"`
+	// Object is what the action was taken on, e.g. the signal and PID.
+	Object string `json:"object,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Log is an audit log rotated by size.  A nil *Log records nothing.
+type Log struct {
+	f *rotate.File
+}
+
+// Functions
+// Open opens the audit log path for appending.  Once it grows over maxSize bytes,
+// it is rotated to path.1, keeping up to backups rotated files.
+func Open(path string, maxSize int64, backups int) (*Log, error) {
+	f, err := rotate.Open(path, maxSize, backups)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log: %v", err)
+	}
+	return &Log{f: f}, nil
+}
+
+// Record appends e to the audit log; a zero e.Time is set to the current time.
+func (l *Log) Record(e Entry) error {
+	if l == nil {
+		return nil
+	}
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	if _, err := l.f.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to record in the audit log: %v", err)
+	}
+	return nil
+}
+
+// Close closes the audit log.
+func (l *Log) Close() error {
+	if l == nil {
+		return nil
+	}
+	return l.f.Close()
+}
@@ -0,0 +1,156 @@
+// Package featuregate enables and disables openshift-tuned subsystems per cluster,
+// so that new subsystems can be shipped disabled and existing ones turned off.
+package featuregate
+
+import (
+	"fmt"     // Errorf()
+	"sort"    // sort.Strings()
+	"strconv" // strconv.ParseBool()
+	"strings" // strings.Split()
+	"sync"    // sync.RWMutex
+)
+
+// Types
+// Feature is the name of a feature gate, e.g. "RecommendCache".
+type Feature string
+
+// Stage is the maturity of a feature.
+type Stage string
+
+// Spec describes a feature gate.
+type Spec struct {
+	// Default is whether the feature is enabled unless set otherwise.
+	Default bool
+	Stage   Stage
+}
+
+// Gates are the states of the known feature gates, safe for concurrent use.
+type Gates struct {
+	sync.RWMutex
+	known   map[Feature]Spec
+	enabled map[Feature]bool
+}
+
+// Constants
+const (
+	Alpha Stage = "Alpha"
+	Beta  Stage = "Beta"
+	GA    Stage = "GA"
+)
+
+// Functions
+// New returns the gates of the known features with their defaults.
+func New(known map[Feature]Spec) *Gates {
+	return &Gates{known: known, enabled: defaults(known)}
+}
+
+// defaults returns the default states of the known features.
+func defaults(known map[Feature]Spec) map[Feature]bool {
+	enabled := map[Feature]bool{}
+	for f, spec := range known {
+		enabled[f] = spec.Default
+	}
+	return enabled
+}
+
+// Set sets the gates of s, a comma-separated list of "Feature=bool", e.g.
+// "RecommendCache=false,CanaryProbes=true".  Setting an unknown feature or a GA
+// feature to false is an error; no gate is changed then.
+func (g *Gates) Set(s string) error {
+	g.Lock()
+	defer g.Unlock()
+
+	enabled := map[Feature]bool{}
+	for f, e := range g.enabled {
+		enabled[f] = e
+	}
+	if err := g.parse(s, enabled); err != nil {
+		return err
+	}
+	g.enabled = enabled
+	return nil
+}
+
+// Reset sets the gates not in s back to their defaults and the others like
+// Set() does.  On an error, no gate is changed.
+func (g *Gates) Reset(s string) error {
+	g.Lock()
+	defer g.Unlock()
+
+	enabled := defaults(g.known)
+	if err := g.parse(s, enabled); err != nil {
+		return err
+	}
+	g.enabled = enabled
+	return nil
+}
+
+// parse sets the gates of s in enabled, see Set().
+func (g *Gates) parse(s string, enabled map[Feature]bool) error {
+	for _, kv := range strings.Split(s, ",") {
+		kv = strings.TrimSpace(kv)
+		if len(kv) == 0 {
+			continue
+		}
+		parts := strings.SplitN(kv, "=", 2)
+		if len(parts) != 2 {
+			return fmt.Errorf("invalid feature gate %q, expected Feature=true|false", kv)
+		}
+		f := Feature(strings.TrimSpace(parts[0]))
+		spec, ok := g.known[f]
+		if !ok {
+			return fmt.Errorf("unknown feature gate %q, known: %s", f, strings.Join(g.names(), ", "))
+		}
+		on, err := strconv.ParseBool(strings.TrimSpace(parts[1]))
+		if err != nil {
+			return fmt.Errorf("invalid value of feature gate %q: %v", f, err)
+		}
+		if spec.Stage == GA && !on {
+			return fmt.Errorf("feature %q is GA and cannot be disabled", f)
+		}
+		enabled[f] = on
+	}
+	return nil
+}
+
+// Enabled returns true if feature f is enabled.  A nil *Gates enables no feature.
+func (g *Gates) Enabled(f Feature) bool {
+	if g == nil {
+		return false
+	}
+	g.RLock()
+	defer g.RUnlock()
+	return g.enabled[f]
+}
+
+// Map returns the states of all known features.
+func (g *Gates) Map() map[string]bool {
+	g.RLock()
+	defer g.RUnlock()
+	m := map[string]bool{}
+	for f, enabled := range g.enabled {
+		m[string(f)] = enabled
+	}
+	return m
+}
+
+// names returns the names of the known features, sorted.
+func (g *Gates) names() []string {
+	var names []string
+	for f := range g.known {
+		names = append(names, string(f))
+	}
+	sort.Strings(names)
+	return names
+}
+
+// String returns the states of all known features in the format of Set().
+func (g *Gates) String() string {
+	g.RLock()
+	defer g.RUnlock()
+	var kvs []string
+	for _, name := range g.names() {
+		kvs = append(kvs, fmt.Sprintf("%s=%t", name, g.enabled[Feature(name)]))
+	}
+	return strings.Join(kvs, ",")
+}
@@ -0,0 +1,202 @@
+package hooks
+
+import (
+	"context"       // context.Context
+	"fmt"           // Sprintf()
+	"io/ioutil"     // ioutil.ReadFile()
+	"os"            // os.IsNotExist()
+	"path/filepath" // filepath.Glob()
+	"sort"          // sort.Ints()
+	"strconv"       // strconv.Atoi()
+	"strings"       // strings.Split()
+
+	"k8s.io/klog"
+)
+
+// Constants
+const (
+	cpuOnlineFile        = "/sys/devices/system/cpu/online"
+	irqAffinityListGlob  = "/proc/irq/*/smp_affinity_list"
+	irqbalanceConfigFile = "/etc/sysconfig/irqbalance"
+	irqbalanceBannedVar  = "IRQBALANCE_BANNED_CPUS"
+)
+
+// Functions
+// CPUListParse parses cpulist s, e.g. "0-3,8", into a sorted list of CPUs.
+func CPUListParse(s string) ([]int, error) {
+	var cpus []int
+
+	seen := map[int]bool{}
+	for _, r := range strings.Split(strings.TrimSpace(s), ",") {
+		r = strings.TrimSpace(r)
+		if len(r) == 0 {
+			continue
+		}
+		bounds := strings.SplitN(r, "-", 2)
+		first, err := strconv.Atoi(bounds[0])
+		if err != nil {
+			return nil, fmt.Errorf("invalid cpulist %q: %v", s, err)
+		}
+		last := first
+		if len(bounds) == 2 {
+			if last, err = strconv.Atoi(bounds[1]); err != nil {
+				return nil, fmt.Errorf("invalid cpulist %q: %v", s, err)
+			}
+		}
+		if first < 0 || last < first {
+			return nil, fmt.Errorf("invalid cpulist %q: bad range %q", s, r)
+		}
+		for cpu := first; cpu <= last; cpu++ {
+			if !seen[cpu] {
+				seen[cpu] = true
+				cpus = append(cpus, cpu)
+			}
+		}
+	}
+	sort.Ints(cpus)
+	return cpus, nil
+}
+
+// CPUListFormat formats sorted cpus as a cpulist, e.g. "0-3,8".
+func CPUListFormat(cpus []int) string {
+	var ranges []string
+
+	for i := 0; i < len(cpus); {
+		j := i
+		for j+1 < len(cpus) && cpus[j+1] == cpus[j]+1 {
+			j++
+		}
+		if i == j {
+			ranges = append(ranges, strconv.Itoa(cpus[i]))
+		} else {
+			ranges = append(ranges, fmt.Sprintf("%d-%d", cpus[i], cpus[j]))
+		}
+		i = j + 1
+	}
+	return strings.Join(ranges, ",")
+}
+
+// CPUMaskFormat formats cpus as a hexadecimal CPU mask in comma-separated 32-bit
+// groups, e.g. "00000000,0000fffc".
+func CPUMaskFormat(cpus []int) string {
+	var groups []string
+
+	words := 1
+	for _, cpu := range cpus {
+		if cpu/32+1 > words {
+			words = cpu/32 + 1
+		}
+	}
+	mask := make([]uint32, words)
+	for _, cpu := range cpus {
+		mask[cpu/32] |= 1 << uint(cpu%32)
+	}
+	for i := words - 1; i >= 0; i-- {
+		groups = append(groups, fmt.Sprintf("%08x", mask[i]))
+	}
+	return strings.Join(groups, ",")
+}
+
+// housekeepingCPUs returns the online CPUs which are not isolated.
+func housekeepingCPUs(isolated []int) ([]int, error) {
+	var housekeeping []int
+
+	data, err := ioutil.ReadFile(cpuOnlineFile)
+	if err != nil {
+		return nil, err
+	}
+	online, err := CPUListParse(string(data))
+	if err != nil {
+		return nil, err
+	}
+	skip := map[int]bool{}
+	for _, cpu := range isolated {
+		skip[cpu] = true
+	}
+	for _, cpu := range online {
+		if !skip[cpu] {
+			housekeeping = append(housekeeping, cpu)
+		}
+	}
+	return housekeeping, nil
+}
+
+// irqRepin moves all movable IRQs off the isolated CPUs.
+func irqRepin(ctx context.Context, hc Context) error {
+	isolated, err := CPUListParse(hc.IsolatedCPUs)
+	if err != nil {
+		return err
+	}
+	if len(isolated) == 0 {
+		return nil
+	}
+	housekeeping, err := housekeepingCPUs(isolated)
+	if err != nil {
+		return err
+	}
+	if len(housekeeping) == 0 {
+		return fmt.Errorf("all online CPUs are isolated, no CPUs left for IRQs")
+	}
+	list := CPUListFormat(housekeeping)
+
+	files, _ := filepath.Glob(irqAffinityListGlob)
+	moved := 0
+	for _, file := range files {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err := ioutil.WriteFile(file, []byte(list+"\n"), 0644); err != nil {
+			// Some IRQs, e.g. per-CPU timers, cannot be moved
+			klog.V(2).Infof("cannot set %s to %s: %v", file, list, err)
+			continue
+		}
+		moved++
+	}
+	if moved == 0 && len(files) > 0 {
+		return fmt.Errorf("none of %d IRQs could be moved to CPUs %s", len(files), list)
+	}
+	klog.Infof("pinned %d of %d IRQs to CPUs %s", moved, len(files), list)
+
+	return nil
+}
+
+// irqbalanceBannedCPUs sets IRQBALANCE_BANNED_CPUS in the irqbalance configuration
+// to the isolated CPUs, so that irqbalance does not move IRQs onto them.
+func irqbalanceBannedCPUs(ctx context.Context, hc Context) error {
+	var lines []string
+
+	isolated, err := CPUListParse(hc.IsolatedCPUs)
+	if err != nil {
+		return err
+	}
+	setting := irqbalanceBannedVar + "=" + CPUMaskFormat(isolated)
+
+	data, err := ioutil.ReadFile(irqbalanceConfigFile)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	found := false
+	for _, line := range strings.Split(strings.TrimRight(string(data), "\n"), "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), irqbalanceBannedVar+"=") {
+			if found {
+				continue
+			}
+			line = setting
+			found = true
+		}
+		lines = append(lines, line)
+	}
+	if !found {
+		lines = append(lines, setting)
+	}
+	content := strings.TrimLeft(strings.Join(lines, "\n"), "\n") + "\n"
+	if content == string(data) {
+		return nil
+	}
+	if err = ioutil.WriteFile(irqbalanceConfigFile, []byte(content), 0644); err != nil {
+		return err
+	}
+	klog.Infof("set %s in %s", setting, irqbalanceConfigFile)
+
+	return nil
+}
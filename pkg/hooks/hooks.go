@@ -0,0 +1,137 @@
+// Package hooks runs actions after tuned applied a profile, so that other node
+// agents can react to the changes, e.g. of the isolated CPUs.
+package hooks
+
+import (
+	"bytes"   // bytes.Buffer
+	"context" // context.WithTimeout()
+	"fmt"     // Errorf()
+	"os"      // os.Environ()
+	"os/exec" // exec.Command()
+	"sort"    // sort.Strings()
+	"strings" // strings.SplitN()
+	"time"    // time.Duration
+
+	"k8s.io/klog"
+
+	"github.com/openshift/openshift-tuned/pkg/process"
+)
+
+// Types
+// Context describes the profile applied to the hooks.
+type Context struct {
+	// Profile is the tuned profile applied.
+	Profile string
+	// IsolatedCPUs is the cpulist of the isolated CPUs, e.g. "2-7,10"; empty if
+	// there are none.
+	IsolatedCPUs string
+}
+
+// Action is a hook action.
+type Action func(ctx context.Context, hc Context) error
+
+// Hook is a named action run after a profile is applied.
+type Hook struct {
+	// Name identifies the hook in logs and results, "kind:location".
+	Name   string
+	action Action
+}
+
+// Result is the outcome of running a hook.
+type Result struct {
+	Name            string  `json:"name"`
+	DurationSeconds float64 `json:"durationSeconds"`
+	Error           string  `json:"error,omitempty"`
+}
+
+// Global variables
+var (
+	// builtins are the actions available as "builtin:<name>" hooks
+	builtins = map[string]Action{
+		"irq-repin":              irqRepin,
+		"irqbalance-banned-cpus": irqbalanceBannedCPUs,
+	}
+)
+
+// Constants
+const (
+	// Timeout is the time a single hook may run for.
+	Timeout = 60 * time.Second
+)
+
+// Functions
+// RegisterBuiltin makes action available as hook "builtin:<name>".
+func RegisterBuiltin(name string, action Action) {
+	builtins[name] = action
+}
+
+// New creates a hook from spec "builtin:<name>" or "exec:<path>"; the
+// executable <path> is run with the Context in the TUNED_PROFILE and
+// TUNED_ISOLATED_CPUS environment variables.
+func New(spec string) (*Hook, error) {
+	parts := strings.SplitN(spec, ":", 2)
+	if len(parts) != 2 || len(parts[1]) == 0 {
+		return nil, fmt.Errorf("invalid hook %q, expected builtin:<name> or exec:<path>", spec)
+	}
+	switch parts[0] {
+	case "builtin":
+		action, ok := builtins[parts[1]]
+		if !ok {
+			var names []string
+			for name := range builtins {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			return nil, fmt.Errorf("unknown builtin hook %q, known: %s", parts[1], strings.Join(names, ", "))
+		}
+		return &Hook{Name: spec, action: action}, nil
+	case "exec":
+		return &Hook{Name: spec, action: execAction(parts[1])}, nil
+	}
+	return nil, fmt.Errorf("unknown hook kind %q in %q, expected builtin or exec", parts[0], spec)
+}
+
+// execAction returns an action running executable path.
+func execAction(path string) Action {
+	return func(ctx context.Context, hc Context) error {
+		var out bytes.Buffer
+
+		cmd := exec.Command(path)
+		cmd.Env = append(os.Environ(), "TUNED_PROFILE="+hc.Profile, "TUNED_ISOLATED_CPUS="+hc.IsolatedCPUs)
+		cmd.Stdout = &out
+		cmd.Stderr = &out
+		if err := process.RunContext(ctx, cmd); err != nil {
+			return fmt.Errorf("%v: %s", err, strings.TrimSpace(out.String()))
+		}
+		if out.Len() > 0 {
+			klog.V(1).Infof("hook %s: %s", path, strings.TrimSpace(out.String()))
+		}
+		return nil
+	}
+}
+
+// Run runs hooks one after another in the given order, each limited to Timeout.
+// A failing hook does not prevent the following hooks from running.  Once ctx is
+// done, the running hook is cancelled and the remaining hooks are not run.
+func Run(ctx context.Context, hooks []*Hook, hc Context) []Result {
+	results := make([]Result, 0, len(hooks))
+
+	for _, h := range hooks {
+		if ctx.Err() != nil {
+			break
+		}
+		start := time.Now()
+		hctx, cancel := context.WithTimeout(ctx, Timeout)
+		err := h.action(hctx, hc)
+		cancel()
+		r := Result{Name: h.Name, DurationSeconds: time.Since(start).Seconds()}
+		if err != nil {
+			r.Error = err.Error()
+			klog.Errorf("hook %s failed for profile %q: %v", h.Name, hc.Profile, err)
+		} else {
+			klog.V(1).Infof("hook %s succeeded for profile %q", h.Name, hc.Profile)
+		}
+		results = append(results, r)
+	}
+	return results
+}
@@ -0,0 +1,150 @@
+// Package layout creates files and directories which cannot be tampered with by
+// unprivileged processes on the node.
+package layout
+
+import (
+	"fmt"           // Errorf()
+	"io/ioutil"     // ioutil.TempFile()
+	"os"            // os.Chmod(), os.Rename(), ...
+	"path/filepath" // filepath.Dir()
+)
+
+// Constants
+const (
+	DirMode        os.FileMode = 0700
+	FileMode       os.FileMode = 0600
+	selinuxEnforce             = "/sys/fs/selinux/enforce"
+)
+
+// Functions
+// selinuxEnabled returns true if SELinux is enabled on the node.
+func selinuxEnabled() bool {
+	_, err := os.Stat(selinuxEnforce)
+	return err == nil
+}
+
+// Verify verifies that path is owned by us, is not a symbolic link and
+// has no group/other permissions, i.e. it cannot be tampered with by unprivileged
+// processes on the node.
+func Verify(path string) error {
+	fi, err := os.Lstat(path)
+	if err != nil {
+		return fmt.Errorf("failed to stat %q: %v", path, err)
+	}
+	if fi.Mode()&os.ModeSymlink != 0 {
+		return fmt.Errorf("%q is a symbolic link", path)
+	}
+	if fi.Mode().Perm()&0077 != 0 {
+		return fmt.Errorf("%q has insecure permissions %v", path, fi.Mode().Perm())
+	}
+	if uid, ok := fileOwner(fi); ok && uid != os.Geteuid() {
+		return fmt.Errorf("%q is owned by UID %d, expected %d", path, uid, os.Geteuid())
+	}
+	return nil
+}
+
+// VerifyDir verifies that directory dir is owned by us, is not a symbolic link
+// and cannot be written to by group/other, i.e. the files we create in it cannot
+// be replaced by unprivileged processes on the node.
+func VerifyDir(dir string) error {
+	fi, err := os.Lstat(dir)
+	if err != nil {
+		return fmt.Errorf("failed to stat %q: %v", dir, err)
+	}
+	if fi.Mode()&os.ModeSymlink != 0 {
+		return fmt.Errorf("%q is a symbolic link", dir)
+	}
+	if !fi.IsDir() {
+		return fmt.Errorf("%q is not a directory", dir)
+	}
+	if fi.Mode().Perm()&0022 != 0 {
+		return fmt.Errorf("%q is writable by group/other, permissions %v", dir, fi.Mode().Perm())
+	}
+	if uid, ok := fileOwner(fi); ok && uid != os.Geteuid() {
+		return fmt.Errorf("%q is owned by UID %d, expected %d", dir, uid, os.Geteuid())
+	}
+	return nil
+}
+
+// Mkdir creates directory dir (and its parents) with strict permissions.  A
+// pre-existing dir, e.g. /etc/tuned, keeps its permissions, but must pass
+// VerifyDir().
+func Mkdir(dir string) error {
+	if _, err := os.Lstat(dir); err == nil {
+		return VerifyDir(dir)
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+	if err := os.MkdirAll(dir, DirMode); err != nil {
+		return err
+	}
+	// Not subject to the umask
+	if err := os.Chmod(dir, DirMode); err != nil {
+		return err
+	}
+	if err := selinuxLabelFromParent(dir); err != nil {
+		return err
+	}
+	return Verify(dir)
+}
+
+// WriteFile atomically writes data to file path with strict permissions.
+// The data is written to a temporary file in the same directory first, which
+// is then labelled, verified and renamed to path.
+func WriteFile(path string, data []byte) error {
+	f, err := ioutil.TempFile(filepath.Dir(path), "."+filepath.Base(path)+".")
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+	defer os.Remove(tmp) // no-op after a successful rename
+
+	if _, err = f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err = f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err = f.Close(); err != nil {
+		return err
+	}
+	if err = os.Chmod(tmp, FileMode); err != nil {
+		return err
+	}
+	if err = selinuxLabelFromParent(tmp); err != nil {
+		return err
+	}
+	if err = Verify(tmp); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, path)
+}
+
+// swapRename replaces target by staged in two renames; target is briefly missing.
+func swapRename(staged, target string) error {
+	old := staged + ".old"
+	if err := os.Rename(target, old); err != nil {
+		return err
+	}
+	if err := os.Rename(staged, target); err != nil {
+		os.Rename(old, target)
+		return err
+	}
+	return os.Rename(old, staged)
+}
+
+// Swap replaces target by staged, e.g. a directory written in full beforehand.
+// Afterwards, staged holds the previous target, if any.  Where supported, both
+// are exchanged atomically, so target is never missing or partially written.
+func Swap(staged, target string) error {
+	if _, err := os.Lstat(target); os.IsNotExist(err) {
+		return os.Rename(staged, target)
+	}
+	if err := exchange(staged, target); err == nil {
+		return nil
+	}
+	return swapRename(staged, target)
+}
@@ -0,0 +1,50 @@
+// +build linux
+
+package layout
+
+import (
+	"fmt"           // Errorf()
+	"os"            // os.FileInfo
+	"path/filepath" // filepath.Dir()
+	"syscall"       // syscall.Stat_t
+
+	"golang.org/x/sys/unix"
+)
+
+// Constants
+const (
+	selinuxXattr     = "security.selinux"
+	selinuxLabelSize = 256
+)
+
+// Functions
+// selinuxLabelFromParent sets the SELinux label of path to the label of its parent
+// directory, i.e. the label the file would get when created by tuned itself.
+func selinuxLabelFromParent(path string) error {
+	if !selinuxEnabled() {
+		return nil
+	}
+	label := make([]byte, selinuxLabelSize)
+	sz, err := unix.Getxattr(filepath.Dir(path), selinuxXattr, label)
+	if err != nil {
+		return fmt.Errorf("failed to get SELinux label of %q: %v", filepath.Dir(path), err)
+	}
+	if err = unix.Lsetxattr(path, selinuxXattr, label[:sz], 0); err != nil {
+		return fmt.Errorf("failed to set SELinux label of %q: %v", path, err)
+	}
+	return nil
+}
+
+// fileOwner returns the UID owning the file described by fi.
+func fileOwner(fi os.FileInfo) (int, bool) {
+	st, ok := fi.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, false
+	}
+	return int(st.Uid), true
+}
+
+// exchange atomically exchanges paths a and b, see renameat2(2) RENAME_EXCHANGE.
+func exchange(a, b string) error {
+	return unix.Renameat2(unix.AT_FDCWD, a, unix.AT_FDCWD, b, unix.RENAME_EXCHANGE)
+}
@@ -0,0 +1,24 @@
+// +build !linux
+
+package layout
+
+import (
+	"errors" // errors.New()
+	"os"     // os.FileInfo
+)
+
+// Functions
+// selinuxLabelFromParent does nothing, SELinux is Linux-specific.
+func selinuxLabelFromParent(path string) error {
+	return nil
+}
+
+// fileOwner returns false, the file owner is not checked on this platform.
+func fileOwner(fi os.FileInfo) (int, bool) {
+	return 0, false
+}
+
+// exchange fails, atomic exchanges are Linux-specific.
+func exchange(a, b string) error {
+	return errors.New("atomic exchange not supported")
+}
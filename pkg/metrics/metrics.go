@@ -0,0 +1,133 @@
+// Package metrics writes openshift-tuned metrics in the Prometheus text exposition format.
+package metrics
+
+import (
+	"bytes"    // bytes.Buffer
+	"fmt"      // Fprintf()
+	"net/http" // http.ResponseWriter
+	"sort"     // sort.Strings()
+	"strconv"  // strconv.FormatFloat()
+	"strings"  // strings.Join()
+
+	"k8s.io/klog"
+)
+
+// Types
+// Sample is a single sample of a metric.
+type Sample struct {
+	Labels map[string]string
+	Value  float64
+}
+
+// Collector writes metrics in the Prometheus text exposition format.
+type Collector func(buf *bytes.Buffer)
+
+// Histogram counts observed values in buckets.  It is not safe for concurrent use.
+type Histogram struct {
+	// Bounds are the upper bounds of the buckets in increasing order.
+	Bounds []float64
+	// Counts are the numbers of observations per bucket, not cumulative; the
+	// last element counts the observations above all Bounds.
+	Counts []uint64
+	Count  uint64
+	Sum    float64
+}
+
+// HistogramSample is a histogram of a metric with labels.
+type HistogramSample struct {
+	Labels    map[string]string
+	Histogram Histogram
+}
+
+// Global variables
+var (
+	// DurationBounds are histogram bucket bounds for durations in seconds.
+	DurationBounds = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60}
+)
+
+// Constants
+const (
+	Prefix = "openshift_tuned_"
+)
+
+// Functions
+func labelsFormat(labels map[string]string) string {
+	if len(labels) == 0 {
+		return ""
+	}
+	pairs := []string{}
+	for k, v := range labels {
+		pairs = append(pairs, fmt.Sprintf("%s=%q", k, v))
+	}
+	sort.Strings(pairs)
+
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+// Write writes metric name (without Prefix) of type typ with its samples to buf.
+func Write(buf *bytes.Buffer, name, typ, help string, samples ...Sample) {
+	name = Prefix + name
+	fmt.Fprintf(buf, "# HELP %s %s\n", name, help)
+	fmt.Fprintf(buf, "# TYPE %s %s\n", name, typ)
+	for _, s := range samples {
+		fmt.Fprintf(buf, "%s%s %g\n", name, labelsFormat(s.Labels), s.Value)
+	}
+}
+
+// NewHistogram returns an empty Histogram with buckets bounded by bounds.
+func NewHistogram(bounds []float64) *Histogram {
+	return &Histogram{Bounds: bounds, Counts: make([]uint64, len(bounds)+1)}
+}
+
+// Observe adds value v to h.
+func (h *Histogram) Observe(v float64) {
+	i := sort.SearchFloat64s(h.Bounds, v)
+	h.Counts[i]++
+	h.Count++
+	h.Sum += v
+}
+
+// Copy returns a copy of h.
+func (h *Histogram) Copy() Histogram {
+	c := *h
+	c.Counts = append([]uint64(nil), h.Counts...)
+	return c
+}
+
+// WriteHistogram writes histogram metric name (without Prefix) with its samples to buf.
+func WriteHistogram(buf *bytes.Buffer, name, help string, samples ...HistogramSample) {
+	name = Prefix + name
+	fmt.Fprintf(buf, "# HELP %s %s\n", name, help)
+	fmt.Fprintf(buf, "# TYPE %s histogram\n", name)
+	for _, s := range samples {
+		labels := map[string]string{}
+		for k, v := range s.Labels {
+			labels[k] = v
+		}
+		cumulative := uint64(0)
+		for i, bound := range s.Histogram.Bounds {
+			cumulative += s.Histogram.Counts[i]
+			labels["le"] = strconv.FormatFloat(bound, 'g', -1, 64)
+			fmt.Fprintf(buf, "%s_bucket%s %d\n", name, labelsFormat(labels), cumulative)
+		}
+		labels["le"] = "+Inf"
+		fmt.Fprintf(buf, "%s_bucket%s %d\n", name, labelsFormat(labels), s.Histogram.Count)
+		fmt.Fprintf(buf, "%s_sum%s %g\n", name, labelsFormat(s.Labels), s.Histogram.Sum)
+		fmt.Fprintf(buf, "%s_count%s %d\n", name, labelsFormat(s.Labels), s.Histogram.Count)
+	}
+}
+
+// Handler returns an HTTP handler serving the metrics written by collectors.
+func Handler(collectors ...Collector) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var buf bytes.Buffer
+
+		for _, collect := range collectors {
+			collect(&buf)
+		}
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		if _, err := w.Write(buf.Bytes()); err != nil {
+			klog.Errorf("failed to write metrics: %v", err)
+		}
+	}
+}
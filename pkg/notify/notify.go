@@ -0,0 +1,228 @@
+// Package notify informs external systems, e.g. a CMDB or alerting, of the node
+// tuning changes: the tuned profile changed or failed to apply.
+package notify
+
+import (
+	"bytes"         // bytes.Buffer
+	"encoding/json" // json.Marshal()
+	"fmt"           // Errorf()
+	"net/http"      // http.Client
+	"os"            // os.Environ()
+	"os/exec"       // exec.Command()
+	"strings"       // strings.SplitN()
+	"sync"          // sync.Mutex
+	"time"          // time.Time
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/rest"
+	"k8s.io/klog"
+
+	"github.com/openshift/openshift-tuned/pkg/process"
+)
+
+// Types
+// Event is a node tuning change.
+type Event struct {
+	Time time.Time `json:"time"`
+	Node string    `json:"node"`
+	// Type is EventProfileChanged or EventApplyFailed.
+	Type    string `json:"type"`
+	Profile string `json:"profile"`
+	// PreviousProfile is the profile applied before, if any.
+	PreviousProfile string `json:"previousProfile,omitempty"`
+	Message         string `json:"message,omitempty"`
+}
+
+// Notifier is a sink of tuning change events.
+type Notifier interface {
+	// Name identifies the notifier in logs, "kind:location".
+	Name() string
+	Notify(e Event) error
+}
+
+// Webhook POSTs events as JSON to URL.
+type Webhook struct {
+	URL    string
+	client *http.Client
+}
+
+// Exec runs the executable Path for every event with the event as JSON on its
+// standard input and in the TUNED_EVENT_* environment variables.
+type Exec struct {
+	Path string
+}
+
+// KubeEvents records events as Kubernetes Events of the node.  Events are dropped
+// until a client is set by SetClient(), e.g. while openshift-tuned runs standalone.
+type KubeEvents struct {
+	mu     sync.Mutex
+	client rest.Interface
+}
+
+// Dispatcher sends events to notifiers in the background, so that slow sinks do
+// not hold up tuning.
+type Dispatcher struct {
+	notifiers []Notifier
+	queue     chan Event
+}
+
+// Constants
+const (
+	EventProfileChanged = "ProfileChanged"
+	EventApplyFailed    = "ApplyFailed"
+
+	// Timeout is the time a single notification may take.
+	Timeout = 10 * time.Second
+
+	// namespace of the Kubernetes Events of nodes, like the kubelet's
+	kubeEventsNamespace = "default"
+	kubeEventsComponent = "openshift-tuned"
+	queueSize           = 64
+)
+
+// Functions
+// New creates a notifier from spec "webhook:<url>", "exec:<path>" or "events".
+func New(spec string) (Notifier, error) {
+	if spec == "events" {
+		return &KubeEvents{}, nil
+	}
+	parts := strings.SplitN(spec, ":", 2)
+	if len(parts) != 2 || len(parts[1]) == 0 {
+		return nil, fmt.Errorf("invalid notifier %q, expected webhook:<url>, exec:<path> or events", spec)
+	}
+	switch parts[0] {
+	case "webhook":
+		return &Webhook{URL: parts[1], client: &http.Client{Timeout: Timeout}}, nil
+	case "exec":
+		return &Exec{Path: parts[1]}, nil
+	}
+	return nil, fmt.Errorf("unknown notifier kind %q in %q, expected webhook, exec or events", parts[0], spec)
+}
+
+func (w *Webhook) Name() string {
+	return "webhook:" + w.URL
+}
+
+func (w *Webhook) Notify(e Event) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	resp, err := w.client.Post(w.URL, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("%s: %s", w.URL, resp.Status)
+	}
+	return nil
+}
+
+func (x *Exec) Name() string {
+	return "exec:" + x.Path
+}
+
+func (x *Exec) Notify(e Event) error {
+	var out bytes.Buffer
+
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	cmd := exec.Command(x.Path)
+	cmd.Env = append(os.Environ(),
+		"TUNED_EVENT_TYPE="+e.Type,
+		"TUNED_EVENT_NODE="+e.Node,
+		"TUNED_EVENT_PROFILE="+e.Profile,
+		"TUNED_EVENT_PREVIOUS_PROFILE="+e.PreviousProfile,
+		"TUNED_EVENT_MESSAGE="+e.Message)
+	cmd.Stdin = bytes.NewReader(data)
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	if err := process.RunTimeout(cmd, Timeout); err != nil {
+		return fmt.Errorf("%v: %s", err, strings.TrimSpace(out.String()))
+	}
+	return nil
+}
+
+func (k *KubeEvents) Name() string {
+	return "events"
+}
+
+// SetClient sets the client of the core ("v1") API group to create Events with.
+func (k *KubeEvents) SetClient(client rest.Interface) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	k.client = client
+}
+
+func (k *KubeEvents) Notify(e Event) error {
+	k.mu.Lock()
+	client := k.client
+	k.mu.Unlock()
+	if client == nil {
+		klog.V(1).Infof("no API client, dropping %s event of node %q", e.Type, e.Node)
+		return nil
+	}
+
+	eventType := corev1.EventTypeNormal
+	if e.Type == EventApplyFailed {
+		eventType = corev1.EventTypeWarning
+	}
+	t := metav1.NewTime(e.Time)
+	ev := &corev1.Event{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: e.Node + ".",
+			Namespace:    kubeEventsNamespace,
+		},
+		InvolvedObject: corev1.ObjectReference{Kind: "Node", Name: e.Node, APIVersion: "v1"},
+		Reason:         e.Type,
+		Message:        e.Message,
+		Source:         corev1.EventSource{Component: kubeEventsComponent, Host: e.Node},
+		FirstTimestamp: t,
+		LastTimestamp:  t,
+		Count:          1,
+		Type:           eventType,
+	}
+	data, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+	return client.Post().Namespace(kubeEventsNamespace).Resource("events").Body(data).Do().Error()
+}
+
+// NewDispatcher creates a Dispatcher of notifiers and starts sending events.
+func NewDispatcher(notifiers []Notifier) *Dispatcher {
+	d := &Dispatcher{notifiers: notifiers, queue: make(chan Event, queueSize)}
+	go d.sendLoop()
+	return d
+}
+
+// Dispatch queues e for the notifiers.  Events are dropped if the notifiers cannot
+// keep up.  A nil *Dispatcher drops all events.
+func (d *Dispatcher) Dispatch(e Event) {
+	if d == nil {
+		return
+	}
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	select {
+	case d.queue <- e:
+	default:
+		klog.Errorf("notification queue full, dropping %s event of profile %q", e.Type, e.Profile)
+	}
+}
+
+func (d *Dispatcher) sendLoop() {
+	for e := range d.queue {
+		for _, n := range d.notifiers {
+			if err := n.Notify(e); err != nil {
+				klog.Errorf("notifier %s failed to send %s event: %v", n.Name(), e.Type, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,22 @@
+// +build linux
+
+package process
+
+import (
+	"os/exec" // exec.Cmd
+	"syscall" // syscall.SysProcAttr
+)
+
+// Functions
+// processGroupSet makes cmd start a new process group.
+func processGroupSet(cmd *exec.Cmd) {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.Setpgid = true
+}
+
+// processGroupKill kills the process group of cmd started by processGroupSet().
+func processGroupKill(cmd *exec.Cmd) {
+	syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+}
@@ -0,0 +1,17 @@
+// +build !linux
+
+package process
+
+import (
+	"os/exec" // exec.Cmd
+)
+
+// Functions
+// processGroupSet does nothing, process groups are only used on Linux.
+func processGroupSet(cmd *exec.Cmd) {
+}
+
+// processGroupKill kills cmd only.
+func processGroupKill(cmd *exec.Cmd) {
+	cmd.Process.Kill()
+}
@@ -0,0 +1,101 @@
+package process
+
+import (
+	"bufio"   // bufio.Reader
+	"bytes"   // bytes.TrimRight()
+	"fmt"     // Sprintf()
+	"regexp"  // regexp.MustCompile()
+	"strings" // strings.TrimPrefix()
+)
+
+// Types
+// Stream is an output stream of tuned.
+type Stream string
+
+// LogError is an error or a warning logged by tuned.
+type LogError struct {
+	// Plugin is the tuned plugin which logged the error, e.g. "sysctl", or the
+	// tuned module for errors outside of plugins, e.g. "utils.commands".
+	Plugin  string `json:"plugin"`
+	Level   string `json:"level"`
+	Message string `json:"message"`
+}
+
+// Global variables
+var (
+	// 2019-11-19 10:04:55,341 ERROR    tuned.plugins.plugin_sysctl: Failed to set sysctl parameter ...
+	logErrorRe = regexp.MustCompile(`^\S+ \S+ (ERROR|WARNING)\s+tuned\.([\w.]+):\s*(.*)$`)
+	logLevelRe = regexp.MustCompile(`^\S+ \S+ (DEBUG|INFO|WARNING|ERROR|CRITICAL)\s`)
+)
+
+// Constants
+const (
+	StreamStdout Stream = "stdout"
+	StreamStderr Stream = "stderr"
+
+	logPluginPrefix = "plugins.plugin_"
+	// DefaultMaxLineSize is the default maximum length of a line of the tuned
+	// output; longer lines, e.g. of huge tracebacks, are truncated.
+	DefaultMaxLineSize = 256 << 10
+)
+
+// Functions
+// LogErrorParse parses a line of the tuned log.  Returns false if the line does not
+// report an error or a warning.
+func LogErrorParse(line string) (LogError, bool) {
+	m := logErrorRe.FindStringSubmatch(strings.TrimSpace(line))
+	if m == nil {
+		return LogError{}, false
+	}
+	return LogError{
+		Plugin:  strings.TrimPrefix(m[2], logPluginPrefix),
+		Level:   m[1],
+		Message: m[3],
+	}, true
+}
+
+// LogLevel returns the level of a line of the tuned log, e.g. "ERROR", or "" if
+// the line does not start with one, e.g. a line of a traceback.
+func LogLevel(line string) string {
+	m := logLevelRe.FindStringSubmatch(line)
+	if m == nil {
+		return ""
+	}
+	return m[1]
+}
+
+// ReadLine reads a line from br without the line terminator.  Lines longer than
+// max bytes are truncated and marked as such, the rest of the line is skipped.
+// A last line without a terminator is returned with a nil error, the error
+// reading the following line is returned then.
+func ReadLine(br *bufio.Reader, max int) (string, error) {
+	var (
+		line    []byte
+		skipped int
+		err     error
+	)
+	for {
+		var frag []byte
+		frag, err = br.ReadSlice('\n')
+		if n := max - len(line); n < len(frag) {
+			line = append(line, frag[:n]...)
+			skipped += len(frag) - n
+		} else {
+			line = append(line, frag...)
+		}
+		if err != bufio.ErrBufferFull {
+			break
+		}
+	}
+	if len(line) == 0 && skipped == 0 && err != nil {
+		return "", err
+	}
+	if skipped > 0 {
+		if err == nil {
+			// Do not count the terminator
+			skipped--
+		}
+		return fmt.Sprintf("%s... [truncated %d bytes]", bytes.TrimRight(line, "\r\n"), skipped), nil
+	}
+	return string(bytes.TrimRight(line, "\r\n")), nil
+}
@@ -0,0 +1,168 @@
+package process
+
+import (
+	"fmt"     // Errorf()
+	"sync"    // sync.Mutex
+	"syscall" // syscall.SIGTERM, ...
+)
+
+// Types
+// State is the state of the tuned process controlled by a Manager.
+type State int
+
+// Manager serializes the access to a Runner and tracks the state of the tuned
+// process, so that tuned is not signalled after it exited and a new tuned is not
+// started before the previous one exited.
+type Manager struct {
+	mu     sync.Mutex
+	runner Runner
+	state  State
+}
+
+// Constants
+const (
+	// tuned was not started or its exit was handled by Reset()
+	StateStopped State = iota
+	// tuned runs
+	StateRunning
+	// tuned was asked to terminate
+	StateStopping
+	// tuned exited, waiting for Reset()
+	StateExited
+)
+
+// Global variables
+var (
+	stateNames = map[State]string{
+		StateStopped:  "stopped",
+		StateRunning:  "running",
+		StateStopping: "stopping",
+		StateExited:   "exited",
+	}
+)
+
+// Functions
+func (st State) String() string {
+	return stateNames[st]
+}
+
+// NewManager creates a Manager controlling tuned run by runner.
+func NewManager(runner Runner) *Manager {
+	return &Manager{runner: runner}
+}
+
+// Unwrap returns the Runner controlled by m.
+func (m *Manager) Unwrap() Runner {
+	return m.runner
+}
+
+// State returns the state of the tuned process.
+func (m *Manager) State() State {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.state
+}
+
+func (m *Manager) Start(exit chan<- bool) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	switch m.state {
+	case StateRunning, StateStopping:
+		return fmt.Errorf("cannot start tuned: tuned is %s", m.state)
+	case StateExited:
+		// The exit was not handled yet
+		m.runner.Reset()
+	}
+
+	exited := make(chan bool, 1)
+	if err := m.runner.Start(exited); err != nil {
+		m.state = StateStopped
+		return err
+	}
+	m.state = StateRunning
+
+	go func() {
+		<-exited
+		m.mu.Lock()
+		m.state = StateExited
+		m.mu.Unlock()
+		exit <- true
+	}()
+
+	return nil
+}
+
+// Adopt takes over the running tuned process pid, see Adopter.
+func (m *Manager) Adopt(pid int, exit chan<- bool) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	a, ok := m.runner.(Adopter)
+	if !ok {
+		return fmt.Errorf("cannot adopt tuned: %T does not support it", m.runner)
+	}
+	if m.state == StateRunning || m.state == StateStopping {
+		return fmt.Errorf("cannot adopt tuned: tuned is %s", m.state)
+	}
+	exited := make(chan bool, 1)
+	if err := a.Adopt(pid, exited); err != nil {
+		return err
+	}
+	m.state = StateRunning
+
+	go func() {
+		<-exited
+		m.mu.Lock()
+		m.state = StateExited
+		m.mu.Unlock()
+		exit <- true
+	}()
+
+	return nil
+}
+
+// Pid returns the PID of tuned or 0 if tuned does not run.
+func (m *Manager) Pid() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.state != StateRunning && m.state != StateStopping {
+		return 0
+	}
+	return m.runner.Pid()
+}
+
+// Signal sends signal sig to tuned unless it already exited.
+func (m *Manager) Signal(sig syscall.Signal) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.state != StateRunning && m.state != StateStopping {
+		return fmt.Errorf("cannot send %v to tuned: tuned is %s", sig, m.state)
+	}
+	if err := m.runner.Signal(sig); err != nil {
+		return err
+	}
+	if sig == syscall.SIGTERM || sig == syscall.SIGINT {
+		m.state = StateStopping
+	}
+	return nil
+}
+
+// Reset forgets an exited tuned.  Reset is a no-op if tuned was restarted since
+// it exited.
+func (m *Manager) Reset() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.state == StateExited {
+		m.runner.Reset()
+		m.state = StateStopped
+	}
+}
+
+func (m *Manager) Recommend() (string, error) {
+	return m.runner.Recommend()
+}
@@ -0,0 +1,115 @@
+package process
+
+import (
+	"reflect" // reflect.DeepEqual()
+	"syscall" // syscall.SIGTERM, ...
+	"testing"
+	"time" // time.After()
+)
+
+// waitExit waits for the Manager to report that tuned exited on exit.
+func waitExit(t *testing.T, exit <-chan bool) {
+	select {
+	case <-exit:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("tuned exit not reported")
+	}
+}
+
+func TestManagerSignal(t *testing.T) {
+	tests := []struct {
+		name     string
+		signals  []syscall.Signal
+		wantExit bool
+	}{
+		{
+			name:    "reload",
+			signals: []syscall.Signal{syscall.SIGHUP, syscall.SIGHUP},
+		},
+		{
+			name:     "terminate",
+			signals:  []syscall.Signal{syscall.SIGHUP, syscall.SIGTERM},
+			wantExit: true,
+		},
+		{
+			name:     "kill",
+			signals:  []syscall.Signal{syscall.SIGKILL},
+			wantExit: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &FakeRunner{}
+			m := NewManager(r)
+			exit := make(chan bool, 1)
+			if err := m.Start(exit); err != nil {
+				t.Fatalf("Start() error = %v", err)
+			}
+			if m.Pid() == 0 {
+				t.Fatalf("Pid() = 0 after Start()")
+			}
+			for _, sig := range tt.signals {
+				if err := m.Signal(sig); err != nil {
+					t.Fatalf("Signal(%v) error = %v", sig, err)
+				}
+			}
+			if !reflect.DeepEqual(r.Signals, tt.signals) {
+				t.Errorf("signals sent = %v, want %v", r.Signals, tt.signals)
+			}
+			if !tt.wantExit {
+				if st := m.State(); st != StateRunning {
+					t.Errorf("State() = %v, want %v", st, StateRunning)
+				}
+				return
+			}
+
+			waitExit(t, exit)
+			if st := m.State(); st != StateExited {
+				t.Errorf("State() = %v, want %v", st, StateExited)
+			}
+			if pid := m.Pid(); pid != 0 {
+				t.Errorf("Pid() = %d after exit, want 0", pid)
+			}
+			if err := m.Signal(syscall.SIGHUP); err == nil {
+				t.Errorf("Signal() to an exited tuned succeeded")
+			}
+		})
+	}
+}
+
+func TestManagerRestart(t *testing.T) {
+	r := &FakeRunner{Recommended: "test"}
+	m := NewManager(r)
+	exit := make(chan bool, 1)
+
+	if err := m.Start(exit); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	if err := m.Start(exit); err == nil {
+		t.Errorf("Start() of a running tuned succeeded")
+	}
+	if err := m.Signal(syscall.SIGTERM); err != nil {
+		t.Fatalf("Signal() error = %v", err)
+	}
+	if err := m.Start(exit); err == nil {
+		t.Errorf("Start() of a stopping tuned succeeded")
+	}
+	waitExit(t, exit)
+
+	m.Reset()
+	if st := m.State(); st != StateStopped {
+		t.Errorf("State() after Reset() = %v, want %v", st, StateStopped)
+	}
+	if err := m.Start(exit); err != nil {
+		t.Fatalf("Start() after Reset() error = %v", err)
+	}
+	// A Reset() of a restarted tuned is a no-op
+	m.Reset()
+	if st := m.State(); st != StateRunning {
+		t.Errorf("State() = %v, want %v", st, StateRunning)
+	}
+	if profile, err := m.Recommend(); err != nil || profile != "test" {
+		t.Errorf("Recommend() = %q, %v, want %q", profile, err, "test")
+	}
+}
@@ -0,0 +1,98 @@
+package process
+
+import (
+	"fmt"       // Errorf()
+	"io/ioutil" // ioutil.WriteFile()
+	"syscall"   // syscall.SIGHUP, ...
+
+	"k8s.io/klog"
+)
+
+// Types
+// MockRunner is a Runner which runs an in-process stub instead of tuned.  The stub
+// "applies" a profile by writing the profile returned by Recommender to ActiveProfileFile
+// on start and on SIGHUP, and exits on SIGTERM, SIGINT and SIGKILL.
+type MockRunner struct {
+	// Recommender returns the profile to apply, e.g. by evaluating the recommend.d rules
+	Recommender func() (string, error)
+	// ActiveProfileFile is the tuned active profile file
+	ActiveProfileFile string
+	pid               int
+	sigs              chan syscall.Signal
+	// done is closed when the stub exits
+	done chan struct{}
+}
+
+// Functions
+func (r *MockRunner) apply() {
+	profile, err := r.Recommender()
+	if err != nil {
+		klog.Errorf("mock tuned: %v", err)
+		return
+	}
+	if err = ioutil.WriteFile(r.ActiveProfileFile, []byte(profile+"\n"), 0644); err != nil {
+		klog.Errorf("mock tuned: failed to write %q: %v", r.ActiveProfileFile, err)
+		return
+	}
+	klog.Infof("mock tuned: applied profile %q", profile)
+}
+
+func (r *MockRunner) Start(exit chan<- bool) error {
+	klog.Infof("starting mock tuned...")
+
+	r.pid = fakePid
+	r.sigs = make(chan syscall.Signal, 1)
+	r.done = make(chan struct{})
+	go func(sigs <-chan syscall.Signal, done chan<- struct{}) {
+		r.apply()
+		for sig := range sigs {
+			switch sig {
+			case syscall.SIGHUP:
+				r.apply()
+			case syscall.SIGTERM, syscall.SIGINT:
+				klog.Infof("mock tuned: terminating")
+				close(done)
+				exit <- true
+				return
+			case syscall.SIGKILL:
+				klog.Infof("mock tuned: killed")
+				close(done)
+				exit <- true
+				return
+			}
+		}
+	}(r.sigs, r.done)
+
+	return nil
+}
+
+func (r *MockRunner) Pid() int {
+	return r.pid
+}
+
+func (r *MockRunner) Signal(sig syscall.Signal) error {
+	if r.pid == 0 {
+		return fmt.Errorf("cannot find the tuned process!")
+	}
+	// Nothing receives the signals of an exited stub; check done first so that
+	// a signal is not queued in the free buffer slot after the exit
+	select {
+	case <-r.done:
+		return fmt.Errorf("mock tuned already exited")
+	default:
+	}
+	select {
+	case r.sigs <- sig:
+		return nil
+	case <-r.done:
+		return fmt.Errorf("mock tuned already exited")
+	}
+}
+
+func (r *MockRunner) Reset() {
+	r.pid = 0
+}
+
+func (r *MockRunner) Recommend() (string, error) {
+	return r.Recommender()
+}
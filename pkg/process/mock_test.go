@@ -0,0 +1,49 @@
+package process
+
+import (
+	"io/ioutil"     // ioutil.TempDir()
+	"os"            // os.RemoveAll()
+	"path/filepath" // filepath.Join()
+	"syscall"       // syscall.SIGTERM, ...
+	"testing"
+	"time" // time.After()
+)
+
+func TestMockRunnerSignalAfterExit(t *testing.T) {
+	dir, err := ioutil.TempDir("", "mock")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	r := &MockRunner{
+		Recommender:       func() (string, error) { return "test", nil },
+		ActiveProfileFile: filepath.Join(dir, "active_profile"),
+	}
+	exit := make(chan bool, 1)
+	if err := r.Start(exit); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	if err := r.Signal(syscall.SIGTERM); err != nil {
+		t.Fatalf("Signal() error = %v", err)
+	}
+	waitExit(t, exit)
+
+	// The stub no longer receives signals; Signal() must not block
+	errs := make(chan error, 2)
+	go func() {
+		for _, sig := range []syscall.Signal{syscall.SIGTERM, syscall.SIGHUP} {
+			errs <- r.Signal(sig)
+		}
+	}()
+	for i := 0; i < 2; i++ {
+		select {
+		case err := <-errs:
+			if err == nil {
+				t.Errorf("Signal() to an exited stub succeeded")
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("Signal() to an exited stub blocked")
+		}
+	}
+}
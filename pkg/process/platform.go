@@ -0,0 +1,24 @@
+package process
+
+import (
+	"errors"  // errors.New()
+	"runtime" // runtime.GOOS
+)
+
+// Global variables
+var (
+	// ErrUnsupportedPlatform is returned by the operations which are only
+	// available on Linux, the only platform tuned runs on.
+	ErrUnsupportedPlatform = errors.New("unsupported platform " + runtime.GOOS + ", tuned only runs on linux")
+)
+
+// Functions
+// PlatformCheck returns ErrUnsupportedPlatform unless tuned can be run on this
+// platform.  The platform-independent parts of openshift-tuned (e.g. the recommend
+// engine and the profile parsing) can be used and tested on any platform.
+func PlatformCheck() error {
+	if runtime.GOOS != "linux" {
+		return ErrUnsupportedPlatform
+	}
+	return nil
+}
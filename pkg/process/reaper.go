@@ -0,0 +1,51 @@
+package process
+
+import (
+	"os/exec" // exec.Cmd
+	"sync"    // sync.Mutex
+)
+
+// Global variables
+var (
+	// children started by Start() and not waited for yet; they must not be reaped
+	// by Reap(), or exec.Cmd.Wait() would fail
+	children struct {
+		sync.Mutex
+		pids map[int]bool
+	}
+)
+
+// Functions
+// Start starts cmd as a child which is not reaped by Reap().
+func Start(cmd *exec.Cmd) error {
+	children.Lock()
+	defer children.Unlock()
+
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	if children.pids == nil {
+		children.pids = map[int]bool{}
+	}
+	children.pids[cmd.Process.Pid] = true
+	return nil
+}
+
+// Wait waits for cmd started by Start() to exit.
+func Wait(cmd *exec.Cmd) error {
+	err := cmd.Wait()
+
+	children.Lock()
+	delete(children.pids, cmd.Process.Pid)
+	children.Unlock()
+
+	return err
+}
+
+// Run starts cmd by Start() and waits for it to exit.
+func Run(cmd *exec.Cmd) error {
+	if err := Start(cmd); err != nil {
+		return err
+	}
+	return Wait(cmd)
+}
@@ -0,0 +1,104 @@
+// +build linux
+
+package process
+
+import (
+	"os"        // os.Getpid()
+	"os/signal" // signal.Notify()
+	"syscall"   // syscall.SIGCHLD
+	"time"      // time.NewTicker()
+	"unsafe"    // unsafe.Pointer()
+
+	"golang.org/x/sys/unix"
+	"k8s.io/klog"
+)
+
+// Types
+// siginfo is the part of siginfo_t filled in by waitid(2) we need.
+type siginfo struct {
+	signo int32
+	errno int32
+	code  int32
+	_     int32
+	pid   int32
+	uid   uint32
+	_     [104]byte
+}
+
+// Constants
+const (
+	reapInterval = 30 * time.Second // reap even if SIGCHLD was missed
+)
+
+// Functions
+// ReaperNeeded returns true if orphaned processes are reparented to this process,
+// i.e. it runs as PID 1 of a container or is a child subreaper.
+func ReaperNeeded() bool {
+	if os.Getpid() == 1 {
+		return true
+	}
+	var subreaper int32
+	if err := unix.Prctl(unix.PR_GET_CHILD_SUBREAPER, uintptr(unsafe.Pointer(&subreaper)), 0, 0, 0); err != nil {
+		return false
+	}
+	return subreaper != 0
+}
+
+// SetSubreaper makes this process the child subreaper of its descendants, see
+// prctl(2) PR_SET_CHILD_SUBREAPER.
+func SetSubreaper() error {
+	return unix.Prctl(unix.PR_SET_CHILD_SUBREAPER, 1, 0, 0, 0)
+}
+
+// zombiePeek returns the PID of an exited child without reaping it, or 0.
+func zombiePeek() int {
+	var info siginfo
+
+	_, _, errno := syscall.Syscall6(syscall.SYS_WAITID, 0 /* P_ALL */, 0, uintptr(unsafe.Pointer(&info)),
+		syscall.WEXITED|syscall.WNOHANG|0x1000000 /* WNOWAIT */, 0, 0)
+	if errno != 0 {
+		return 0
+	}
+	return int(info.pid)
+}
+
+// reap reaps the exited children not started by Start().
+func reap() {
+	for {
+		children.Lock()
+		pid := zombiePeek()
+		if pid <= 0 || children.pids[pid] {
+			// Exited children started by Start() are reaped by Wait(); other zombies
+			// queued behind them are reaped on the next SIGCHLD or tick
+			children.Unlock()
+			return
+		}
+		var ws syscall.WaitStatus
+		_, err := syscall.Wait4(pid, &ws, syscall.WNOHANG, nil)
+		children.Unlock()
+		if err != nil {
+			klog.Errorf("failed to reap process %d: %v", pid, err)
+			return
+		}
+		klog.V(2).Infof("reaped orphaned process %d, exit status %d", pid, ws.ExitStatus())
+	}
+}
+
+// Reap reaps orphaned processes reparented to this process until stop is closed.
+func Reap(stop <-chan struct{}) {
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGCHLD)
+	defer signal.Stop(sigs)
+	ticker := time.NewTicker(reapInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-sigs:
+		case <-ticker.C:
+		}
+		reap()
+	}
+}
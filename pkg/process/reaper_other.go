@@ -0,0 +1,19 @@
+// +build !linux
+
+package process
+
+// Functions
+// ReaperNeeded always returns false, orphaned processes are reaped by init.
+func ReaperNeeded() bool {
+	return false
+}
+
+// SetSubreaper returns ErrUnsupportedPlatform, child subreapers are Linux-specific.
+func SetSubreaper() error {
+	return ErrUnsupportedPlatform
+}
+
+// Reap does nothing until stop is closed.
+func Reap(stop <-chan struct{}) {
+	<-stop
+}
@@ -0,0 +1,348 @@
+// Package process runs and controls the tuned daemon.
+package process
+
+import (
+	"bufio"     // bufio.NewReader()
+	"bytes"     // bytes.Buffer
+	"fmt"       // Errorf()
+	"io"        // io.Reader
+	"io/ioutil" // ioutil.ReadFile()
+	"os"        // os.Process
+	"os/exec"   // os.Exec()
+	"strings"   // strings.TrimSpace()
+	"sync"      // sync.WaitGroup
+	"syscall"   // syscall.SIGHUP, ...
+	"time"      // time.NewTicker()
+
+	"k8s.io/klog"
+)
+
+// Types
+// Runner runs and controls the tuned daemon.
+type Runner interface {
+	// Start starts tuned in the background.  A value is sent on exit when tuned exits.
+	Start(exit chan<- bool) error
+	// Pid returns the PID of tuned or 0 if tuned was not started.
+	Pid() int
+	// Signal sends signal sig to tuned.
+	Signal(sig syscall.Signal) error
+	// Reset forgets an exited tuned, so that it can be started again.
+	Reset()
+	// Recommend returns the tuned profile recommended by tuned.
+	Recommend() (string, error)
+}
+
+// Adopter is implemented by Runners which can take over a running tuned.
+type Adopter interface {
+	// Adopt takes over the tuned process pid, e.g. started by another
+	// openshift-tuned instance.  A value is sent on exit when tuned exits.
+	Adopt(pid int, exit chan<- bool) error
+}
+
+// Signaler sends signals to processes.
+type Signaler interface {
+	// Signal sends signal sig to process p.
+	Signal(p *os.Process, sig syscall.Signal) error
+}
+
+// MissingError is returned when a tuned binary cannot be found.
+type MissingError struct {
+	Path string
+	Err  error
+}
+
+// ExecRunner runs /usr/sbin/tuned as a child process.
+type ExecRunner struct {
+	// Features of the tuned release run, see FeaturesFor()
+	Features Features
+	// LogHandler, if set, is called with every line tuned logs and the stream
+	// it logged the line to
+	LogHandler func(stream Stream, line string)
+	// Verbosity is the klog verbosity the DEBUG and INFO lines of each stream
+	// are logged at; WARNING and more severe lines are always logged.
+	Verbosity map[Stream]klog.Level
+	// Timeout limits the tuned-adm commands run, 0 for no limit
+	Timeout time.Duration
+	// MaxLineSize is the maximum length of a line tuned logs, longer lines are
+	// truncated; 0 is DefaultMaxLineSize.
+	MaxLineSize int
+	// Output, if set, receives every line tuned logs, prefixed by the stream;
+	// only the WARNING and more severe lines are logged then.
+	Output   io.Writer
+	signaler Signaler
+	cmd      *exec.Cmd
+	// tuned taken over by Adopt(), not a child of this process
+	adopted *os.Process
+	// closed to stop polling the adopted tuned
+	adoptedStop chan struct{}
+}
+
+// FakeRunner is a Runner which does not run any process.  It records the signals
+// sent to it in Signals and recommends the profile set in Recommended; it exits
+// on SIGTERM and SIGKILL.
+type FakeRunner struct {
+	Signals     []syscall.Signal
+	Recommended string
+	pid         int
+	exit        chan<- bool
+}
+
+// Constants
+const (
+	TunedBinary    = "/usr/sbin/tuned"
+	TunedAdmBinary = "/usr/sbin/tuned-adm"
+
+	fakePid = 1 << 22 // above the default pid_max, cannot clash with a real process
+
+	adoptedPollInterval = time.Second // how often to check an adopted tuned still runs
+)
+
+// Functions
+func (e *MissingError) Error() string {
+	return fmt.Sprintf("cannot run %s: %v", e.Path, e.Err)
+}
+
+// NewExecRunner creates an ExecRunner which signals tuned via signaler.
+func NewExecRunner(signaler Signaler) *ExecRunner {
+	return &ExecRunner{Features: FeaturesFor(nil), signaler: signaler}
+}
+
+func (r *ExecRunner) Start(exit chan<- bool) error {
+	klog.Infof("starting tuned...")
+
+	r.adoptedForget()
+	var args []string
+	if r.Features.NoDBus {
+		args = append(args, "--no-dbus")
+	}
+	r.cmd = exec.Command(TunedBinary, args...)
+	stdout, err := r.cmd.StdoutPipe()
+	if err != nil {
+		return fmt.Errorf("error creating StdoutPipe for tuned: %v", err)
+	}
+	stderr, err := r.cmd.StderrPipe()
+	if err != nil {
+		return fmt.Errorf("error creating StderrPipe for tuned: %v", err)
+	}
+
+	// Wait() closes the pipes, all output must be read before
+	var logged sync.WaitGroup
+	logged.Add(2)
+	go r.logStream(StreamStdout, stdout, &logged)
+	go r.logStream(StreamStderr, stderr, &logged)
+
+	if err = Start(r.cmd); err != nil {
+		if os.IsNotExist(err) || err == exec.ErrNotFound {
+			return &MissingError{Path: TunedBinary, Err: err}
+		}
+		return fmt.Errorf("error starting tuned: %v", err)
+	}
+
+	go func(cmd *exec.Cmd) {
+		logged.Wait()
+		if err := Wait(cmd); err != nil {
+			// The command exited with non 0 exit status, e.g. terminated by a signal
+			klog.Errorf("error waiting for tuned: %v", err)
+		}
+		exit <- true
+	}(r.cmd)
+
+	return nil
+}
+
+// logStream logs the lines tuned writes to stream until EOF.  The lines without a
+// level, e.g. those of a traceback, are logged at the level of the preceding line.
+func (r *ExecRunner) logStream(stream Stream, pipe io.Reader, done *sync.WaitGroup) {
+	defer done.Done()
+
+	max := r.MaxLineSize
+	if max <= 0 {
+		max = DefaultMaxLineSize
+	}
+	level := ""
+	outputFailed := false
+	br := bufio.NewReader(pipe)
+	for {
+		line, err := ReadLine(br, max)
+		if err != nil {
+			if err != io.EOF {
+				klog.Errorf("failed to read the tuned %s, no longer logging it: %v", stream, err)
+				// Do not block tuned writing to the pipe
+				io.Copy(ioutil.Discard, pipe)
+			}
+			return
+		}
+		if l := LogLevel(line); len(l) > 0 {
+			level = l
+		}
+		if r.Output != nil {
+			// Log a failing Output once, not for every line
+			if _, err := fmt.Fprintf(r.Output, "%s: %s\n", stream, line); err != nil && !outputFailed {
+				klog.Errorf("failed to capture the tuned output: %v", err)
+				outputFailed = true
+			} else if err == nil {
+				outputFailed = false
+			}
+		}
+		switch level {
+		case "ERROR", "CRITICAL":
+			klog.Errorf("tuned %s: %s", stream, line)
+		case "WARNING":
+			klog.Warningf("tuned %s: %s", stream, line)
+		default:
+			if r.Output == nil {
+				klog.V(r.Verbosity[stream]).Infof("tuned %s: %s", stream, line)
+			}
+		}
+		if r.LogHandler != nil {
+			r.LogHandler(stream, line)
+		}
+	}
+}
+
+// Adopt takes over tuned process pid.  Its exit is detected by polling, as it
+// is not a child of this process.
+func (r *ExecRunner) Adopt(pid int, exit chan<- bool) error {
+	comm, err := ioutil.ReadFile(fmt.Sprintf("/proc/%d/comm", pid))
+	if err != nil {
+		return fmt.Errorf("cannot adopt tuned PID %d: %v", pid, err)
+	}
+	if !strings.HasPrefix(strings.TrimSpace(string(comm)), "tuned") {
+		return fmt.Errorf("cannot adopt PID %d: it is %q, not tuned", pid, strings.TrimSpace(string(comm)))
+	}
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return fmt.Errorf("cannot adopt tuned PID %d: %v", pid, err)
+	}
+	klog.Infof("adopted tuned PID %d", pid)
+	r.adoptedForget()
+	r.adopted = p
+	r.adoptedStop = make(chan struct{})
+
+	go func(stop <-chan struct{}) {
+		t := time.NewTicker(adoptedPollInterval)
+		defer t.Stop()
+		for {
+			select {
+			case <-t.C:
+			case <-stop:
+				return
+			}
+			if err := p.Signal(syscall.Signal(0)); err != nil {
+				klog.Errorf("adopted tuned PID %d exited", pid)
+				select {
+				case exit <- true:
+				case <-stop:
+				}
+				return
+			}
+		}
+	}(r.adoptedStop)
+	return nil
+}
+
+// adoptedForget forgets the adopted tuned and stops polling it.
+func (r *ExecRunner) adoptedForget() {
+	if r.adoptedStop != nil {
+		close(r.adoptedStop)
+		r.adoptedStop = nil
+	}
+	r.adopted = nil
+}
+
+func (r *ExecRunner) process() *os.Process {
+	if r.adopted != nil {
+		return r.adopted
+	}
+	if r.cmd == nil {
+		return nil
+	}
+	return r.cmd.Process
+}
+
+func (r *ExecRunner) Pid() int {
+	p := r.process()
+	if p == nil {
+		return 0
+	}
+	return p.Pid
+}
+
+func (r *ExecRunner) Signal(sig syscall.Signal) error {
+	p := r.process()
+	if p == nil {
+		// This should never happen
+		return fmt.Errorf("cannot find the tuned process!")
+	}
+	return r.signaler.Signal(p, sig)
+}
+
+func (r *ExecRunner) Reset() {
+	r.cmd = nil // cmd.Start() cannot be used more than once
+	r.adoptedForget()
+}
+
+func (r *ExecRunner) Recommend() (string, error) {
+	var stdout, stderr bytes.Buffer
+
+	klog.V(1).Infof("getting recommended profile...")
+	cmd := exec.Command(TunedAdmBinary, "recommend")
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	err := RunTimeout(cmd, r.Timeout)
+	if err != nil {
+		return "", fmt.Errorf("error getting recommended profile: %v: %v", err, stderr.String())
+	}
+
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+// TunedAdmActive returns the profile tuned reports as active via its control
+// interface, "tuned-adm active"; tuned must run with D-Bus enabled.  The command is
+// killed after timeout, see RunTimeout().
+func TunedAdmActive(timeout time.Duration) (string, error) {
+	var stdout, stderr bytes.Buffer
+
+	cmd := exec.Command(TunedAdmBinary, "active")
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := RunTimeout(cmd, timeout); err != nil {
+		return "", fmt.Errorf("error getting active profile: %v: %v", err, stderr.String())
+	}
+	// "Current active profile: openshift-node"
+	out := strings.TrimSpace(stdout.String())
+	i := strings.LastIndex(out, ":")
+	if i < 0 {
+		return "", fmt.Errorf("unexpected output of %s active: %q", TunedAdmBinary, out)
+	}
+	return strings.TrimSpace(out[i+1:]), nil
+}
+
+func (r *FakeRunner) Start(exit chan<- bool) error {
+	r.pid = fakePid
+	r.exit = exit
+	return nil
+}
+
+func (r *FakeRunner) Pid() int {
+	return r.pid
+}
+
+func (r *FakeRunner) Signal(sig syscall.Signal) error {
+	if r.pid == 0 {
+		return fmt.Errorf("cannot find the tuned process!")
+	}
+	r.Signals = append(r.Signals, sig)
+	if sig == syscall.SIGTERM || sig == syscall.SIGKILL {
+		r.exit <- true
+	}
+	return nil
+}
+
+func (r *FakeRunner) Reset() {
+	r.pid = 0
+}
+
+func (r *FakeRunner) Recommend() (string, error) {
+	return r.Recommended, nil
+}
@@ -0,0 +1,107 @@
+package process
+
+import (
+	"context"       // context.WithTimeout()
+	"fmt"           // Errorf()
+	"os/exec"       // exec.Cmd
+	"path/filepath" // filepath.Base()
+	"sync"          // sync.Mutex
+	"time"          // time.Duration
+)
+
+// Types
+// TimeoutError is returned by RunTimeout() and RunContext() for a command which
+// did not exit in time.
+type TimeoutError struct {
+	// Command is the executable and its first argument, e.g. "tuned-adm recommend"
+	Command string
+	Timeout time.Duration
+}
+
+// Global variables
+var (
+	// number of timed out commands by TimeoutError.Command
+	timeouts struct {
+		sync.Mutex
+		counts map[string]int64
+	}
+)
+
+// Functions
+func (e *TimeoutError) Error() string {
+	return fmt.Sprintf("%s timed out after %v", e.Command, e.Timeout)
+}
+
+// commandName returns the executable of cmd and its first argument.
+func commandName(cmd *exec.Cmd) string {
+	name := filepath.Base(cmd.Path)
+	if len(cmd.Args) > 1 {
+		name += " " + cmd.Args[1]
+	}
+	return name
+}
+
+// RunTimeout runs cmd like Run(), but kills it and the processes it started if it
+// does not exit within timeout; a *TimeoutError is returned then.  A timeout of 0
+// does not limit cmd.
+func RunTimeout(cmd *exec.Cmd, timeout time.Duration) error {
+	if timeout <= 0 {
+		return Run(cmd)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return runContext(ctx, cmd, timeout)
+}
+
+// RunContext runs cmd like Run(), but kills it and the processes it started once
+// ctx is done.  A *TimeoutError is returned if the deadline of ctx passed,
+// ctx.Err() if ctx was cancelled.
+func RunContext(ctx context.Context, cmd *exec.Cmd) error {
+	var timeout time.Duration
+	if deadline, ok := ctx.Deadline(); ok {
+		timeout = time.Until(deadline).Round(time.Second)
+	}
+	return runContext(ctx, cmd, timeout)
+}
+
+// runContext implements RunContext(); timeout is reported in the *TimeoutError.
+func runContext(ctx context.Context, cmd *exec.Cmd, timeout time.Duration) error {
+	// The children holding the output pipes open would make Wait() block
+	processGroupSet(cmd)
+	if err := Start(cmd); err != nil {
+		return err
+	}
+	done := make(chan error, 1)
+	go func() { done <- Wait(cmd) }()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		processGroupKill(cmd)
+		<-done
+	}
+	if ctx.Err() != context.DeadlineExceeded {
+		return ctx.Err()
+	}
+
+	name := commandName(cmd)
+	timeouts.Lock()
+	if timeouts.counts == nil {
+		timeouts.counts = map[string]int64{}
+	}
+	timeouts.counts[name]++
+	timeouts.Unlock()
+	return &TimeoutError{Command: name, Timeout: timeout}
+}
+
+// Timeouts returns the number of commands killed on timeout by TimeoutError.Command.
+func Timeouts() map[string]int64 {
+	timeouts.Lock()
+	defer timeouts.Unlock()
+
+	counts := make(map[string]int64, len(timeouts.counts))
+	for name, n := range timeouts.counts {
+		counts[name] = n
+	}
+	return counts
+}
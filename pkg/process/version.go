@@ -0,0 +1,104 @@
+package process
+
+import (
+	"bytes"   // bytes.Buffer
+	"fmt"     // Errorf()
+	"os/exec" // exec.Command()
+	"strconv" // strconv.Atoi()
+	"strings" // strings.Fields()
+	"time"    // time.Duration
+)
+
+// Types
+// Version is a dotted tuned version, e.g. [2 10 0] for tuned 2.10.0.
+type Version []int
+
+// Features are the tuned features which differ between the tuned releases
+// shipped in RHEL 7, 8 and 9.
+type Features struct {
+	// tuned can run without D-Bus (--no-dbus)
+	NoDBus bool `json:"noDBus"`
+	// tuned distinguishes manually selected and recommended profiles
+	// (/etc/tuned/profile_mode)
+	ProfileMode bool `json:"profileMode"`
+	// tuned rewrites the active profile file whenever it (re)applies a profile
+	ActiveProfileRewrite bool `json:"activeProfileRewrite"`
+}
+
+// Functions
+// ParseVersion parses a dotted version, optionally prefixed by "v".  Anything after
+// the numeric components, e.g. "-rc1", is ignored.
+func ParseVersion(s string) (Version, error) {
+	var v Version
+
+	s = strings.TrimPrefix(strings.TrimSpace(s), "v")
+	if i := strings.IndexAny(s, "-+~ "); i >= 0 {
+		s = s[:i]
+	}
+	for _, part := range strings.Split(s, ".") {
+		n, err := strconv.Atoi(part)
+		if err != nil {
+			return nil, fmt.Errorf("invalid version %q", s)
+		}
+		v = append(v, n)
+	}
+	return v, nil
+}
+
+func (v Version) String() string {
+	parts := make([]string, len(v))
+	for i, n := range v {
+		parts[i] = strconv.Itoa(n)
+	}
+	return strings.Join(parts, ".")
+}
+
+// Less returns true if v is older than w; missing components are taken as 0.
+func (v Version) Less(w Version) bool {
+	for i := 0; i < len(v) || i < len(w); i++ {
+		var a, b int
+		if i < len(v) {
+			a = v[i]
+		}
+		if i < len(w) {
+			b = w[i]
+		}
+		if a != b {
+			return a < b
+		}
+	}
+	return false
+}
+
+// TunedVersion returns the version of tuned reported by "tuned --version".  The
+// command is killed after timeout, see RunTimeout().
+func TunedVersion(timeout time.Duration) (Version, error) {
+	// Older tuned releases print the version to stderr
+	var out bytes.Buffer
+
+	cmd := exec.Command(TunedBinary, "--version")
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	if err := RunTimeout(cmd, timeout); err != nil {
+		return nil, fmt.Errorf("failed to get the tuned version: %v: %s", err, strings.TrimSpace(out.String()))
+	}
+	// "tuned 2.10.0"
+	fields := strings.Fields(out.String())
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("failed to get the tuned version: no output from %s --version", TunedBinary)
+	}
+	return ParseVersion(fields[len(fields)-1])
+}
+
+// FeaturesFor returns the features of tuned version v; a nil v is taken as the
+// latest tuned release.
+func FeaturesFor(v Version) Features {
+	has := func(since Version) bool {
+		return v == nil || !v.Less(since)
+	}
+	return Features{
+		NoDBus:               has(Version{2, 6}),
+		ProfileMode:          has(Version{2, 11}),
+		ActiveProfileRewrite: has(Version{2, 11}),
+	}
+}
@@ -0,0 +1,118 @@
+package profile
+
+import (
+	"bytes"           // bytes.NewReader()
+	"compress/gzip"   // gzip.NewReader()
+	"encoding/base64" // base64.StdEncoding
+	"fmt"             // Errorf()
+	"io/ioutil"       // ioutil.ReadFile()
+	"os"              // os.IsNotExist()
+	"path/filepath"   // filepath.Glob()
+	"sort"            // sort.Slice()
+	"strconv"         // strconv.Atoi()
+	"strings"         // strings.HasPrefix()
+
+	"gopkg.in/yaml.v2"
+)
+
+// Constants
+const (
+	// DataGzipBase64 prefixes gzip-compressed and base64-encoded profile data
+	DataGzipBase64 = "gzip+base64:"
+)
+
+// Functions
+// DataDecode decodes profile data prefixed by DataGzipBase64; other data is
+// returned unchanged.
+func DataDecode(data string) (string, error) {
+	if !strings.HasPrefix(data, DataGzipBase64) {
+		return data, nil
+	}
+	compressed, err := base64.StdEncoding.DecodeString(strings.TrimSpace(strings.TrimPrefix(data, DataGzipBase64)))
+	if err != nil {
+		return "", fmt.Errorf("failed to decode base64 profile data: %v", err)
+	}
+	r, err := gzip.NewReader(bytes.NewReader(compressed))
+	if err != nil {
+		return "", fmt.Errorf("failed to decompress profile data: %v", err)
+	}
+	defer r.Close()
+	decoded, err := ioutil.ReadAll(r)
+	if err != nil {
+		return "", fmt.Errorf("failed to decompress profile data: %v", err)
+	}
+	return string(decoded), nil
+}
+
+// configMapChunks returns the tuned profiles ConfigMap file followed by its
+// chunks, e.g. tuned-profiles.yaml, tuned-profiles-1.yaml, tuned-profiles-2.yaml.
+func configMapChunks(configMap string) ([]string, error) {
+	type chunk struct {
+		file  string
+		index int
+	}
+	var (
+		chunks []chunk
+		files  []string
+	)
+	ext := filepath.Ext(configMap)
+	base := strings.TrimSuffix(configMap, ext)
+
+	matches, err := filepath.Glob(base + "-*" + ext)
+	if err != nil {
+		return nil, err
+	}
+	for _, match := range matches {
+		index, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(match, base+"-"), ext))
+		if err != nil {
+			// Not a chunk
+			continue
+		}
+		chunks = append(chunks, chunk{match, index})
+	}
+	sort.Slice(chunks, func(i, j int) bool { return chunks[i].index < chunks[j].index })
+
+	files = append(files, configMap)
+	for _, ch := range chunks {
+		files = append(files, ch.file)
+	}
+	return files, nil
+}
+
+// ConfigMapRead reads and merges the tuned profiles ConfigMap file configMap and
+// its chunks and decodes the profile data.  Returns nil if there is no such file.
+func ConfigMapRead(configMap string) (map[string]string, error) {
+	var mProfiles map[string]string
+
+	files, err := configMapChunks(configMap)
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range files {
+		tunedProfilesYaml, err := ioutil.ReadFile(file)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, fmt.Errorf("failed to read tuned profiles ConfigMap file %q: %v", file, err)
+		}
+
+		chunk := make(map[string]string)
+		if err = yaml.Unmarshal(tunedProfilesYaml, &chunk); err != nil {
+			return nil, fmt.Errorf("failed to parse tuned profiles ConfigMap file %q: %v", file, err)
+		}
+		if mProfiles == nil {
+			mProfiles = make(map[string]string)
+		}
+		for key, value := range chunk {
+			if _, ok := mProfiles[key]; ok {
+				return nil, fmt.Errorf("duplicate tuned profile %q in ConfigMap file %q", key, file)
+			}
+			if mProfiles[key], err = DataDecode(value); err != nil {
+				return nil, fmt.Errorf("tuned profile %q in ConfigMap file %q: %v", key, file, err)
+			}
+		}
+	}
+
+	return mProfiles, nil
+}
@@ -0,0 +1,134 @@
+package profile
+
+import (
+	"bytes"   // bytes.Buffer
+	"fmt"     // Fprintf()
+	"strings" // strings.Split()
+)
+
+// Types
+// diffOp is a line of a diff: ' ' kept, '-' removed or '+' added.
+type diffOp struct {
+	op   byte
+	line string
+	// line numbers in a and b, 0-based
+	ai, bi int
+}
+
+// Constants
+const (
+	diffContext = 3 // lines of context around the changes of a unified diff
+)
+
+// Functions
+// diffLines splits data into lines without the trailing newline.
+func diffLines(data string) []string {
+	if len(data) == 0 {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(data, "\n"), "\n")
+}
+
+// diffOps returns the edit script turning a into b based on their longest
+// common subsequence.  tuned profiles are small, O(len(a)*len(b)) is fine.
+func diffOps(a, b []string) []diffOp {
+	var ops []diffOp
+
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			ops = append(ops, diffOp{' ', a[i], i, j})
+			i++
+			j++
+		case j < len(b) && (i == len(a) || lcs[i][j+1] > lcs[i+1][j]):
+			ops = append(ops, diffOp{'+', b[j], i, j})
+			j++
+		default:
+			ops = append(ops, diffOp{'-', a[i], i, j})
+			i++
+		}
+	}
+
+	return ops
+}
+
+// Diff returns a unified diff of tuned profile data a and b labelled with
+// fromFile and toFile, or an empty string if they are the same.
+func Diff(fromFile, toFile, a, b string) string {
+	var buf bytes.Buffer
+
+	if a == b {
+		return ""
+	}
+	ops := diffOps(diffLines(a), diffLines(b))
+
+	fmt.Fprintf(&buf, "--- %s\n+++ %s\n", fromFile, toFile)
+	for start := 0; start < len(ops); {
+		// Find the next change
+		for start < len(ops) && ops[start].op == ' ' {
+			start++
+		}
+		if start == len(ops) {
+			break
+		}
+		// Extend the hunk until more than 2*diffContext unchanged lines follow a change
+		end, kept := start, 0
+		for i := start; i < len(ops) && kept <= 2*diffContext; i++ {
+			if ops[i].op == ' ' {
+				kept++
+			} else {
+				kept = 0
+				end = i + 1
+			}
+		}
+		from := start - diffContext
+		if from < 0 {
+			from = 0
+		}
+		to := end + diffContext
+		if to > len(ops) {
+			to = len(ops)
+		}
+
+		var aLen, bLen int
+		for _, op := range ops[from:to] {
+			if op.op != '+' {
+				aLen++
+			}
+			if op.op != '-' {
+				bLen++
+			}
+		}
+		aStart, bStart := ops[from].ai+1, ops[from].bi+1
+		if aLen == 0 {
+			aStart--
+		}
+		if bLen == 0 {
+			bStart--
+		}
+		fmt.Fprintf(&buf, "@@ -%d,%d +%d,%d @@\n", aStart, aLen, bStart, bLen)
+		for _, op := range ops[from:to] {
+			fmt.Fprintf(&buf, "%c%s\n", op.op, op.line)
+		}
+		start = to
+	}
+
+	return buf.String()
+}
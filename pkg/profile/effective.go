@@ -0,0 +1,133 @@
+package profile
+
+import (
+	"regexp"  // regexp.MustCompile()
+	"sort"    // sort.Strings()
+	"strings" // strings.Contains()
+)
+
+// Types
+// Effective is the effective configuration of a tuned profile: the sections of
+// the profile and the profiles it includes merged like tuned merges them, with
+// the profile variables expanded.
+type Effective struct {
+	Profile string `json:"profile" yaml:"profile"`
+	// Chain is the profile and the profiles it includes, included profiles first.
+	Chain []string `json:"chain,omitempty" yaml:"chain,omitempty"`
+	// Sections are the merged sections: section name -> option name -> value.
+	Sections map[string]map[string]string `json:"sections,omitempty" yaml:"sections,omitempty"`
+	// Variables are the profile variables, including those of variables files.
+	Variables map[string]string `json:"variables,omitempty" yaml:"variables,omitempty"`
+	// Unexpanded are the references which cannot be expanded outside of tuned,
+	// e.g. ${f:...} functions, or are undefined.
+	Unexpanded []string `json:"unexpanded,omitempty" yaml:"unexpanded,omitempty"`
+	Error      string   `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+// Global variables
+var (
+	varReference = regexp.MustCompile(`\$\{([^}]+)\}`)
+)
+
+// Constants
+const (
+	varExpandDepthMax = 16 // nesting of variables referencing variables
+)
+
+// Functions
+// ChainEffective returns the effective configuration of tuned profile profileName.
+// The variables files included by [variables] sections are read by readFile.
+func ChainEffective(store Store, profileName string, readFile func(path string) (string, error)) Effective {
+	e := Effective{Profile: profileName, Sections: map[string]map[string]string{}, Variables: map[string]string{}}
+
+	names, err := ChainNames(store, profileName)
+	if err != nil {
+		e.Error = err.Error()
+		return e
+	}
+	e.Chain = names
+	for _, name := range names {
+		conf, err := Load(store, name)
+		if err != nil {
+			e.Error = err.Error()
+			return e
+		}
+		for section, options := range conf {
+			if optionTrue(options["replace"]) || e.Sections[section] == nil {
+				e.Sections[section] = map[string]string{}
+			}
+			for option, value := range options {
+				if option == "replace" || (section == "main" && option == "include") {
+					continue
+				}
+				e.Sections[section][option] = value
+			}
+		}
+	}
+
+	// Variables of the included files first, the [variables] options override them
+	if vars, ok := e.Sections["variables"]; ok {
+		if include, ok := vars["include"]; ok {
+			data, err := readFile(strings.TrimSpace(include))
+			if err != nil {
+				e.Error = err.Error()
+			}
+			for _, s := range ParseSections("[variables]\n" + data) {
+				for k, v := range s.Options {
+					e.Variables[k] = v
+				}
+			}
+		}
+		for k, v := range vars {
+			if k != "include" {
+				e.Variables[k] = v
+			}
+		}
+		delete(e.Sections, "variables")
+	}
+
+	unexpanded := map[string]bool{}
+	for k, v := range e.Variables {
+		e.Variables[k] = e.expand(v, unexpanded)
+	}
+	for _, options := range e.Sections {
+		for option, value := range options {
+			options[option] = e.expand(value, unexpanded)
+		}
+	}
+	for ref := range unexpanded {
+		e.Unexpanded = append(e.Unexpanded, ref)
+	}
+	sort.Strings(e.Unexpanded)
+
+	return e
+}
+
+// expand expands the variable references in s; references which cannot be
+// expanded are kept and recorded in unexpanded.
+func (e *Effective) expand(s string, unexpanded map[string]bool) string {
+	for depth := 0; depth < varExpandDepthMax && strings.Contains(s, "${"); depth++ {
+		expanded := varReference.ReplaceAllStringFunc(s, func(ref string) string {
+			name := ref[2 : len(ref)-1]
+			if v, ok := e.Variables[name]; ok && !strings.Contains(name, ":") {
+				return v
+			}
+			unexpanded[ref] = true
+			return ref
+		})
+		if expanded == s {
+			break
+		}
+		s = expanded
+	}
+	return s
+}
+
+// optionTrue returns true if option value v is a tuned boolean true.
+func optionTrue(v string) bool {
+	switch strings.ToLower(strings.TrimSpace(v)) {
+	case "1", "true", "yes", "y":
+		return true
+	}
+	return false
+}
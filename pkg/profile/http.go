@@ -0,0 +1,302 @@
+package profile
+
+import (
+	"archive/tar"   // tar.NewReader()
+	"bytes"         // bytes.NewReader()
+	"compress/gzip" // gzip.NewReader()
+	"crypto/sha256" // sha256.Sum256()
+	"encoding/hex"  // hex.EncodeToString()
+	"fmt"           // Errorf()
+	"io"            // io.EOF
+	"io/ioutil"     // ioutil.ReadAll()
+	"net/http"      // http.Client
+	"net/url"       // url.Parse()
+	"os"            // os.IsNotExist()
+	"path"          // path.Split()
+	"path/filepath" // filepath.Join()
+	"strings"       // strings.TrimPrefix()
+	"sync"          // sync.Mutex
+	"time"          // time.Second
+
+	"gopkg.in/yaml.v2"
+	"k8s.io/klog"
+
+	"github.com/openshift/openshift-tuned/pkg/layout"
+)
+
+// Types
+// Polled is implemented by remote sources which need to be read again periodically.
+type Polled interface {
+	// Interval returns the polling interval.
+	Interval() time.Duration
+}
+
+// HTTPSource downloads the profiles from an HTTP(S) URL.  The URL serves either a
+// tuned profiles ConfigMap YAML file (name: data) or a gzip-compressed tarball of
+// <name>/tuned.conf files and their signatures.  The download is verified against
+// the SHA-256 checksum in the "sha256" URL fragment (https://host/p.tgz#sha256=...)
+// or served at <URL>.sha256, if any.  Unchanged profiles are not downloaded again
+// (ETag), and the last profiles downloaded are used while the URL is unreachable.
+type HTTPSource struct {
+	URL string
+	// CacheDir keeps the last profiles downloaded across restarts; empty caches
+	// the profiles in memory only.
+	CacheDir string
+	// PollInterval is the interval to check the URL for changes.
+	PollInterval time.Duration
+
+	client   *http.Client
+	mu       sync.Mutex
+	etag     string
+	profiles map[string]string
+}
+
+// Constants
+const (
+	httpSourceTimeout      = 30 * time.Second
+	httpSourcePollInterval = 5 * time.Minute
+	httpSourceSizeMax      = 64 << 20
+	httpSourceFragment     = "sha256="
+	httpSourceChecksumExt  = ".sha256"
+)
+
+// Functions
+func init() {
+	for _, scheme := range []string{"http", "https"} {
+		scheme := scheme
+		RegisterSourceKind(scheme, func(location string) (Source, error) {
+			return NewHTTPSource(scheme + ":" + location)
+		})
+	}
+}
+
+// NewHTTPSource creates an HTTPSource downloading the profiles from rawURL.
+func NewHTTPSource(rawURL string) (*HTTPSource, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid profile source URL %q: %v", rawURL, err)
+	}
+	if len(u.Fragment) > 0 && !strings.HasPrefix(u.Fragment, httpSourceFragment) {
+		return nil, fmt.Errorf("invalid profile source URL %q: unknown fragment %q", rawURL, u.Fragment)
+	}
+	return &HTTPSource{
+		URL:          rawURL,
+		PollInterval: httpSourcePollInterval,
+		client:       &http.Client{Timeout: httpSourceTimeout},
+	}, nil
+}
+
+func (s *HTTPSource) Name() string {
+	return s.URL
+}
+
+func (s *HTTPSource) Priority() int {
+	return PriorityHTTP
+}
+
+func (s *HTTPSource) Interval() time.Duration {
+	return s.PollInterval
+}
+
+// cacheFile returns the file caching the download and its ETag, or "" if disabled.
+func (s *HTTPSource) cacheFile() string {
+	if len(s.CacheDir) == 0 {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(s.URL))
+	return filepath.Join(s.CacheDir, hex.EncodeToString(sum[:8]))
+}
+
+// cacheLoad loads the download cached by a previous openshift-tuned run.
+func (s *HTTPSource) cacheLoad() {
+	file := s.cacheFile()
+	if len(file) == 0 || s.profiles != nil {
+		return
+	}
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			klog.Warningf("profile source %s: failed to read the cache: %v", s.URL, err)
+		}
+		return
+	}
+	profiles, err := httpSourceParse(data)
+	if err != nil {
+		klog.Warningf("profile source %s: ignoring the cache: %v", s.URL, err)
+		return
+	}
+	if etag, err := ioutil.ReadFile(file + ".etag"); err == nil {
+		s.etag = string(etag)
+	}
+	s.profiles = profiles
+}
+
+// cacheSave saves the download data and its etag.
+func (s *HTTPSource) cacheSave(data []byte, etag string) {
+	file := s.cacheFile()
+	if len(file) == 0 {
+		return
+	}
+	err := layout.Mkdir(s.CacheDir)
+	if err == nil {
+		err = layout.WriteFile(file, data)
+	}
+	if err == nil {
+		err = layout.WriteFile(file+".etag", []byte(etag))
+	}
+	if err != nil {
+		klog.Warningf("profile source %s: failed to update the cache: %v", s.URL, err)
+	}
+}
+
+// get downloads rawURL.  Returns nil data if the content did not change since etag.
+func (s *HTTPSource) get(rawURL string, etag string) (data []byte, newEtag string, err error) {
+	req, err := http.NewRequest("GET", rawURL, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	if len(etag) > 0 {
+		req.Header.Set("If-None-Match", etag)
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotModified:
+		return nil, etag, nil
+	case resp.StatusCode == http.StatusNotFound:
+		return nil, "", os.ErrNotExist
+	case resp.StatusCode/100 != 2:
+		return nil, "", fmt.Errorf("GET %s: %s", rawURL, resp.Status)
+	}
+	data, err = ioutil.ReadAll(io.LimitReader(resp.Body, httpSourceSizeMax+1))
+	if err != nil {
+		return nil, "", fmt.Errorf("GET %s: %v", rawURL, err)
+	}
+	if len(data) > httpSourceSizeMax {
+		return nil, "", fmt.Errorf("GET %s: response larger than %d bytes", rawURL, httpSourceSizeMax)
+	}
+	return data, resp.Header.Get("ETag"), nil
+}
+
+// checksumVerify verifies data against the checksum in the URL fragment or at
+// <URL>.sha256; data without a checksum is accepted.
+func (s *HTTPSource) checksumVerify(data []byte) error {
+	u, err := url.Parse(s.URL)
+	if err != nil {
+		return err
+	}
+	want := strings.TrimPrefix(u.Fragment, httpSourceFragment)
+	if len(want) == 0 {
+		u.Fragment = ""
+		sum, _, err := s.get(u.String()+httpSourceChecksumExt, "")
+		if err == os.ErrNotExist {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get the checksum: %v", err)
+		}
+		// sha256sum(1) format: "<checksum>  <file>"
+		if fields := strings.Fields(string(sum)); len(fields) > 0 {
+			want = fields[0]
+		}
+	}
+	got := sha256.Sum256(data)
+	if !strings.EqualFold(want, hex.EncodeToString(got[:])) {
+		return fmt.Errorf("checksum mismatch: expected sha256 %s, got %s", want, hex.EncodeToString(got[:]))
+	}
+	return nil
+}
+
+func (s *HTTPSource) Profiles() (map[string]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.cacheLoad()
+	u, _ := url.Parse(s.URL)
+	u.Fragment = ""
+
+	data, etag, err := s.get(u.String(), s.etag)
+	if err == nil && data != nil {
+		if err = s.checksumVerify(data); err == nil {
+			var profiles map[string]string
+			if profiles, err = httpSourceParse(data); err == nil {
+				s.profiles, s.etag = profiles, etag
+				s.cacheSave(data, etag)
+			}
+		}
+	}
+	if err != nil {
+		if s.profiles == nil {
+			return nil, err
+		}
+		klog.Warningf("profile source %s: %v; using the last profiles downloaded", s.URL, err)
+	}
+	return s.profiles, nil
+}
+
+// httpSourceParse parses a tuned profiles ConfigMap YAML file or a gzip-compressed
+// tarball of <name>/tuned.conf files.
+func httpSourceParse(data []byte) (map[string]string, error) {
+	profiles := map[string]string{}
+
+	if !bytes.HasPrefix(data, []byte{0x1f, 0x8b}) {
+		if err := yaml.Unmarshal(data, &profiles); err != nil {
+			return nil, fmt.Errorf("failed to parse tuned profiles: %v", err)
+		}
+		for name, value := range profiles {
+			if err := NameCheck(strings.TrimSuffix(name, SignatureSuffix)); err != nil {
+				return nil, err
+			}
+			decoded, err := DataDecode(value)
+			if err != nil {
+				return nil, fmt.Errorf("tuned profile %q: %v", name, err)
+			}
+			profiles[name] = decoded
+		}
+		return profiles, nil
+	}
+
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress tuned profiles: %v", err)
+	}
+	defer gz.Close()
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read tuned profiles tarball: %v", err)
+		}
+		if hdr.Typeflag != tar.TypeReg && hdr.Typeflag != tar.TypeRegA {
+			continue
+		}
+		dir, file := path.Split(strings.TrimPrefix(path.Clean(hdr.Name), "./"))
+		name := strings.TrimSuffix(dir, "/")
+		if len(name) == 0 || strings.Contains(name, "/") {
+			continue
+		}
+		if err := NameCheck(name); err != nil {
+			return nil, err
+		}
+		switch file {
+		case ConfFile:
+		case ConfFile + SignatureSuffix:
+			name += SignatureSuffix
+		default:
+			continue
+		}
+		content, err := ioutil.ReadAll(tr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %q from tuned profiles tarball: %v", hdr.Name, err)
+		}
+		profiles[name] = string(content)
+	}
+	return profiles, nil
+}
@@ -0,0 +1,92 @@
+package profile
+
+import (
+	"archive/tar"   // tar.NewWriter()
+	"bytes"         // bytes.Buffer
+	"compress/gzip" // gzip.NewWriter()
+	"reflect"       // reflect.DeepEqual()
+	"testing"
+)
+
+// tarball returns a gzip-compressed tarball of files, path -> content.
+func tarball(t *testing.T, files [][2]string) []byte {
+	var buf bytes.Buffer
+
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for _, f := range files {
+		hdr := &tar.Header{Name: f[0], Mode: 0644, Size: int64(len(f[1])), Typeflag: tar.TypeReg}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(f[1])); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestHTTPSourceParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    []byte
+		want    map[string]string
+		wantErr bool
+	}{
+		{
+			name: "yaml",
+			data: []byte("a: |\n  [main]\n  summary=a\na.sig: sig\n"),
+			want: map[string]string{"a": "[main]\nsummary=a\n", "a.sig": "sig"},
+		},
+		{
+			name:    "yaml name escaping the profiles directory",
+			data:    []byte("../../etc/foo: |\n  [main]\n"),
+			wantErr: true,
+		},
+		{
+			name:    "yaml parent directory name",
+			data:    []byte("..: |\n  [main]\n"),
+			wantErr: true,
+		},
+		{
+			name:    "yaml empty name",
+			data:    []byte("'': |\n  [main]\n"),
+			wantErr: true,
+		},
+		{
+			name: "tarball",
+			data: tarball(t, [][2]string{
+				{"a/tuned.conf", "[main]\n"},
+				{"./b/tuned.conf", "[main]\n"},
+				{"b/tuned.conf.sig", "sig"},
+				{"b/script.sh", "#!/bin/sh\n"},
+				{"c/d/tuned.conf", "[main]\n"},
+				{"tuned.conf", "[main]\n"},
+			}),
+			want: map[string]string{"a": "[main]\n", "b": "[main]\n", "b.sig": "sig"},
+		},
+		{
+			name:    "tarball parent directory entry",
+			data:    tarball(t, [][2]string{{"../tuned.conf", "[main]\n"}}),
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := httpSourceParse(tt.data)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("httpSourceParse() error = %v, want error %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("httpSourceParse() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package profile
+
+import (
+	"fmt"     // Errorf()
+	"sort"    // sort.Strings()
+	"strings" // strings.Join()
+)
+
+// Functions
+// ValidateIncludes checks the include= chains of tuned profiles about to be
+// extracted.  Included profiles are looked up in profiles first, then in store.
+// Returns an error listing every include of a missing profile and every include
+// cycle, with the chain of includes leading to it.
+func ValidateIncludes(profiles map[string]string, store Store) error {
+	var (
+		names, problems []string
+		visit           func(name string)
+	)
+	const (
+		visiting = 1
+		visited  = 2
+	)
+	state := map[string]int{}
+	path := []string{}
+	reported := map[string]bool{}
+
+	report := func(problem string) {
+		if !reported[problem] {
+			reported[problem] = true
+			problems = append(problems, problem)
+		}
+	}
+
+	read := func(name string) (string, error) {
+		if data, ok := profiles[name]; ok {
+			return data, nil
+		}
+		return store.ReadProfile(name)
+	}
+
+	visit = func(name string) {
+		switch state[name] {
+		case visiting:
+			// Report the cycle starting from its first profile
+			for i := range path {
+				if path[i] == name {
+					report(fmt.Sprintf("include cycle: %s", strings.Join(append(path[i:len(path):len(path)], name), " -> ")))
+					break
+				}
+			}
+			return
+		case visited:
+			return
+		}
+
+		data, err := read(name)
+		if err != nil {
+			if len(path) == 0 {
+				report(err.Error())
+			} else {
+				report(fmt.Sprintf("missing included profile %q: %s", name, strings.Join(append(path[:len(path):len(path)], name), " -> ")))
+			}
+			state[name] = visited
+			return
+		}
+
+		state[name] = visiting
+		path = append(path, name)
+		for _, include := range Parse(data).Includes() {
+			if strings.Contains(include, "${") {
+				// Variables are expanded by tuned, we cannot resolve them
+				continue
+			}
+			visit(include)
+		}
+		path = path[:len(path)-1]
+		state[name] = visited
+	}
+
+	for name := range profiles {
+		if IsSignature(name) {
+			continue
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		visit(name)
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid tuned profile includes: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
@@ -0,0 +1,147 @@
+package profile
+
+import (
+	"bufio"   // scanner
+	"fmt"     // Sprintf()
+	"sort"    // sort.Slice()
+	"strings" // strings.TrimSpace()
+)
+
+// Types
+// Problem is a problem of a tuned profile found by Lint().
+type Problem struct {
+	Profile string `json:"profile"`
+	// Line is the line number of the problem, 0 if not tied to a line.
+	Line    int    `json:"line,omitempty"`
+	Message string `json:"message"`
+	// Warning is set for problems which do not prevent tuned from applying the profile.
+	Warning bool `json:"warning,omitempty"`
+}
+
+// Global variables
+var (
+	// the tuned plugins, i.e. the section names tuned knows besides [main] and
+	// [variables]; other sections need a type= option naming the plugin
+	knownPlugins = map[string]bool{
+		"audio": true, "bootloader": true, "cpu": true, "disk": true, "eeepc_she": true,
+		"irq": true, "irqbalance": true, "modules": true, "mounts": true, "net": true,
+		"rtentsk": true, "scheduler": true, "script": true, "scsi_host": true, "selinux": true,
+		"service": true, "sysctl": true, "sysfs": true, "systemd": true, "uncore": true,
+		"usb": true, "video": true, "vm": true,
+	}
+	// the options of the [main] section
+	mainOptions = map[string]bool{"include": true, "summary": true, "description": true}
+)
+
+// Functions
+func (p Problem) String() string {
+	kind := "error"
+	if p.Warning {
+		kind = "warning"
+	}
+	if p.Line > 0 {
+		return fmt.Sprintf("%s:%d: %s: %s", p.Profile, p.Line, kind, p.Message)
+	}
+	return fmt.Sprintf("%s: %s: %s", p.Profile, kind, p.Message)
+}
+
+// Lint checks the tuned.conf data of profile name: its syntax, the plugins of its
+// sections, the options of [main] and its canary probes.
+func Lint(name string, data string) []Problem {
+	var problems []Problem
+
+	problem := func(line int, warning bool, format string, args ...interface{}) {
+		problems = append(problems, Problem{Profile: name, Line: line, Message: fmt.Sprintf(format, args...), Warning: warning})
+	}
+
+	section, sectionLine := "", 0
+	options := map[string]int{}
+	sectionEnd := func() {
+		if len(section) == 0 || section == "main" || section == "variables" || knownPlugins[section] {
+			return
+		}
+		if _, ok := options["type"]; !ok {
+			problem(sectionLine, true, "unknown plugin %q; set type= for a custom section name", section)
+		}
+	}
+
+	scanner := bufio.NewScanner(strings.NewReader(data))
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) == 0 || line[0] == '#' || line[0] == ';' {
+			continue
+		}
+		if line[0] == '[' {
+			if line[len(line)-1] != ']' || len(strings.TrimSpace(line[1:len(line)-1])) == 0 {
+				problem(n, false, "invalid section header %q", line)
+				continue
+			}
+			sectionEnd()
+			section, sectionLine = strings.TrimSpace(line[1:len(line)-1]), n
+			options = map[string]int{}
+			continue
+		}
+		i := strings.Index(line, "=")
+		if i <= 0 {
+			problem(n, false, "invalid line %q, expected [section], option=value or a comment", line)
+			continue
+		}
+		if len(section) == 0 {
+			problem(n, true, "option %q outside of a section is ignored", strings.TrimSpace(line[:i]))
+			continue
+		}
+		option := strings.TrimSpace(line[:i])
+		if prev, ok := options[option]; ok {
+			problem(n, true, "option %q of section [%s] overrides the one on line %d", option, section, prev)
+		}
+		options[option] = n
+		if section == "main" && !mainOptions[option] {
+			problem(n, true, "unknown option %q of section [main]", option)
+		}
+		if option == "type" && !knownPlugins[strings.TrimSpace(line[i+1:])] {
+			problem(n, true, "unknown plugin type %q", strings.TrimSpace(line[i+1:]))
+		}
+	}
+	sectionEnd()
+
+	if _, err := ParseProbes(name, data); err != nil {
+		problem(0, true, "%v", err)
+	}
+
+	return problems
+}
+
+// Validate checks tuned profiles about to be extracted, profile name -> data,
+// by Lint() and ValidateIncludes().  Returns the problems found; the error is set
+// if any problem prevents tuned from applying a profile.
+func Validate(profiles map[string]string, store Store) ([]Problem, error) {
+	var (
+		problems []Problem
+		errs     []string
+	)
+
+	for name, data := range profiles {
+		if IsSignature(name) {
+			continue
+		}
+		problems = append(problems, Lint(name, data)...)
+	}
+	sort.Slice(problems, func(i, j int) bool {
+		if problems[i].Profile != problems[j].Profile {
+			return problems[i].Profile < problems[j].Profile
+		}
+		return problems[i].Line < problems[j].Line
+	})
+	for _, p := range problems {
+		if !p.Warning {
+			errs = append(errs, p.String())
+		}
+	}
+	if err := ValidateIncludes(profiles, store); err != nil {
+		errs = append(errs, err.Error())
+	}
+	if len(errs) > 0 {
+		return problems, fmt.Errorf("%s", strings.Join(errs, "; "))
+	}
+	return problems, nil
+}
@@ -0,0 +1,101 @@
+package profile
+
+import (
+	"fmt"     // Errorf()
+	"strings" // strings.HasPrefix()
+)
+
+// Types
+// Probe is a canary check verifying a tuned profile was applied.  Probes are
+// defined by tuned.conf comments, which tuned ignores:
+//
+//	# probe: sysctl <key> = <value>    the sysctl has the value
+//	# probe: exists <path>             the file exists
+//	# probe: exec <path> [args...]     the command exits with status 0
+type Probe struct {
+	// Profile is the profile defining the probe.
+	Profile string `json:"profile"`
+	// Kind is "sysctl", "exists" or "exec".
+	Kind string `json:"kind"`
+	// Args are the arguments of the probe: key and value, path or command.
+	Args []string `json:"args"`
+}
+
+// Constants
+const (
+	probePrefix = "probe:"
+
+	ProbeSysctl = "sysctl"
+	ProbeExists = "exists"
+	ProbeExec   = "exec"
+)
+
+// Functions
+// ParseProbes returns the probes defined by the comments of tuned.conf data of
+// profile profileName.
+func ParseProbes(profileName string, data string) ([]Probe, error) {
+	var probes []Probe
+
+	for n, line := range strings.Split(data, "\n") {
+		line = strings.TrimSpace(line)
+		if !strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimSpace(strings.TrimLeft(line, "#"))
+		if !strings.HasPrefix(line, probePrefix) {
+			continue
+		}
+		p, err := probeParse(profileName, strings.TrimSpace(strings.TrimPrefix(line, probePrefix)))
+		if err != nil {
+			return nil, fmt.Errorf("profile %q line %d: %v", profileName, n+1, err)
+		}
+		probes = append(probes, p)
+	}
+	return probes, nil
+}
+
+func probeParse(profileName string, s string) (Probe, error) {
+	fields := strings.Fields(s)
+	if len(fields) < 2 {
+		return Probe{}, fmt.Errorf("invalid probe %q", s)
+	}
+	p := Probe{Profile: profileName, Kind: fields[0]}
+	switch p.Kind {
+	case ProbeSysctl:
+		kv := strings.SplitN(strings.TrimSpace(strings.TrimPrefix(s, ProbeSysctl)), "=", 2)
+		if len(kv) != 2 || len(strings.TrimSpace(kv[0])) == 0 {
+			return Probe{}, fmt.Errorf("invalid sysctl probe %q, expected sysctl <key> = <value>", s)
+		}
+		p.Args = []string{SysctlKey(kv[0]), strings.Join(strings.Fields(kv[1]), " ")}
+	case ProbeExists:
+		p.Args = []string{strings.TrimSpace(strings.TrimPrefix(s, ProbeExists))}
+	case ProbeExec:
+		p.Args = fields[1:]
+	default:
+		return Probe{}, fmt.Errorf("unknown probe kind %q, expected %s, %s or %s", p.Kind, ProbeSysctl, ProbeExists, ProbeExec)
+	}
+	return p, nil
+}
+
+// ChainProbes returns the probes of tuned profile profileName and all the profiles
+// it includes in the order returned by ChainNames().
+func ChainProbes(store Store, profileName string) ([]Probe, error) {
+	var probes []Probe
+
+	names, err := ChainNames(store, profileName)
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range names {
+		data, err := store.ReadProfile(name)
+		if err != nil {
+			return nil, err
+		}
+		p, err := ParseProbes(name, data)
+		if err != nil {
+			return nil, err
+		}
+		probes = append(probes, p...)
+	}
+	return probes, nil
+}
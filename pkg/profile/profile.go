@@ -0,0 +1,275 @@
+// Package profile parses, stores and verifies tuned profiles.
+package profile
+
+import (
+	"bufio"         // scanner
+	"crypto/sha256" // sha256.New()
+	"encoding/hex"  // hex.EncodeToString()
+	"fmt"           // Fprintf()
+	"reflect"       // reflect.DeepEqual()
+	"strings"       // strings.TrimSpace()
+)
+
+// Types
+// Section is a section of a tuned configuration file
+type Section struct {
+	Name    string
+	Options map[string]string
+}
+
+// Conf holds a parsed tuned.conf file: section name -> option name -> value
+type Conf map[string]map[string]string
+
+// Constants
+const (
+	ConfFile = "tuned.conf"
+)
+
+// Functions
+// ParseSections parses tuned configuration file data (tuned.conf, recommend.conf) and
+// returns its sections in order.  Only the subset of the configobj syntax used by
+// tuned is supported: [sections], key=value options and comments.
+func ParseSections(data string) []Section {
+	var sections []Section
+
+	scanner := bufio.NewScanner(strings.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) == 0 || line[0] == '#' || line[0] == ';' {
+			continue
+		}
+		if line[0] == '[' && line[len(line)-1] == ']' {
+			sections = append(sections, Section{
+				Name:    strings.TrimSpace(line[1 : len(line)-1]),
+				Options: map[string]string{},
+			})
+			continue
+		}
+		i := strings.Index(line, "=")
+		if i < 0 || len(sections) == 0 {
+			// Not an option or an option outside of a section, ignore it
+			continue
+		}
+		sections[len(sections)-1].Options[strings.TrimSpace(line[:i])] = strings.TrimSpace(line[i+1:])
+	}
+
+	return sections
+}
+
+// Parse parses tuned.conf data.  Options of repeated sections are merged.
+func Parse(data string) Conf {
+	conf := Conf{}
+
+	for _, section := range ParseSections(data) {
+		if _, ok := conf[section.Name]; !ok {
+			conf[section.Name] = map[string]string{}
+		}
+		for option, value := range section.Options {
+			conf[section.Name][option] = value
+		}
+	}
+
+	return conf
+}
+
+// Load loads tuned profile profileName from store.
+func Load(store Store, profileName string) (Conf, error) {
+	data, err := store.ReadProfile(profileName)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(data), nil
+}
+
+// Includes returns the names of profiles included by the [main] section of conf.
+func (conf Conf) Includes() []string {
+	var includes []string
+
+	main, ok := conf["main"]
+	if !ok {
+		return nil
+	}
+	for _, include := range strings.Split(main["include"], ",") {
+		include = strings.TrimSpace(include)
+		if len(include) > 0 {
+			includes = append(includes, include)
+		}
+	}
+
+	return includes
+}
+
+// ChainNames returns the names of tuned profile profileName and all the
+// profiles it includes in the order tuned applies them, i.e. included profiles
+// first.  Include cycles are broken by visiting every profile only once.
+func ChainNames(store Store, profileName string) ([]string, error) {
+	var (
+		chain []string
+		visit func(string) error
+	)
+	seen := map[string]bool{}
+
+	visit = func(name string) error {
+		if seen[name] {
+			return nil
+		}
+		seen[name] = true
+
+		conf, err := Load(store, name)
+		if err != nil {
+			return err
+		}
+		for _, include := range conf.Includes() {
+			if strings.Contains(include, "${") {
+				// Variables are expanded by tuned, we cannot resolve them
+				continue
+			}
+			if err = visit(include); err != nil {
+				return err
+			}
+		}
+		chain = append(chain, name)
+		return nil
+	}
+
+	if err := visit(profileName); err != nil {
+		return nil, err
+	}
+
+	return chain, nil
+}
+
+// ChainLoad loads tuned profile profileName and all the profiles it includes
+// in the order returned by ChainNames().
+func ChainLoad(store Store, profileName string) ([]Conf, error) {
+	var chain []Conf
+
+	names, err := ChainNames(store, profileName)
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range names {
+		conf, err := Load(store, name)
+		if err != nil {
+			return nil, err
+		}
+		chain = append(chain, conf)
+	}
+
+	return chain, nil
+}
+
+// Hash returns a hash of tuned profile data.
+func Hash(data string) string {
+	sum := sha256.Sum256([]byte(data))
+	return hex.EncodeToString(sum[:])
+}
+
+// ChainHash returns a hash of the content of tuned profile profileName and
+// all the profiles it includes.
+func ChainHash(store Store, profileName string) (string, error) {
+	names, err := ChainNames(store, profileName)
+	if err != nil {
+		return "", err
+	}
+	h := sha256.New()
+	for _, name := range names {
+		data, err := store.ReadProfile(name)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(h, "%s\x00%s\x00", name, data)
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// SysctlKey normalizes sysctl key to the dotted form, e.g. net/ipv4/ip_forward
+// to net.ipv4.ip_forward.
+func SysctlKey(key string) string {
+	return strings.Replace(strings.TrimSpace(key), "/", ".", -1)
+}
+
+// ChainSysctls returns the sysctls set by the [sysctl] sections of chain, a profile
+// and the profiles it includes in the order returned by ChainLoad(); later
+// profiles override earlier ones.
+func ChainSysctls(chain []Conf) map[string]string {
+	sysctls := map[string]string{}
+
+	for _, conf := range chain {
+		for key, value := range conf["sysctl"] {
+			if key == "type" || key == "replace" || key == "devices" {
+				// Plugin options, not sysctls
+				continue
+			}
+			sysctls[SysctlKey(key)] = value
+		}
+	}
+	return sysctls
+}
+
+// ChainSysctlChanges returns the sysctls whose values differ between chains old
+// and new, as set by new, if these are the only differences: both chains consist
+// of the same number of profiles with the same sections and options.  Returns
+// false if anything else changed.
+func ChainSysctlChanges(old, new []Conf) (map[string]string, bool) {
+	changed := map[string]string{}
+
+	if len(old) != len(new) {
+		return nil, false
+	}
+	for i := range new {
+		if len(old[i]) != len(new[i]) {
+			return nil, false
+		}
+		for section, options := range new[i] {
+			oldOptions, ok := old[i][section]
+			if !ok || len(oldOptions) != len(options) {
+				return nil, false
+			}
+			if section != "sysctl" {
+				if !reflect.DeepEqual(oldOptions, options) {
+					return nil, false
+				}
+				continue
+			}
+			for key, value := range options {
+				oldValue, ok := oldOptions[key]
+				if !ok {
+					return nil, false
+				}
+				if key == "type" || key == "replace" || key == "devices" {
+					if oldValue != value {
+						return nil, false
+					}
+					continue
+				}
+				if oldValue != value {
+					changed[SysctlKey(key)] = ""
+				}
+			}
+		}
+	}
+
+	sysctls := ChainSysctls(new)
+	for key := range changed {
+		changed[key] = sysctls[key]
+	}
+	return changed, true
+}
+
+// ChainSysfs returns the sysfs settings of the [sysfs] sections of chain: path
+// (possibly a glob pattern) -> value; later profiles override earlier ones.
+func ChainSysfs(chain []Conf) map[string]string {
+	sysfs := map[string]string{}
+
+	for _, conf := range chain {
+		for path, value := range conf["sysfs"] {
+			if path == "type" || path == "replace" || path == "devices" {
+				continue
+			}
+			sysfs[strings.TrimSpace(path)] = value
+		}
+	}
+	return sysfs
+}
@@ -0,0 +1,161 @@
+package profile
+
+import (
+	"reflect" // reflect.DeepEqual()
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want Conf
+	}{
+		{
+			name: "sections and options",
+			data: "[main]\nsummary=test\ninclude=a, b\n\n[sysctl]\nvm.swappiness = 10\n",
+			want: Conf{
+				"main":   {"summary": "test", "include": "a, b"},
+				"sysctl": {"vm.swappiness": "10"},
+			},
+		},
+		{
+			name: "comments and blank lines",
+			data: "# comment\n; comment\n\n[main]\n  # indented comment\nsummary=test\n",
+			want: Conf{"main": {"summary": "test"}},
+		},
+		{
+			name: "option outside of a section",
+			data: "summary=test\n[main]\n",
+			want: Conf{"main": {}},
+		},
+		{
+			name: "line without an option",
+			data: "[main]\nsummary\n",
+			want: Conf{"main": {}},
+		},
+		{
+			name: "value with an equal sign",
+			data: "[bootloader]\ncmdline=isolcpus=1-3\n",
+			want: Conf{"bootloader": {"cmdline": "isolcpus=1-3"}},
+		},
+		{
+			name: "repeated section merged",
+			data: "[sysctl]\na=1\nb=2\n[sysctl]\nb=3\n",
+			want: Conf{"sysctl": {"a": "1", "b": "3"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Parse(tt.data); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Parse() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestChainNames(t *testing.T) {
+	tests := []struct {
+		name     string
+		profiles map[string]string
+		want     []string
+		wantErr  bool
+	}{
+		{
+			name:     "no includes",
+			profiles: map[string]string{"p": "[main]\n"},
+			want:     []string{"p"},
+		},
+		{
+			name: "includes applied first",
+			profiles: map[string]string{
+				"p": "[main]\ninclude=a,b\n",
+				"a": "[main]\ninclude=c\n",
+				"b": "[main]\n",
+				"c": "[main]\n",
+			},
+			want: []string{"c", "a", "b", "p"},
+		},
+		{
+			name: "include cycle",
+			profiles: map[string]string{
+				"p": "[main]\ninclude=a\n",
+				"a": "[main]\ninclude=p\n",
+			},
+			want: []string{"a", "p"},
+		},
+		{
+			name:     "include with a variable skipped",
+			profiles: map[string]string{"p": "[main]\ninclude=${f:virt_check:a:b}\n"},
+			want:     []string{"p"},
+		},
+		{
+			name:     "missing include",
+			profiles: map[string]string{"p": "[main]\ninclude=missing\n"},
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := NewMemStore()
+			store.WriteProfiles(tt.profiles)
+			got, err := ChainNames(store, "p")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ChainNames() error = %v, want error %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ChainNames() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestChainHash(t *testing.T) {
+	hash := func(profiles map[string]string) string {
+		store := NewMemStore()
+		store.WriteProfiles(profiles)
+		h, err := ChainHash(store, "p")
+		if err != nil {
+			t.Fatalf("ChainHash() error = %v", err)
+		}
+		return h
+	}
+	base := map[string]string{"p": "[main]\ninclude=a\n", "a": "[sysctl]\nvm.swappiness=10\n", "unrelated": "[main]\n"}
+
+	tests := []struct {
+		name     string
+		change   map[string]string
+		wantSame bool
+	}{
+		{
+			name:     "unrelated profile changed",
+			change:   map[string]string{"unrelated": "[main]\nsummary=changed\n"},
+			wantSame: true,
+		},
+		{
+			name:   "included profile changed",
+			change: map[string]string{"a": "[sysctl]\nvm.swappiness=20\n"},
+		},
+		{
+			name:   "profile changed",
+			change: map[string]string{"p": "[main]\ninclude=a\nsummary=changed\n"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			changed := map[string]string{}
+			for name, data := range base {
+				changed[name] = data
+			}
+			for name, data := range tt.change {
+				changed[name] = data
+			}
+			if same := hash(base) == hash(changed); same != tt.wantSame {
+				t.Errorf("ChainHash() unchanged = %v, want %v", same, tt.wantSame)
+			}
+		})
+	}
+}
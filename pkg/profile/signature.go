@@ -0,0 +1,107 @@
+package profile
+
+import (
+	"crypto"          // crypto.SHA256
+	"crypto/ecdsa"    // ecdsa.PublicKey
+	"crypto/rsa"      // rsa.PublicKey
+	"crypto/sha256"   // sha256.Sum256()
+	"crypto/x509"     // x509.ParsePKIXPublicKey()
+	"encoding/asn1"   // asn1.Unmarshal()
+	"encoding/base64" // base64.StdEncoding
+	"encoding/pem"    // pem.Decode()
+	"fmt"             // Errorf()
+	"io/ioutil"       // ioutil.ReadFile()
+	"math/big"        // big.Int
+	"strings"         // strings.HasSuffix()
+)
+
+// Constants
+const (
+	// SignatureSuffix is the suffix of profile entries holding a base64-encoded
+	// detached signature of the profile data, e.g. "openshift-node.sig"
+	SignatureSuffix = ".sig"
+)
+
+// Functions
+// IsSignature returns true if profile entry name holds a profile signature.
+func IsSignature(name string) bool {
+	return strings.HasSuffix(name, SignatureSuffix)
+}
+
+func signingKeyLoad(path string) (crypto.PublicKey, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read profile signing key %q: %v", path, err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("failed to decode PEM profile signing key %q", path)
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse profile signing key %q: %v", path, err)
+	}
+	return key, nil
+}
+
+func signatureVerify(key crypto.PublicKey, data []byte, sig []byte) error {
+	digest := sha256.Sum256(data)
+
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		return rsa.VerifyPKCS1v15(k, crypto.SHA256, digest[:], sig)
+	case *ecdsa.PublicKey:
+		var esig struct {
+			R, S *big.Int
+		}
+		if _, err := asn1.Unmarshal(sig, &esig); err != nil {
+			return fmt.Errorf("malformed ECDSA signature: %v", err)
+		}
+		if !ecdsa.Verify(k, digest[:], esig.R, esig.S) {
+			return fmt.Errorf("ECDSA verification failure")
+		}
+		return nil
+	}
+
+	return fmt.Errorf("unsupported profile signing key type %T", key)
+}
+
+// Verify verifies the signatures of tuned profiles with the PEM-encoded public key
+// in file keyFile.  profiles maps profile names to their data and contains the
+// signature entries as well.  Unsigned profiles are only accepted when signed profiles
+// are not required.  A profile with an invalid signature is always refused.
+func Verify(profiles map[string]string, keyFile string, requireSigned bool) error {
+	if !requireSigned && len(keyFile) == 0 {
+		return nil
+	}
+	if len(keyFile) == 0 {
+		return fmt.Errorf("signed profiles required, but no profile signing key specified")
+	}
+
+	key, err := signingKeyLoad(keyFile)
+	if err != nil {
+		return err
+	}
+
+	for name, data := range profiles {
+		if IsSignature(name) {
+			continue
+		}
+		sigB64, ok := profiles[name+SignatureSuffix]
+		if !ok {
+			if requireSigned {
+				return fmt.Errorf("tuned profile %q is not signed", name)
+			}
+			continue
+		}
+		sig, err := base64.StdEncoding.DecodeString(strings.TrimSpace(sigB64))
+		if err != nil {
+			return fmt.Errorf("failed to decode signature of tuned profile %q: %v", name, err)
+		}
+		if err = signatureVerify(key, []byte(data), sig); err != nil {
+			return fmt.Errorf("invalid signature of tuned profile %q: %v", name, err)
+		}
+	}
+
+	return nil
+}
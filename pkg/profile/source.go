@@ -0,0 +1,182 @@
+package profile
+
+import (
+	"fmt"           // Errorf()
+	"io/ioutil"     // ioutil.ReadDir()
+	"os"            // os.IsNotExist()
+	"path/filepath" // filepath.Join()
+	"sort"          // sort.SliceStable()
+	"strings"       // strings.SplitN()
+	"sync"          // sync.Mutex
+)
+
+// Types
+// Source provides tuned profiles to extract.
+type Source interface {
+	// Name identifies the source, e.g. in logs and the /profiles API.
+	Name() string
+	// Priority orders the sources; profiles of a source with a higher priority
+	// take precedence over the profiles of the same name from other sources.
+	Priority() int
+	// Profiles returns profile name -> tuned.conf data, including the profile
+	// signatures (see SignatureSuffix).  A nil map means no profiles are available.
+	Profiles() (map[string]string, error)
+}
+
+// Watched is implemented by sources backed by local files; the sources are read
+// again when the files change.
+type Watched interface {
+	// Paths returns the files/directories to watch for changes.
+	Paths() []string
+}
+
+// SourceFactory creates a Source from its location, e.g. a file path or a URL.
+type SourceFactory func(location string) (Source, error)
+
+// ConfigMapSource reads the profiles from a tuned profiles ConfigMap file.
+type ConfigMapSource struct {
+	Path string
+}
+
+// DirSource reads the profiles from the <name>/tuned.conf files of a directory.
+type DirSource struct {
+	Dir string
+}
+
+// StaticSource provides the profiles set by Set(), e.g. from an API object.
+type StaticSource struct {
+	name     string
+	priority int
+	mu       sync.Mutex
+	profiles map[string]string
+}
+
+// Constants
+const (
+	// default source priorities
+	PriorityDir       = 10
+	PriorityHTTP      = 20
+	PriorityConfigMap = 50
+	PriorityTuned     = 100
+)
+
+// Global variables
+var (
+	sourceKinds = map[string]SourceFactory{
+		"configmap": func(location string) (Source, error) { return &ConfigMapSource{Path: location}, nil },
+		"dir":       func(location string) (Source, error) { return &DirSource{Dir: location}, nil },
+	}
+)
+
+// Functions
+// RegisterSourceKind makes sources of kind available to NewSource().
+func RegisterSourceKind(kind string, factory SourceFactory) {
+	sourceKinds[kind] = factory
+}
+
+// NewSource creates a source from spec "kind:location", e.g. "dir:/etc/tuned-extra".
+func NewSource(spec string) (Source, error) {
+	parts := strings.SplitN(spec, ":", 2)
+	if len(parts) != 2 || len(parts[1]) == 0 {
+		return nil, fmt.Errorf("invalid profile source %q, expected kind:location", spec)
+	}
+	factory, ok := sourceKinds[parts[0]]
+	if !ok {
+		var kinds []string
+		for kind := range sourceKinds {
+			kinds = append(kinds, kind)
+		}
+		sort.Strings(kinds)
+		return nil, fmt.Errorf("unknown profile source kind %q, known kinds: %s", parts[0], strings.Join(kinds, ", "))
+	}
+	return factory(parts[1])
+}
+
+// SortSources sorts sources by increasing priority.
+func SortSources(sources []Source) {
+	sort.SliceStable(sources, func(i, j int) bool {
+		return sources[i].Priority() < sources[j].Priority()
+	})
+}
+
+func (s *ConfigMapSource) Name() string {
+	return "configmap"
+}
+
+func (s *ConfigMapSource) Priority() int {
+	return PriorityConfigMap
+}
+
+func (s *ConfigMapSource) Profiles() (map[string]string, error) {
+	return ConfigMapRead(s.Path)
+}
+
+func (s *ConfigMapSource) Paths() []string {
+	return []string{s.Path}
+}
+
+func (s *DirSource) Name() string {
+	return "dir:" + s.Dir
+}
+
+func (s *DirSource) Priority() int {
+	return PriorityDir
+}
+
+func (s *DirSource) Profiles() (map[string]string, error) {
+	names, err := profilesDirList(s.Dir)
+	if err != nil || names == nil {
+		return nil, err
+	}
+
+	profiles := map[string]string{}
+	for _, name := range names {
+		for _, entry := range []string{name, name + SignatureSuffix} {
+			file := filepath.Join(s.Dir, name, ConfFile)
+			if IsSignature(entry) {
+				file += SignatureSuffix
+			}
+			data, err := ioutil.ReadFile(file)
+			if err != nil {
+				if os.IsNotExist(err) && IsSignature(entry) {
+					continue
+				}
+				return nil, fmt.Errorf("failed to read tuned profile file %q: %v", file, err)
+			}
+			profiles[entry] = string(data)
+		}
+	}
+	return profiles, nil
+}
+
+func (s *DirSource) Paths() []string {
+	return []string{s.Dir}
+}
+
+// NewStaticSource creates a StaticSource without profiles.
+func NewStaticSource(name string, priority int) *StaticSource {
+	return &StaticSource{name: name, priority: priority}
+}
+
+// Set replaces the profiles of s.
+func (s *StaticSource) Set(profiles map[string]string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.profiles = profiles
+}
+
+func (s *StaticSource) Name() string {
+	return s.name
+}
+
+func (s *StaticSource) Priority() int {
+	return s.priority
+}
+
+func (s *StaticSource) Profiles() (map[string]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.profiles, nil
+}
@@ -0,0 +1,357 @@
+package profile
+
+import (
+	"bufio"         // scanner
+	"crypto/sha256" // sha256.New()
+	"encoding/hex"  // hex.EncodeToString()
+	"fmt"           // Errorf()
+	"io/ioutil"     // ioutil.ReadFile()
+	"os"            // os.Stat()
+	"path/filepath" // filepath.Join()
+	"sort"          // sort.Strings()
+	"strings"       // strings.TrimSpace()
+	"time"          // time.Time
+)
+
+// Types
+// Store stores tuned profiles and the tuned configuration selecting them.
+type Store interface {
+	// WriteProfile writes the tuned.conf data of profile name.
+	WriteProfile(name, data string) error
+	// WriteProfiles writes the tuned.conf data of several profiles, name -> data,
+	// so that tuned never sees a partially written set.
+	WriteProfiles(profiles map[string]string) error
+	// ReadProfile returns the tuned.conf data of profile name; profiles written
+	// by WriteProfile take precedence over the profiles shipped with tuned.
+	ReadProfile(name string) (string, error)
+	// HasProfile returns true if profile name was written by WriteProfile.
+	HasProfile(name string) bool
+	// ListProfiles returns the names of the profiles written by WriteProfile
+	// and the names of the profiles shipped with tuned.
+	ListProfiles() (written []string, system []string, err error)
+	// WriteRecommend makes tuned recommend profile name.
+	WriteRecommend(name string) error
+	// ActiveProfile returns the profile tuned reports as active.
+	ActiveProfile() (string, error)
+	// ActiveProfileChanged returns when tuned last reported the active profile;
+	// the zero time if unknown.
+	ActiveProfileChanged() (time.Time, error)
+}
+
+// FileWriter creates the files of a FSStore.
+type FileWriter interface {
+	// Mkdir creates directory dir.
+	Mkdir(dir string) error
+	// WriteFile writes file path.
+	WriteFile(path string, data []byte) error
+	// Swap replaces target by staged; staged then holds the previous target.
+	Swap(staged, target string) error
+	// RemoveAll removes path and everything below it.
+	RemoveAll(path string) error
+}
+
+// FSStore stores the profiles in the tuned configuration directories.
+type FSStore struct {
+	// Writer creates the profile and recommend.d files
+	Writer FileWriter
+	// ProfilesDir is the directory to write profiles to.
+	ProfilesDir string
+	// SystemProfilesDir is the directory with the profiles shipped with tuned.
+	SystemProfilesDir string
+	// RecommendFile is the recommend.d file to write.
+	RecommendFile string
+	// ActiveProfileFile is the tuned active profile file.
+	ActiveProfileFile string
+	// ActiveProfileQuery, if set, returns the active profile instead of ActiveProfileFile.
+	ActiveProfileQuery func() (string, error)
+}
+
+// MemStore stores the profiles in memory.
+type MemStore struct {
+	// Profiles maps profile names to their tuned.conf data
+	Profiles map[string]string
+	// Recommend is the profile last passed to WriteRecommend()
+	Recommend string
+	// Active is the profile returned by ActiveProfile()
+	Active string
+	// ActiveChanged is the time returned by ActiveProfileChanged()
+	ActiveChanged time.Time
+}
+
+// Constants
+const (
+	// prefix of the staging directories in ProfilesDir, see WriteProfiles()
+	stagingPrefix = ".staging-"
+)
+
+// Functions
+// NameCheck returns an error if name cannot be a profile directory in
+// ProfilesDir, e.g. a name from a remote source escaping it.
+func NameCheck(name string) error {
+	if len(name) == 0 || name == "." || name == ".." || strings.Contains(name, "/") {
+		return fmt.Errorf("invalid tuned profile name %q", name)
+	}
+	return nil
+}
+
+func (s *FSStore) WriteProfile(name, data string) error {
+	if err := NameCheck(name); err != nil {
+		return err
+	}
+	profileDir := fmt.Sprintf("%s/%s", s.ProfilesDir, name)
+	profileFile := fmt.Sprintf("%s/%s", profileDir, ConfFile)
+
+	if err := s.Writer.Mkdir(profileDir); err != nil {
+		return fmt.Errorf("failed to create tuned profile directory %q: %v", profileDir, err)
+	}
+	if err := s.Writer.WriteFile(profileFile, []byte(data)); err != nil {
+		return fmt.Errorf("failed to write tuned profile file %q: %v", profileFile, err)
+	}
+	return nil
+}
+
+// WriteProfiles writes the changed profiles into a staging directory in
+// ProfilesDir first.  Once all of them are written and read back intact, each
+// staged profile directory is swapped with the one in use.  If a swap fails, the
+// profiles swapped already are switched back, so that the previous set stays in
+// place.
+func (s *FSStore) WriteProfiles(profiles map[string]string) error {
+	var names []string
+	// profiles not written before, which have no previous directory to switch back to
+	created := map[string]bool{}
+
+	for name, data := range profiles {
+		if err := NameCheck(name); err != nil {
+			return err
+		}
+		current, err := ioutil.ReadFile(filepath.Join(s.ProfilesDir, name, ConfFile))
+		if err == nil && string(current) == data {
+			continue
+		}
+		if _, err := os.Lstat(filepath.Join(s.ProfilesDir, name)); os.IsNotExist(err) {
+			created[name] = true
+		}
+		names = append(names, name)
+	}
+	if len(names) == 0 {
+		return nil
+	}
+	sort.Strings(names)
+
+	h := sha256.New()
+	for _, name := range names {
+		fmt.Fprintf(h, "%s\x00%s\x00", name, profiles[name])
+	}
+	staging := filepath.Join(s.ProfilesDir, stagingPrefix+hex.EncodeToString(h.Sum(nil))[:12])
+	if err := s.Writer.RemoveAll(staging); err != nil {
+		return fmt.Errorf("failed to remove staging directory %q: %v", staging, err)
+	}
+	defer s.Writer.RemoveAll(staging)
+	if err := s.Writer.Mkdir(staging); err != nil {
+		return fmt.Errorf("failed to create staging directory %q: %v", staging, err)
+	}
+
+	for _, name := range names {
+		profileDir := filepath.Join(staging, name)
+		profileFile := filepath.Join(profileDir, ConfFile)
+		if err := s.Writer.Mkdir(profileDir); err != nil {
+			return fmt.Errorf("failed to create tuned profile directory %q: %v", profileDir, err)
+		}
+		if err := s.Writer.WriteFile(profileFile, []byte(profiles[name])); err != nil {
+			return fmt.Errorf("failed to write tuned profile file %q: %v", profileFile, err)
+		}
+	}
+	for _, name := range names {
+		profileFile := filepath.Join(staging, name, ConfFile)
+		data, err := ioutil.ReadFile(profileFile)
+		if err != nil || string(data) != profiles[name] {
+			return fmt.Errorf("staged tuned profile file %q is incomplete: %v", profileFile, err)
+		}
+	}
+
+	for i, name := range names {
+		target := filepath.Join(s.ProfilesDir, name)
+		if err := s.Writer.Swap(filepath.Join(staging, name), target); err != nil {
+			err = fmt.Errorf("failed to switch over tuned profile directory %q: %v", target, err)
+			if rerr := s.switchBack(staging, names[:i], created); rerr != nil {
+				return fmt.Errorf("%v; %v", err, rerr)
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// switchBack undoes the swaps of profiles names by WriteProfiles(): the previous
+// profile directories held by staging are swapped back, created ones are removed.
+func (s *FSStore) switchBack(staging string, names []string, created map[string]bool) error {
+	var failed []string
+
+	for _, name := range names {
+		target := filepath.Join(s.ProfilesDir, name)
+		var err error
+		if created[name] {
+			err = s.Writer.RemoveAll(target)
+		} else {
+			err = s.Writer.Swap(filepath.Join(staging, name), target)
+		}
+		if err != nil {
+			failed = append(failed, fmt.Sprintf("%s: %v", target, err))
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("failed to switch back tuned profile directories: %s", strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+func (s *FSStore) ReadProfile(name string) (string, error) {
+	for _, dir := range []string{s.ProfilesDir, s.SystemProfilesDir} {
+		profileFile := filepath.Join(dir, name, ConfFile)
+		data, err := ioutil.ReadFile(profileFile)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return "", fmt.Errorf("failed to read tuned profile file %q: %v", profileFile, err)
+		}
+		return string(data), nil
+	}
+
+	return "", fmt.Errorf("tuned profile %q not found", name)
+}
+
+func (s *FSStore) HasProfile(name string) bool {
+	_, err := os.Stat(filepath.Join(s.ProfilesDir, name))
+	return err == nil
+}
+
+// profilesDirList returns the names of the profiles in directory dir.
+func profilesDirList(dir string) ([]string, error) {
+	var names []string
+
+	fis, err := ioutil.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to list tuned profiles in %q: %v", dir, err)
+	}
+	for _, fi := range fis {
+		if !fi.IsDir() {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(dir, fi.Name(), ConfFile)); err == nil {
+			names = append(names, fi.Name())
+		}
+	}
+	return names, nil
+}
+
+func (s *FSStore) ListProfiles() ([]string, []string, error) {
+	written, err := profilesDirList(s.ProfilesDir)
+	if err != nil {
+		return nil, nil, err
+	}
+	system, err := profilesDirList(s.SystemProfilesDir)
+	if err != nil {
+		return nil, nil, err
+	}
+	return written, system, nil
+}
+
+func (s *FSStore) WriteRecommend(name string) error {
+	recommendDir := filepath.Dir(s.RecommendFile)
+	if err := s.Writer.Mkdir(recommendDir); err != nil {
+		return fmt.Errorf("failed to create directory %q: %v", recommendDir, err)
+	}
+	if err := s.Writer.WriteFile(s.RecommendFile, []byte(fmt.Sprintf("[%s]\n%s=.*\n", name, s.RecommendFile))); err != nil {
+		return fmt.Errorf("failed to write file %q: %v", s.RecommendFile, err)
+	}
+	return nil
+}
+
+func (s *FSStore) ActiveProfile() (string, error) {
+	var responseString = ""
+
+	if s.ActiveProfileQuery != nil {
+		return s.ActiveProfileQuery()
+	}
+
+	f, err := os.Open(s.ActiveProfileFile)
+	if err != nil {
+		return "", fmt.Errorf("error opening tuned active profile file %s: %v", s.ActiveProfileFile, err)
+	}
+	defer f.Close()
+
+	var scanner = bufio.NewScanner(f)
+	for scanner.Scan() {
+		responseString = strings.TrimSpace(scanner.Text())
+	}
+
+	return responseString, nil
+}
+
+func (s *FSStore) ActiveProfileChanged() (time.Time, error) {
+	if s.ActiveProfileQuery != nil {
+		return time.Time{}, nil
+	}
+	fi, err := os.Stat(s.ActiveProfileFile)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("error checking tuned active profile file %s: %v", s.ActiveProfileFile, err)
+	}
+	return fi.ModTime(), nil
+}
+
+// NewMemStore creates an empty MemStore.
+func NewMemStore() *MemStore {
+	return &MemStore{Profiles: map[string]string{}}
+}
+
+func (s *MemStore) WriteProfile(name, data string) error {
+	s.Profiles[name] = data
+	return nil
+}
+
+func (s *MemStore) WriteProfiles(profiles map[string]string) error {
+	for name, data := range profiles {
+		s.Profiles[name] = data
+	}
+	return nil
+}
+
+func (s *MemStore) ReadProfile(name string) (string, error) {
+	data, ok := s.Profiles[name]
+	if !ok {
+		return "", fmt.Errorf("tuned profile %q not found", name)
+	}
+	return data, nil
+}
+
+func (s *MemStore) HasProfile(name string) bool {
+	_, ok := s.Profiles[name]
+	return ok
+}
+
+func (s *MemStore) ListProfiles() ([]string, []string, error) {
+	var written []string
+
+	for name := range s.Profiles {
+		written = append(written, name)
+	}
+	sort.Strings(written)
+	return written, nil, nil
+}
+
+func (s *MemStore) WriteRecommend(name string) error {
+	s.Recommend = name
+	return nil
+}
+
+func (s *MemStore) ActiveProfile() (string, error) {
+	return s.Active, nil
+}
+
+func (s *MemStore) ActiveProfileChanged() (time.Time, error) {
+	return s.ActiveChanged, nil
+}
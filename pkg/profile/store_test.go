@@ -0,0 +1,155 @@
+package profile
+
+import (
+	"fmt"           // Errorf()
+	"io/ioutil"     // ioutil.TempDir()
+	"os"            // os.RemoveAll()
+	"path/filepath" // filepath.Join()
+	"reflect"       // reflect.DeepEqual()
+	"testing"
+
+	"github.com/openshift/openshift-tuned/pkg/layout"
+)
+
+// Types
+// failingWriter is a FileWriter which fails to swap target failSwap.
+type failingWriter struct {
+	failSwap string
+}
+
+// Functions
+func (w *failingWriter) Mkdir(dir string) error {
+	return layout.Mkdir(dir)
+}
+
+func (w *failingWriter) WriteFile(path string, data []byte) error {
+	return layout.WriteFile(path, data)
+}
+
+func (w *failingWriter) Swap(staged, target string) error {
+	if filepath.Base(target) == w.failSwap {
+		return fmt.Errorf("injected failure")
+	}
+	return layout.Swap(staged, target)
+}
+
+func (w *failingWriter) RemoveAll(path string) error {
+	return os.RemoveAll(path)
+}
+
+func TestFSStoreWriteProfiles(t *testing.T) {
+	tests := []struct {
+		name     string
+		failSwap string
+		wantErr  bool
+		want     map[string]string
+	}{
+		{
+			name: "all swapped",
+			want: map[string]string{"a": "new a", "b": "new b", "c": "new c"},
+		},
+		{
+			name:     "first swap fails",
+			failSwap: "a",
+			wantErr:  true,
+			want:     map[string]string{"a": "old a", "b": "old b"},
+		},
+		{
+			name:     "last swap fails",
+			failSwap: "c",
+			wantErr:  true,
+			want:     map[string]string{"a": "old a", "b": "old b"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "store")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(dir)
+			w := &failingWriter{}
+			s := &FSStore{Writer: w, ProfilesDir: filepath.Join(dir, "tuned")}
+			if err := s.WriteProfiles(map[string]string{"a": "old a", "b": "old b"}); err != nil {
+				t.Fatalf("writing the previous profiles: %v", err)
+			}
+			w.failSwap = tt.failSwap
+
+			err = s.WriteProfiles(map[string]string{"a": "new a", "b": "new b", "c": "new c"})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("WriteProfiles() error = %v, want error %v", err, tt.wantErr)
+			}
+			for name, want := range tt.want {
+				data, err := ioutil.ReadFile(filepath.Join(s.ProfilesDir, name, ConfFile))
+				if err != nil {
+					t.Fatalf("reading profile %q: %v", name, err)
+				}
+				if string(data) != want {
+					t.Errorf("profile %q = %q, want %q", name, data, want)
+				}
+			}
+			if _, ok := tt.want["c"]; !ok && s.HasProfile("c") {
+				t.Errorf("profile %q created by a failed WriteProfiles() was not removed", "c")
+			}
+			written, _, err := s.ListProfiles()
+			if err != nil {
+				t.Fatalf("ListProfiles() error = %v", err)
+			}
+			if len(written) != len(tt.want) {
+				t.Errorf("ListProfiles() = %v, want %d profiles", written, len(tt.want))
+			}
+		})
+	}
+}
+
+func TestFSStoreWriteProfilesName(t *testing.T) {
+	dir, err := ioutil.TempDir("", "store")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	s := &FSStore{Writer: &failingWriter{}, ProfilesDir: filepath.Join(dir, "tuned")}
+
+	for _, name := range []string{"", ".", "..", "../escaped", "a/b"} {
+		if err := s.WriteProfiles(map[string]string{name: "[main]\n"}); err == nil {
+			t.Errorf("WriteProfiles() of profile %q succeeded", name)
+		}
+		if err := s.WriteProfile(name, "[main]\n"); err == nil {
+			t.Errorf("WriteProfile() of profile %q succeeded", name)
+		}
+	}
+	if _, err := os.Lstat(filepath.Join(dir, "escaped")); !os.IsNotExist(err) {
+		t.Errorf("profile written outside of the profiles directory")
+	}
+}
+
+func TestMemStore(t *testing.T) {
+	s := NewMemStore()
+	if err := s.WriteProfiles(map[string]string{"b": "[main]\n", "a": "[main]\n"}); err != nil {
+		t.Fatalf("WriteProfiles() error = %v", err)
+	}
+	if err := s.WriteProfile("a", "[main]\nsummary=a\n"); err != nil {
+		t.Fatalf("WriteProfile() error = %v", err)
+	}
+
+	written, _, err := s.ListProfiles()
+	if err != nil {
+		t.Fatalf("ListProfiles() error = %v", err)
+	}
+	if want := []string{"a", "b"}; !reflect.DeepEqual(written, want) {
+		t.Errorf("ListProfiles() = %v, want %v", written, want)
+	}
+	if data, err := s.ReadProfile("a"); err != nil || data != "[main]\nsummary=a\n" {
+		t.Errorf("ReadProfile() = %q, %v", data, err)
+	}
+	if _, err := s.ReadProfile("missing"); err == nil {
+		t.Errorf("ReadProfile() of a missing profile succeeded")
+	}
+	if s.HasProfile("missing") {
+		t.Errorf("HasProfile() of a missing profile = true")
+	}
+	if err := s.WriteRecommend("b"); err != nil || s.Recommend != "b" {
+		t.Errorf("WriteRecommend() recorded %q, %v", s.Recommend, err)
+	}
+}
@@ -0,0 +1,272 @@
+// Package recommend evaluates the tuned recommend.d rules the way tuned does,
+// explaining why a profile is recommended.
+package recommend
+
+import (
+	"crypto/sha256" // sha256.New()
+	"encoding/hex"  // hex.EncodeToString()
+	"fmt"           // Errorf()
+	"io"            // io.Writer
+	"io/ioutil"     // ioutil.ReadDir()
+	"os"            // os.IsNotExist()
+	"path/filepath" // filepath.Join()
+	"regexp"        // regexp.Compile()
+	"sort"          // sort.Strings()
+	"strings"       // strings.HasPrefix()
+
+	"github.com/openshift/openshift-tuned/pkg/profile"
+)
+
+// Types
+// Rule is a single section of a tuned recommend.d file.  The profile is
+// recommended if all of the conditions in Options match.
+type Rule struct {
+	File    string
+	Profile string
+	Options map[string]string
+}
+
+// ConditionResult is the result of a recommend rule condition evaluation.
+type ConditionResult struct {
+	Condition string `json:"condition"`
+	Value     string `json:"value"`
+	Match     bool   `json:"match"`
+	Reason    string `json:"reason"`
+}
+
+// RuleResult is the result of a recommend rule evaluation.
+type RuleResult struct {
+	File       string            `json:"file"`
+	Profile    string            `json:"profile"`
+	Match      bool              `json:"match"`
+	Conditions []ConditionResult `json:"conditions"`
+}
+
+// FileReader reads the files of recommend rule conditions.
+type FileReader func(path string) ([]byte, error)
+
+// Explanation lists the recommend rules evaluated and the selected profile.
+type Explanation struct {
+	Rules   []RuleResult `json:"rules"`
+	Profile string       `json:"profile,omitempty"`
+	Error   string       `json:"error,omitempty"`
+}
+
+// Functions
+// ExplainPrint prints the explanation of the recommendation in a human-readable form.
+func ExplainPrint(w io.Writer, re Explanation) {
+	for _, rr := range re.Rules {
+		result := "no match"
+		if rr.Match {
+			result = "match"
+		}
+		fmt.Fprintf(w, "[%s] (%s): %s\n", rr.Profile, rr.File, result)
+		for _, c := range rr.Conditions {
+			mark := "-"
+			if c.Match {
+				mark = "+"
+			}
+			fmt.Fprintf(w, "  %s %s=%s: %s\n", mark, c.Condition, c.Value, c.Reason)
+		}
+	}
+	if len(re.Error) > 0 {
+		fmt.Fprintf(w, "error: %s\n", re.Error)
+		return
+	}
+	fmt.Fprintf(w, "recommended profile: %s\n", re.Profile)
+}
+
+// RulesLoad loads the tuned recommend rules from the recommend.d directories dirs in
+// the order tuned evaluates them: files sorted by name, files in later directories
+// override the files of the same name in the earlier ones.
+func RulesLoad(dirs ...string) ([]Rule, error) {
+	var (
+		rules []Rule
+		names []string
+	)
+	files := map[string]string{}
+
+	for _, dir := range dirs {
+		fis, err := ioutil.ReadDir(dir)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, fmt.Errorf("failed to read recommend directory %q: %v", dir, err)
+		}
+		for _, fi := range fis {
+			if fi.IsDir() || !strings.HasSuffix(fi.Name(), ".conf") {
+				continue
+			}
+			if _, ok := files[fi.Name()]; !ok {
+				names = append(names, fi.Name())
+			}
+			files[fi.Name()] = filepath.Join(dir, fi.Name())
+		}
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		data, err := ioutil.ReadFile(files[name])
+		if err != nil {
+			return nil, fmt.Errorf("failed to read recommend file %q: %v", files[name], err)
+		}
+		for _, section := range profile.ParseSections(string(data)) {
+			rules = append(rules, Rule{File: files[name], Profile: section.Name, Options: section.Options})
+		}
+	}
+
+	return rules, nil
+}
+
+// ConditionMatch evaluates a single condition of a recommend rule and
+// returns the reason of the result.  Only file conditions ("/path/to/file=regex")
+// are supported, other conditions (virt, system, ...) never match.
+func ConditionMatch(option, value string) (bool, string, error) {
+	return conditionMatch(ioutil.ReadFile, option, value)
+}
+
+func conditionMatch(read FileReader, option, value string) (bool, string, error) {
+	if !strings.HasPrefix(option, "/") {
+		return false, "unsupported condition", nil
+	}
+	re, err := regexp.Compile("^(?:" + value + ")")
+	if err != nil {
+		return false, "", fmt.Errorf("invalid regular expression %q for %q: %v", value, option, err)
+	}
+	data, err := read(option)
+	if err != nil {
+		return false, err.Error(), nil
+	}
+	if !re.Match(data) {
+		return false, fmt.Sprintf("content of %s does not match %q", option, value), nil
+	}
+	return true, fmt.Sprintf("content of %s matches %q", option, value), nil
+}
+
+// RuleExplain evaluates all conditions of rule.
+func RuleExplain(rule Rule) (RuleResult, error) {
+	return ruleExplain(ioutil.ReadFile, rule)
+}
+
+func ruleExplain(read FileReader, rule Rule) (RuleResult, error) {
+	var options []string
+
+	rr := RuleResult{File: rule.File, Profile: rule.Profile, Match: true}
+	for option := range rule.Options {
+		options = append(options, option)
+	}
+	sort.Strings(options)
+
+	for _, option := range options {
+		match, reason, err := conditionMatch(read, option, rule.Options[option])
+		if err != nil {
+			return rr, err
+		}
+		rr.Conditions = append(rr.Conditions, ConditionResult{
+			Condition: option,
+			Value:     rule.Options[option],
+			Match:     match,
+			Reason:    reason,
+		})
+		rr.Match = rr.Match && match
+	}
+	return rr, nil
+}
+
+// Explain evaluates the recommend rules in dirs like tuned does, i.e. until the
+// first matching rule, and returns the results of all evaluated rules.
+func Explain(dirs ...string) Explanation {
+	return ExplainWith(ioutil.ReadFile, dirs...)
+}
+
+// ExplainWith is Explain() reading the files of the rule conditions by read,
+// e.g. to evaluate the rules for another node.
+func ExplainWith(read FileReader, dirs ...string) Explanation {
+	var re Explanation
+
+	rules, err := RulesLoad(dirs...)
+	if err != nil {
+		re.Error = err.Error()
+		return re
+	}
+	for _, rule := range rules {
+		rr, err := ruleExplain(read, rule)
+		if err != nil {
+			re.Error = err.Error()
+			return re
+		}
+		re.Rules = append(re.Rules, rr)
+		if rr.Match {
+			re.Profile = rule.Profile
+			return re
+		}
+	}
+	re.Error = "no recommend rule matched"
+	return re
+}
+
+// Match returns the first matching recommend rule in dirs.
+func Match(dirs ...string) (*Rule, error) {
+	rules, err := RulesLoad(dirs...)
+	if err != nil {
+		return nil, err
+	}
+	for _, rule := range rules {
+		rr, err := RuleExplain(rule)
+		if err != nil {
+			return nil, err
+		}
+		if rr.Match {
+			return &rule, nil
+		}
+	}
+	return nil, fmt.Errorf("no recommend rule matched")
+}
+
+// Evaluate returns the profile recommended by the first matching recommend rule in dirs.
+func Evaluate(dirs ...string) (string, error) {
+	rule, err := Match(dirs...)
+	if err != nil {
+		return "", err
+	}
+	return rule.Profile, nil
+}
+
+// InputsHash returns a hash of everything the recommendation of the rules in dirs
+// depends on: the rules and the content of the files their conditions read.  The
+// recommendation of tuned is the same as long as the hash does not change.
+func InputsHash(dirs ...string) (string, error) {
+	rules, err := RulesLoad(dirs...)
+	if err != nil {
+		return "", err
+	}
+
+	h := sha256.New()
+	read := map[string]bool{}
+	for _, rule := range rules {
+		var options []string
+
+		fmt.Fprintf(h, "[%s]\x00%s\x00", rule.Profile, rule.File)
+		for option := range rule.Options {
+			options = append(options, option)
+		}
+		sort.Strings(options)
+		for _, option := range options {
+			fmt.Fprintf(h, "%s=%s\x00", option, rule.Options[option])
+			if !strings.HasPrefix(option, "/") || read[option] {
+				continue
+			}
+			read[option] = true
+			data, err := ioutil.ReadFile(option)
+			if err != nil {
+				// An unreadable file does not match, but it may become readable
+				fmt.Fprintf(h, "%s\x00-\x00", option)
+				continue
+			}
+			fmt.Fprintf(h, "%s\x00%d\x00", option, len(data))
+			h.Write(data)
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
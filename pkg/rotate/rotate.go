@@ -0,0 +1,123 @@
+// Package rotate writes log files rotated by size.
+package rotate
+
+import (
+	"fmt"           // Errorf()
+	"os"            // os.OpenFile()
+	"path/filepath" // filepath.Dir()
+	"sync"          // sync.Mutex
+
+	"github.com/openshift/openshift-tuned/pkg/layout"
+)
+
+// Types
+// File is a file opened for appending and rotated by size.  Its methods may be
+// called concurrently; every Write is appended as a whole.
+type File struct {
+	mu      sync.Mutex
+	path    string
+	maxSize int64
+	backups int
+	f       *os.File
+	size    int64
+	closed  bool
+}
+
+// Constants
+const (
+	filePerm = 0600
+)
+
+// Functions
+// New returns path to be opened for appending on the first Write.  Once it grows
+// over maxSize bytes, it is rotated to path.1, keeping up to backups rotated
+// files; maxSize 0 disables the rotation.
+func New(path string, maxSize int64, backups int) *File {
+	return &File{path: path, maxSize: maxSize, backups: backups}
+}
+
+// Open is New, but opens path right away.
+func Open(path string, maxSize int64, backups int) (*File, error) {
+	f := New(path, maxSize, backups)
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (f *File) open() error {
+	if err := layout.Mkdir(filepath.Dir(f.path)); err != nil {
+		return fmt.Errorf("failed to create the directory of %q: %v", f.path, err)
+	}
+	file, err := os.OpenFile(f.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, filePerm)
+	if err != nil {
+		return fmt.Errorf("failed to open %q: %v", f.path, err)
+	}
+	fi, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("failed to stat %q: %v", f.path, err)
+	}
+	f.f, f.size = file, fi.Size()
+	return nil
+}
+
+// rotate renames the file to path.1, the previously rotated files to path.2 and
+// so on, and starts a new file.
+func (f *File) rotate() error {
+	f.f.Close()
+	for i := f.backups - 1; i > 0; i-- {
+		os.Rename(fmt.Sprintf("%s.%d", f.path, i), fmt.Sprintf("%s.%d", f.path, i+1))
+	}
+	if f.backups > 0 {
+		if err := os.Rename(f.path, f.path+".1"); err != nil {
+			return fmt.Errorf("failed to rotate %q: %v", f.path, err)
+		}
+	} else if err := os.Truncate(f.path, 0); err != nil {
+		return fmt.Errorf("failed to truncate %q: %v", f.path, err)
+	}
+	return f.open()
+}
+
+// Write appends data to the file, rotating it first if data would make it grow
+// over the maximum size.
+func (f *File) Write(data []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.closed {
+		return 0, fmt.Errorf("%q is closed", f.path)
+	}
+	if f.f == nil {
+		// Not opened yet or a previous rotation failed
+		if err := f.open(); err != nil {
+			return 0, err
+		}
+	}
+	if f.maxSize > 0 && f.size > 0 && f.size+int64(len(data)) > f.maxSize {
+		if err := f.rotate(); err != nil {
+			f.f = nil
+			return 0, err
+		}
+	}
+	n, err := f.f.Write(data)
+	f.size += int64(n)
+	if err != nil {
+		return n, fmt.Errorf("failed to write %q: %v", f.path, err)
+	}
+	return n, nil
+}
+
+// Close closes the file.
+func (f *File) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.closed = true
+	if f.f == nil {
+		return nil
+	}
+	err := f.f.Close()
+	f.f = nil
+	return err
+}
@@ -0,0 +1,158 @@
+// Package schedule implements maintenance windows: recurring periods of time
+// given by a cron-like start schedule and a duration.
+package schedule
+
+import (
+	"fmt"     // Errorf()
+	"strconv" // strconv.Atoi()
+	"strings" // strings.Fields()
+	"time"    // time.Duration
+)
+
+// Types
+// Window is a recurring maintenance window.
+type Window struct {
+	spec     string
+	minute   field
+	hour     field
+	dom      field
+	month    field
+	dow      field
+	duration time.Duration
+}
+
+// field is the set of values a cron field matches.
+type field struct {
+	values map[int]bool
+	// the field starts with "*", i.e. is unrestricted
+	any bool
+}
+
+// Constants
+const (
+	// MaxDuration is the longest window; a window repeats at least weekly.
+	MaxDuration = 7 * 24 * time.Hour
+)
+
+// Functions
+// Parse parses a window spec "<minute> <hour> <day of month> <month> <day of
+// week> <duration>", e.g. "0 2 * * 6 4h" for Saturdays 02:00-06:00.  The first
+// five fields follow crontab(5) without names: "*", numbers, ranges "a-b",
+// steps "*/n" and "a-b/n" and comma-separated lists thereof; day of week 0 and
+// 7 are Sunday.  The duration is in time.ParseDuration() format.
+func Parse(spec string) (*Window, error) {
+	var err error
+
+	fields := strings.Fields(spec)
+	if len(fields) != 6 {
+		return nil, fmt.Errorf("invalid maintenance window %q: expected 5 cron fields and a duration", spec)
+	}
+	w := &Window{spec: spec}
+	for i, f := range []struct {
+		field    *field
+		min, max int
+	}{
+		{&w.minute, 0, 59},
+		{&w.hour, 0, 23},
+		{&w.dom, 1, 31},
+		{&w.month, 1, 12},
+		{&w.dow, 0, 7},
+	} {
+		if *f.field, err = fieldParse(fields[i], f.min, f.max); err != nil {
+			return nil, fmt.Errorf("invalid maintenance window %q: %v", spec, err)
+		}
+	}
+	if w.dow.values[7] {
+		w.dow.values[0] = true
+	}
+	if w.duration, err = time.ParseDuration(fields[5]); err != nil {
+		return nil, fmt.Errorf("invalid maintenance window %q: %v", spec, err)
+	}
+	if w.duration < time.Minute || w.duration > MaxDuration {
+		return nil, fmt.Errorf("invalid maintenance window %q: duration must be between %v and %v", spec, time.Minute, MaxDuration)
+	}
+	return w, nil
+}
+
+// fieldParse parses cron field s with values from min to max.
+func fieldParse(s string, min int, max int) (field, error) {
+	// As cron, "*/n" counts as unrestricted for the day fields rule
+	f := field{values: map[int]bool{}, any: strings.HasPrefix(s, "*")}
+
+	for _, part := range strings.Split(s, ",") {
+		step := 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			n, err := strconv.Atoi(part[i+1:])
+			if err != nil || n <= 0 {
+				return f, fmt.Errorf("invalid step in %q", part)
+			}
+			step = n
+			part = part[:i]
+		}
+		first, last := min, max
+		if part != "*" {
+			bounds := strings.SplitN(part, "-", 2)
+			n, err := strconv.Atoi(bounds[0])
+			if err != nil {
+				return f, fmt.Errorf("invalid value %q", part)
+			}
+			first, last = n, n
+			if len(bounds) == 2 {
+				if last, err = strconv.Atoi(bounds[1]); err != nil {
+					return f, fmt.Errorf("invalid value %q", part)
+				}
+			} else if step > 1 {
+				// "a/n" is "a-max/n"
+				last = max
+			}
+		}
+		if first < min || last > max || first > last {
+			return f, fmt.Errorf("%q out of range %d-%d", part, min, max)
+		}
+		for v := first; v <= last; v += step {
+			f.values[v] = true
+		}
+	}
+	return f, nil
+}
+
+// starts returns true if a window starts at minute t.
+func (w *Window) starts(t time.Time) bool {
+	if !w.minute.values[t.Minute()] || !w.hour.values[t.Hour()] || !w.month.values[int(t.Month())] {
+		return false
+	}
+	dom := w.dom.values[t.Day()]
+	dow := w.dow.values[int(t.Weekday())]
+	if !w.dom.any && !w.dow.any {
+		// As cron: either day field matches if both are restricted
+		return dom || dow
+	}
+	return dom && dow
+}
+
+// Contains returns true if time t is within a window.
+func (w *Window) Contains(t time.Time) bool {
+	t = t.Truncate(time.Minute)
+	for start := t; t.Sub(start) < w.duration; start = start.Add(-time.Minute) {
+		if w.starts(start) {
+			return true
+		}
+	}
+	return false
+}
+
+// Next returns the start of the first window at or after time t, or the zero
+// time if there is none within a year.
+func (w *Window) Next(t time.Time) time.Time {
+	t = t.Truncate(time.Minute)
+	for end := t.AddDate(1, 0, 0); t.Before(end); t = t.Add(time.Minute) {
+		if w.starts(t) {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+func (w *Window) String() string {
+	return w.spec
+}
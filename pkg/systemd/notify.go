@@ -0,0 +1,75 @@
+// Package systemd implements the systemd service notification protocol, see sd_notify(3)
+// and sd_watchdog_enabled(3).
+package systemd
+
+import (
+	"fmt"     // Errorf()
+	"net"     // net.DialUnix()
+	"os"      // os.Getenv()
+	"strconv" // strconv.Atoi()
+	"time"    // time.Duration
+)
+
+// Constants
+const (
+	// Ready tells the service manager that service startup is finished
+	Ready = "READY=1"
+	// Stopping tells the service manager that the service is beginning its shutdown
+	Stopping = "STOPPING=1"
+	// Watchdog updates the watchdog timestamp
+	Watchdog = "WATCHDOG=1"
+)
+
+// Functions
+// Notify sends state to the service manager.  Returns false if the service manager
+// does not expect notifications, i.e. the process does not run as a systemd unit
+// with NotifyAccess set.
+func Notify(state string) (bool, error) {
+	socket := os.Getenv("NOTIFY_SOCKET")
+	if len(socket) == 0 {
+		return false, nil
+	}
+	addr := &net.UnixAddr{Name: socket, Net: "unixgram"}
+	if socket[0] == '@' {
+		// Abstract namespace socket
+		addr.Name = "\x00" + socket[1:]
+	}
+
+	conn, err := net.DialUnix("unixgram", nil, addr)
+	if err != nil {
+		return false, fmt.Errorf("failed to connect to the systemd notification socket %q: %v", socket, err)
+	}
+	defer conn.Close()
+
+	if _, err = conn.Write([]byte(state)); err != nil {
+		return false, fmt.Errorf("failed to notify systemd of %q: %v", state, err)
+	}
+	return true, nil
+}
+
+// WatchdogEnabled returns the watchdog timeout the service manager expects
+// Watchdog notifications within, or 0 if the watchdog is not enabled for this process.
+func WatchdogEnabled() (time.Duration, error) {
+	usecStr := os.Getenv("WATCHDOG_USEC")
+	if len(usecStr) == 0 {
+		return 0, nil
+	}
+	usec, err := strconv.Atoi(usecStr)
+	if err != nil || usec <= 0 {
+		return 0, fmt.Errorf("invalid WATCHDOG_USEC %q", usecStr)
+	}
+
+	pidStr := os.Getenv("WATCHDOG_PID")
+	if len(pidStr) > 0 {
+		pid, err := strconv.Atoi(pidStr)
+		if err != nil {
+			return 0, fmt.Errorf("invalid WATCHDOG_PID %q", pidStr)
+		}
+		if pid != os.Getpid() {
+			// The watchdog is meant for another process
+			return 0, nil
+		}
+	}
+
+	return time.Duration(usec) * time.Microsecond, nil
+}
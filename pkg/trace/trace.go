@@ -0,0 +1,284 @@
+// Package trace records spans of the openshift-tuned reconcile pipeline and exports
+// them to an OpenTelemetry collector using the OTLP/HTTP JSON encoding.
+package trace
+
+import (
+	"bytes"         // bytes.Buffer
+	"crypto/rand"   // rand.Read()
+	"encoding/hex"  // hex.EncodeToString()
+	"encoding/json" // json.Marshal()
+	"fmt"           // Errorf()
+	"net/http"      // http.Client
+	"strconv"       // strconv.FormatInt()
+	"sync"          // sync.Mutex
+	"time"          // time.Time
+
+	"k8s.io/klog"
+)
+
+// Types
+// Tracer records spans and exports them in batches.  A nil *Tracer records nothing.
+type Tracer struct {
+	endpoint string
+	service  string
+	client   *http.Client
+	queue    chan *Span
+	// called with every ended span, see OnEnd()
+	onEnd func(name string, d time.Duration)
+}
+
+// Span is a timed operation of a trace.  All methods of a nil *Span are no-ops,
+// so that instrumented code does not need to check whether tracing is enabled.
+type Span struct {
+	tracer   *Tracer
+	traceID  string
+	spanID   string
+	parentID string
+	name     string
+	start    time.Time
+	end      time.Time
+
+	mu    sync.Mutex
+	attrs map[string]string
+	err   string
+}
+
+// OTLP/HTTP JSON encoding, see opentelemetry-proto
+type otlpAnyValue struct {
+	StringValue string `json:"stringValue"`
+}
+
+type otlpKeyValue struct {
+	Key   string       `json:"key"`
+	Value otlpAnyValue `json:"value"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+type otlpSpan struct {
+	TraceID           string         `json:"traceId"`
+	SpanID            string         `json:"spanId"`
+	ParentSpanID      string         `json:"parentSpanId,omitempty"`
+	Name              string         `json:"name"`
+	Kind              int            `json:"kind"`
+	StartTimeUnixNano string         `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string         `json:"endTimeUnixNano"`
+	Attributes        []otlpKeyValue `json:"attributes,omitempty"`
+	Status            otlpStatus     `json:"status"`
+}
+
+type otlpScopeSpans struct {
+	Scope struct {
+		Name string `json:"name"`
+	} `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpResourceSpans struct {
+	Resource struct {
+		Attributes []otlpKeyValue `json:"attributes"`
+	} `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpTraces struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+// Constants
+const (
+	queueSize     = 256
+	batchSize     = 64
+	flushInterval = 5 * time.Second
+	exportTimeout = 10 * time.Second
+
+	otlpSpanKindInternal = 1
+	otlpStatusCodeError  = 2
+)
+
+// Functions
+// NewTracer creates a Tracer exporting spans of service to the OTLP/HTTP endpoint,
+// e.g. http://otel-collector:4318/v1/traces.  With an empty endpoint, spans are
+// only passed to the OnEnd() function.
+func NewTracer(endpoint string, service string) *Tracer {
+	t := &Tracer{
+		endpoint: endpoint,
+		service:  service,
+		client:   &http.Client{Timeout: exportTimeout},
+	}
+	if len(endpoint) > 0 {
+		t.queue = make(chan *Span, queueSize)
+		go t.exportLoop()
+	}
+	return t
+}
+
+// OnEnd sets f to be called with the name and duration of every span ended,
+// e.g. to record timing metrics.  Must be called before any span is started.
+func (t *Tracer) OnEnd(f func(name string, d time.Duration)) {
+	t.onEnd = f
+}
+
+func randomID(n int) string {
+	id := make([]byte, n)
+	if _, err := rand.Read(id); err != nil {
+		klog.Errorf("failed to generate a trace ID: %v", err)
+	}
+	return hex.EncodeToString(id)
+}
+
+// Start starts the root span of a new trace.
+func (t *Tracer) Start(name string) *Span {
+	if t == nil {
+		return nil
+	}
+	return &Span{
+		tracer:  t,
+		traceID: randomID(16),
+		spanID:  randomID(8),
+		name:    name,
+		start:   time.Now(),
+	}
+}
+
+// Child starts a span of the trace of s with parent s.
+func (s *Span) Child(name string) *Span {
+	if s == nil {
+		return nil
+	}
+	return &Span{
+		tracer:   s.tracer,
+		traceID:  s.traceID,
+		spanID:   randomID(8),
+		parentID: s.spanID,
+		name:     name,
+		start:    time.Now(),
+	}
+}
+
+// SetAttribute sets attribute key of s to value.
+func (s *Span) SetAttribute(key string, value string) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.attrs == nil {
+		s.attrs = map[string]string{}
+	}
+	s.attrs[key] = value
+}
+
+// SetError marks s as failed by err; a nil err is ignored.
+func (s *Span) SetError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.err = err.Error()
+}
+
+// End ends s and queues it for export.  Spans are dropped if the exporter cannot
+// keep up.
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+	s.end = time.Now()
+	klog.V(2).Infof("trace %s: %s took %v", s.traceID, s.name, s.end.Sub(s.start))
+	if s.tracer.onEnd != nil {
+		s.tracer.onEnd(s.name, s.end.Sub(s.start))
+	}
+	if s.tracer.queue == nil {
+		return
+	}
+
+	select {
+	case s.tracer.queue <- s:
+	default:
+		klog.V(1).Infof("trace queue full, dropping span %q", s.name)
+	}
+}
+
+func (s *Span) otlp() otlpSpan {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	o := otlpSpan{
+		TraceID:           s.traceID,
+		SpanID:            s.spanID,
+		ParentSpanID:      s.parentID,
+		Name:              s.name,
+		Kind:              otlpSpanKindInternal,
+		StartTimeUnixNano: strconv.FormatInt(s.start.UnixNano(), 10),
+		EndTimeUnixNano:   strconv.FormatInt(s.end.UnixNano(), 10),
+	}
+	for k, v := range s.attrs {
+		o.Attributes = append(o.Attributes, otlpKeyValue{Key: k, Value: otlpAnyValue{StringValue: v}})
+	}
+	if len(s.err) > 0 {
+		o.Status = otlpStatus{Code: otlpStatusCodeError, Message: s.err}
+	}
+	return o
+}
+
+// exportLoop exports the queued spans in batches.
+func (t *Tracer) exportLoop() {
+	var batch []*Span
+
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case s := <-t.queue:
+			batch = append(batch, s)
+			if len(batch) < batchSize {
+				continue
+			}
+		case <-ticker.C:
+			if len(batch) == 0 {
+				continue
+			}
+		}
+		if err := t.export(batch); err != nil {
+			klog.Errorf("failed to export %d spans: %v", len(batch), err)
+		}
+		batch = nil
+	}
+}
+
+// export sends spans to the OTLP/HTTP endpoint.
+func (t *Tracer) export(spans []*Span) error {
+	var (
+		rs otlpResourceSpans
+		ss otlpScopeSpans
+	)
+
+	rs.Resource.Attributes = []otlpKeyValue{{Key: "service.name", Value: otlpAnyValue{StringValue: t.service}}}
+	ss.Scope.Name = t.service
+	for _, s := range spans {
+		ss.Spans = append(ss.Spans, s.otlp())
+	}
+	rs.ScopeSpans = []otlpScopeSpans{ss}
+
+	data, err := json.Marshal(otlpTraces{ResourceSpans: []otlpResourceSpans{rs}})
+	if err != nil {
+		return err
+	}
+	resp, err := t.client.Post(t.endpoint, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("%s: %s", t.endpoint, resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,331 @@
+package tuned
+
+import (
+	"net/http" // http.Request
+	"reflect"  // DeepEqual()
+	"sync"     // sync.RWMutex
+	"time"     // time.Time
+
+	"github.com/openshift/openshift-tuned/pkg/api"
+	"github.com/openshift/openshift-tuned/pkg/hooks"
+	"github.com/openshift/openshift-tuned/pkg/metrics"
+	"github.com/openshift/openshift-tuned/pkg/schedule"
+)
+
+// Types
+type rebootRequiredStatus struct {
+	Profile        string   `json:"profile"`
+	RebootRequired bool     `json:"rebootRequired"`
+	Missing        []string `json:"missingKernelParameters,omitempty"`
+}
+
+// daemonStatus is the state of openshift-tuned exposed via the API; it is shared
+// between the event loop and the API handlers.
+type daemonStatus struct {
+	sync.RWMutex
+	rebootRequired rebootRequiredStatus
+	// the kernel command line and huge pages after the last apply
+	boot *bootState
+	// the node's Profile object and the tuned profile it requests
+	profileObject    string
+	requestedProfile string
+	// a delayed switch back to the previously requested profile
+	revertPending *revertPending
+	// running without the Kubernetes API, see Options.Standalone
+	standalone bool
+	// is the node cordoned, see Options.DrainAction
+	draining bool
+	// the effective maintenance window and whether a reload waits for it
+	maintenanceWindow string
+	reloadQueuedFlag  bool
+	// extracted tuned profile name -> where it was extracted from
+	profileSources map[string]string
+	// errors tuned logged while applying the current profile
+	pluginErrors []pluginError
+	// sysctls of the recommended profile managed by other agents too
+	sysctlConflicts []sysctlConflict
+	// results of the startup checks
+	preflight []preflightResult
+	// results of the hooks run after the current profile was applied
+	hookResults []hooks.Result
+	// results of the canary probes of the current profile
+	probeResults []probeResult
+	// the last change of the extracted tuned profiles
+	profileDiff profileDiffResponse
+	// the daemon state, see state.go
+	state            daemonState
+	stateReason      string
+	stateSince       time.Time
+	stateTransitions map[daemonState]int
+	// errors which restarted the event loop per category, see errorCategory()
+	errorCounts map[string]int
+}
+
+// statusResponse is the response of the /status API.
+type statusResponse struct {
+	State            string    `json:"state"`
+	StateReason      string    `json:"stateReason,omitempty"`
+	StateSince       time.Time `json:"stateSince"`
+	RealtimeKernel   bool      `json:"realtimeKernel"`
+	Degraded         bool      `json:"degraded"`
+	ProfileObject    string    `json:"profileObject,omitempty"`
+	RequestedProfile string    `json:"requestedProfile,omitempty"`
+	// a delayed switch back to the previously requested profile
+	RevertPending *revertPending `json:"revertPending,omitempty"`
+	// not attached to the Kubernetes API, the tuned profile is selected from local files
+	Standalone bool `json:"standalone,omitempty"`
+	// the node is cordoned and the tuning held back, see Options.DrainAction
+	Draining bool `json:"draining,omitempty"`
+	// disruptive reloads are executed within this window only
+	MaintenanceWindow string `json:"maintenanceWindow,omitempty"`
+	// a reload waits for the maintenance window
+	ReloadQueued bool `json:"reloadQueued,omitempty"`
+	// errors and warnings tuned logged while applying the current profile
+	PluginErrors []pluginError `json:"pluginErrors,omitempty"`
+	// sysctls of the recommended profile managed by other agents too
+	SysctlConflicts []sysctlConflict `json:"sysctlConflicts,omitempty"`
+	// failed startup checks
+	Preflight []preflightResult `json:"preflight,omitempty"`
+	// results of the hooks run after the current profile was applied
+	Hooks []hooks.Result `json:"hooks,omitempty"`
+	// results of the canary probes of the current profile
+	Probes []probeResult `json:"probes,omitempty"`
+	// the kernel command line and huge pages after the last apply
+	Boot *bootState `json:"boot,omitempty"`
+}
+
+// profileDiffResponse is the response of the /debug/profile_diff API.
+type profileDiffResponse struct {
+	Time time.Time `json:"time,omitempty"`
+	// changed profiles and their sources
+	Changed string `json:"changed,omitempty"`
+	// unified diff of the changed tuned.conf files
+	Diff string `json:"diff"`
+}
+
+// Functions
+// setRebootRequired records the reboot-required state of profile profileName
+// and returns true if the state changed.
+func (s *daemonStatus) setRebootRequired(profileName string, missing []string) bool {
+	s.Lock()
+	defer s.Unlock()
+
+	rr := rebootRequiredStatus{
+		Profile:        profileName,
+		RebootRequired: len(missing) > 0,
+		Missing:        missing,
+	}
+	if reflect.DeepEqual(s.rebootRequired, rr) {
+		return false
+	}
+	s.rebootRequired = rr
+
+	return true
+}
+
+// setBootState records the kernel state resulting from the boot-time tuning.
+func (s *daemonStatus) setBootState(bs *bootState) {
+	s.Lock()
+	defer s.Unlock()
+
+	s.boot = bs
+}
+
+// setRequestedProfile records the tuned profile requested by Profile object.
+func (s *daemonStatus) setRequestedProfile(object string, profileName string) {
+	s.Lock()
+	defer s.Unlock()
+
+	s.profileObject = object
+	s.requestedProfile = profileName
+}
+
+// countError counts an error of category which restarted the event loop.
+func (s *daemonStatus) countError(category string) {
+	s.Lock()
+	defer s.Unlock()
+
+	if s.errorCounts == nil {
+		s.errorCounts = map[string]int{}
+	}
+	s.errorCounts[category]++
+}
+
+// setRevertPending records a delayed switch back to the previously requested profile.
+func (s *daemonStatus) setRevertPending(r *revertPending) {
+	s.Lock()
+	defer s.Unlock()
+
+	s.revertPending = r
+}
+
+func (s *daemonStatus) setStandalone(standalone bool) {
+	s.Lock()
+	defer s.Unlock()
+
+	s.standalone = standalone
+}
+
+func (s *daemonStatus) setDraining(draining bool) {
+	s.Lock()
+	defer s.Unlock()
+
+	s.draining = draining
+}
+
+func (s *daemonStatus) setMaintenanceWindow(w *schedule.Window) {
+	s.Lock()
+	defer s.Unlock()
+
+	s.maintenanceWindow = ""
+	if w != nil {
+		s.maintenanceWindow = w.String()
+	}
+}
+
+func (s *daemonStatus) setReloadQueued(queued bool) {
+	s.Lock()
+	defer s.Unlock()
+
+	s.reloadQueuedFlag = queued
+}
+
+func (s *daemonStatus) reloadQueued() bool {
+	s.RLock()
+	defer s.RUnlock()
+
+	return s.reloadQueuedFlag
+}
+
+// setProfileSource records that tuned profile profileName was extracted from source.
+func (s *daemonStatus) setProfileSource(profileName string, source string) {
+	s.Lock()
+	defer s.Unlock()
+
+	if s.profileSources == nil {
+		s.profileSources = map[string]string{}
+	}
+	s.profileSources[profileName] = source
+}
+
+// setPreflight records the results of the startup checks.
+func (s *daemonStatus) setPreflight(results []preflightResult) {
+	s.Lock()
+	defer s.Unlock()
+
+	s.preflight = results
+}
+
+// setSysctlConflicts records the sysctl conflicts of the recommended profile.
+func (s *daemonStatus) setSysctlConflicts(conflicts []sysctlConflict) {
+	s.Lock()
+	defer s.Unlock()
+
+	s.sysctlConflicts = conflicts
+}
+
+// setHookResults records the results of the hooks run after applying a profile.
+func (s *daemonStatus) setHookResults(results []hooks.Result) {
+	s.Lock()
+	defer s.Unlock()
+
+	s.hookResults = results
+}
+
+// setProbeResults records the results of the canary probes of the applied profile.
+func (s *daemonStatus) setProbeResults(results []probeResult) {
+	s.Lock()
+	defer s.Unlock()
+
+	s.probeResults = results
+}
+
+// setProfileDiff records the unified diff of the last change of the tuned
+// profiles; changed lists the changed profiles and their sources.
+func (s *daemonStatus) setProfileDiff(changed string, diff string) {
+	s.Lock()
+	defer s.Unlock()
+
+	s.profileDiff = profileDiffResponse{Time: time.Now(), Changed: changed, Diff: diff}
+}
+
+// profileSource returns where the extracted tuned profile profileName came from.
+func (s *daemonStatus) profileSource(profileName string) string {
+	s.RLock()
+	defer s.RUnlock()
+
+	if source, ok := s.profileSources[profileName]; ok {
+		return source
+	}
+	// Extracted by a previous openshift-tuned run
+	return profileSourceExtracted
+}
+
+// get returns the status as served by the /status API.
+func (s *daemonStatus) get() statusResponse {
+	s.RLock()
+	defer s.RUnlock()
+
+	return statusResponse{
+		State:             s.state.String(),
+		StateReason:       s.stateReason,
+		StateSince:        s.stateSince,
+		RealtimeKernel:    kernelRealtime(),
+		Degraded:          s.state == stateDegraded,
+		ProfileObject:     s.profileObject,
+		RequestedProfile:  s.requestedProfile,
+		RevertPending:     s.revertPending,
+		Standalone:        s.standalone,
+		Draining:          s.draining,
+		MaintenanceWindow: s.maintenanceWindow,
+		ReloadQueued:      s.reloadQueuedFlag,
+		PluginErrors:      append([]pluginError(nil), s.pluginErrors...),
+		SysctlConflicts:   s.sysctlConflicts,
+		Preflight:         preflightFailed(s.preflight),
+		Hooks:             s.hookResults,
+		Probes:            s.probeResults,
+		Boot:              s.boot,
+	}
+}
+
+// apiServe starts serving the openshift-tuned HTTP API on port in the background.
+func (c *Controller) apiServe(port int) {
+	s := api.NewServer()
+	s.HandleJSON("/status", func(r *http.Request) interface{} {
+		return c.status.get()
+	})
+	s.HandleJSON("/reboot_required", func(r *http.Request) interface{} {
+		c.status.RLock()
+		defer c.status.RUnlock()
+		return c.status.rebootRequired
+	})
+	s.HandleJSON("/recommended_profile", func(r *http.Request) interface{} {
+		return c.recommendedProfileGet(len(r.URL.Query().Get("explain")) > 0)
+	})
+	s.HandleJSON("/profiles", func(r *http.Request) interface{} {
+		return c.profilesGet()
+	})
+	s.HandleJSON("/effective_profile", func(r *http.Request) interface{} {
+		return c.EffectiveProfile(r.URL.Query().Get("profile"))
+	})
+	s.HandleJSON("/debug/profile_diff", func(r *http.Request) interface{} {
+		c.status.RLock()
+		defer c.status.RUnlock()
+		return c.status.profileDiff
+	})
+	s.HandleJSON("/history", func(r *http.Request) interface{} {
+		return c.historyGet()
+	})
+	s.HandleJSON("/pristine", func(r *http.Request) interface{} {
+		return c.pristineGet()
+	})
+	s.HandleJSON("/timings", func(r *http.Request) interface{} {
+		return c.timingsGet()
+	})
+	s.HandleJSON("/version", func(r *http.Request) interface{} {
+		return c.versionGet()
+	})
+	s.Handle("/metrics", metrics.Handler(c.metricsCollectors()...))
+	s.Handle("/healthz", c.apiHealthzHandler)
+	s.Serve(port)
+}
@@ -0,0 +1,76 @@
+package tuned
+
+import (
+	"encoding/json" // json.Marshal()
+	"fmt"           // Errorf()
+	"io/ioutil"     // ioutil.ReadFile()
+	"os"            // os.IsNotExist()
+	"path/filepath" // filepath.Join()
+
+	"k8s.io/klog"
+
+	"github.com/openshift/openshift-tuned/pkg/layout"
+)
+
+// Types
+// appliedState is the profile tuned applied last, persisted across restarts of
+// openshift-tuned and its event loop.
+type appliedState struct {
+	Profile     string `json:"profile"`
+	ContentHash string `json:"contentHash"`
+}
+
+// Constants
+const (
+	appliedFile = "applied.json" // in RunDir
+)
+
+// Functions
+func (c *Controller) appliedFile() string {
+	return filepath.Join(c.opts.RunDir, appliedFile)
+}
+
+// appliedSave persists the profile tuned applied.
+func (c *Controller) appliedSave(profileName string, contentHash string) {
+	data, err := json.Marshal(appliedState{Profile: profileName, ContentHash: contentHash})
+	if err == nil {
+		err = layout.WriteFile(c.appliedFile(), data)
+	}
+	if err != nil {
+		klog.Errorf("failed to save the applied profile state: %v", err)
+	}
+}
+
+// appliedRead returns the persisted profile tuned applied; a zero appliedState
+// if none was persisted.
+func (c *Controller) appliedRead() (appliedState, error) {
+	var st appliedState
+
+	data, err := ioutil.ReadFile(c.appliedFile())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return st, nil
+		}
+		return st, fmt.Errorf("failed to read the applied profile state: %v", err)
+	}
+	if err = json.Unmarshal(data, &st); err != nil {
+		return st, fmt.Errorf("failed to parse the applied profile state %q: %v", c.appliedFile(), err)
+	}
+	return st, nil
+}
+
+// appliedLoad seeds the reload decision with the profile tuned applied before
+// the restart, so that a tuned still running the same profile content is not
+// reloaded needlessly.
+func (c *Controller) appliedLoad(tuned *tunedState) {
+	st, err := c.appliedRead()
+	if err != nil {
+		klog.Errorf("%s", err.Error())
+		return
+	}
+	if len(st.ContentHash) == 0 {
+		return
+	}
+	klog.V(1).Infof("tuned applied profile %q (content %.12s) before the restart", st.Profile, st.ContentHash)
+	tuned.decider.Reloaded(st.ContentHash, nil)
+}
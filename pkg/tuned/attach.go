@@ -0,0 +1,21 @@
+package tuned
+
+import (
+	"k8s.io/klog"
+)
+
+// Functions
+// apiAttach tries to attach a Standalone openshift-tuned to the apiserver, e.g.
+// once the apiserver of a bootstrapping cluster is up.  On success, the Profile of
+// the node takes over from the local recommend.d rules.  Returns true if attached.
+func (c *Controller) apiAttach(tuned *tunedState, w *workers) bool {
+	if err := c.apiWatch(tuned, w); err != nil {
+		klog.V(1).Infof("apiserver not reachable yet, staying standalone: %v", err)
+		return false
+	}
+	klog.Infof("attached to the Kubernetes API, following the Profile of node %q", tuned.nodeName)
+	c.attached = true
+	c.status.setStandalone(false)
+
+	return true
+}
@@ -0,0 +1,44 @@
+package tuned
+
+import (
+	"k8s.io/klog"
+
+	"github.com/openshift/openshift-tuned/pkg/audit"
+)
+
+// Constants
+const (
+	// the actor of the actions openshift-tuned takes on its own
+	auditActorSelf = programName
+	// a signal received by openshift-tuned and forwarded to tuned
+	auditActorSignalForward = "signal-forward"
+
+	auditActionExtract       = "extract"
+	auditActionStart         = "start-tuned"
+	auditActionSignal        = "signal"
+	auditActionSocketCommand = "socket-command"
+	auditActionTaint         = "taint"
+	auditActionUntaint       = "untaint"
+)
+
+// Functions
+// auditInit opens the audit log opts.AuditLog, if set.
+func (c *Controller) auditInit() (err error) {
+	if len(c.opts.AuditLog) == 0 {
+		return nil
+	}
+	c.auditLog, err = audit.Open(c.opts.AuditLog, int64(c.opts.AuditLogMaxSize)<<20, c.opts.AuditLogBackups)
+	return err
+}
+
+// audit records action of actor on object in the audit log; err is the outcome
+// of the action.
+func (c *Controller) audit(action string, actor string, object string, err error) {
+	e := audit.Entry{Action: action, Actor: actor, Object: object}
+	if err != nil {
+		e.Error = err.Error()
+	}
+	if err := c.auditLog.Record(e); err != nil {
+		klog.Errorf("%s", err.Error())
+	}
+}
@@ -0,0 +1,288 @@
+package tuned
+
+import (
+	"bufio"         // bufio.NewScanner()
+	"bytes"         // bytes.Buffer
+	"fmt"           // Errorf()
+	"io/ioutil"     // ioutil.ReadFile()
+	"os"            // os.IsNotExist()
+	"path/filepath" // filepath.Glob()
+	"sort"          // sort.Slice()
+	"strconv"       // strconv.ParseInt()
+	"strings"       // strings.Fields()
+	"time"          // time.Now()
+
+	"k8s.io/klog"
+
+	"github.com/openshift/openshift-tuned/pkg/metrics"
+)
+
+// Types
+// bootState is the kernel state resulting from the boot-time tuning: the kernel
+// command line the node booted with and the huge pages the kernel allocated.  It
+// lets the operator verify that the [bootloader] tuning of a profile took effect
+// after the reboot.
+type bootState struct {
+	Time      time.Time        `json:"time"`
+	Cmdline   string           `json:"cmdline"`
+	Hugepages []hugepagesState `json:"hugepages,omitempty"`
+	// page size in kB -> pages requested on the kernel command line
+	HugepagesRequested map[int64]int64 `json:"hugepagesRequested,omitempty"`
+	// the page sizes the kernel allocated fewer pages of than requested
+	HugepagesShortfall []string `json:"hugepagesShortfall,omitempty"`
+}
+
+// hugepagesState are the counters of a huge page pool.
+type hugepagesState struct {
+	// page size in kB
+	SizeKB int64 `json:"sizeKB"`
+	// NUMA node of the pool, nil for the system-wide pool
+	NUMANode *int  `json:"numaNode,omitempty"`
+	Total    int64 `json:"total"`
+	Free     int64 `json:"free"`
+	Reserved int64 `json:"reserved,omitempty"`
+	Surplus  int64 `json:"surplus"`
+}
+
+// Constants
+const (
+	procMeminfo        = "/proc/meminfo"
+	sysHugepagesDir    = "/sys/kernel/mm/hugepages"
+	sysNUMANodesGlob   = "/sys/devices/system/node/node[0-9]*"
+	hugepagesDirGlob   = "hugepages-*kB"
+	hugepagesDirPrefix = "hugepages-"
+)
+
+// Functions
+// memSizeKB parses a kernel memory size, e.g. "2M" or "1G", into kB.
+func memSizeKB(s string) (int64, error) {
+	mult := int64(1)
+	if len(s) > 0 {
+		switch s[len(s)-1] {
+		case 'k', 'K':
+			mult = 1 << 10
+		case 'm', 'M':
+			mult = 1 << 20
+		case 'g', 'G':
+			mult = 1 << 30
+		}
+		if mult > 1 {
+			s = s[:len(s)-1]
+		}
+	}
+	n, err := strconv.ParseInt(s, 0, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid memory size %q", s)
+	}
+	return n * mult / 1024, nil
+}
+
+// hugepagesDefaultSizeKB returns the default huge page size from /proc/meminfo.
+func hugepagesDefaultSizeKB() (int64, error) {
+	data, err := ioutil.ReadFile(procMeminfo)
+	if err != nil {
+		return 0, err
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) >= 2 && fields[0] == "Hugepagesize:" {
+			return strconv.ParseInt(fields[1], 10, 64)
+		}
+	}
+	return 0, fmt.Errorf("no Hugepagesize in %s", procMeminfo)
+}
+
+// hugepagesRequested returns the huge pages requested by the kernel command-line
+// parameters params: page size in kB -> number of pages.  hugepages= applies to
+// the page size of the preceding hugepagesz=, or to the default size, see
+// hugepagesDefaultSizeKB().
+func hugepagesRequested(params []string, defaultSizeKB int64) map[int64]int64 {
+	requested := map[int64]int64{}
+	size := int64(0) // the default size, which default_hugepagesz= may set later
+
+	for _, param := range params {
+		parts := strings.SplitN(param, "=", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		switch parts[0] {
+		case "default_hugepagesz":
+			if kb, err := memSizeKB(parts[1]); err == nil {
+				defaultSizeKB = kb
+			}
+		case "hugepagesz":
+			if kb, err := memSizeKB(parts[1]); err == nil {
+				size = kb
+			}
+		case "hugepages":
+			// Either a count or per NUMA node counts, "<node>:<count>,..."
+			n := int64(0)
+			for _, c := range strings.Split(parts[1], ",") {
+				if i := strings.Index(c, ":"); i >= 0 {
+					c = c[i+1:]
+				}
+				count, err := strconv.ParseInt(c, 10, 64)
+				if err != nil {
+					n = -1
+					break
+				}
+				n += count
+			}
+			if n >= 0 {
+				requested[size] = n
+			}
+		}
+	}
+	if n, ok := requested[0]; ok {
+		delete(requested, 0)
+		if defaultSizeKB > 0 {
+			requested[defaultSizeKB] = n
+		}
+	}
+	return requested
+}
+
+// hugepagesRead reads the counters of the huge page pools in directory dir, the
+// system-wide one or that of NUMA node numaNode.
+func hugepagesRead(dir string, numaNode *int) ([]hugepagesState, error) {
+	var pools []hugepagesState
+
+	dirs, err := filepath.Glob(filepath.Join(dir, hugepagesDirGlob))
+	if err != nil {
+		return nil, err
+	}
+	for _, d := range dirs {
+		size, err := strconv.ParseInt(strings.TrimSuffix(strings.TrimPrefix(filepath.Base(d), hugepagesDirPrefix), "kB"), 10, 64)
+		if err != nil {
+			continue
+		}
+		pool := hugepagesState{SizeKB: size, NUMANode: numaNode}
+		for file, counter := range map[string]*int64{
+			"nr_hugepages":      &pool.Total,
+			"free_hugepages":    &pool.Free,
+			"resv_hugepages":    &pool.Reserved,
+			"surplus_hugepages": &pool.Surplus,
+		} {
+			data, err := ioutil.ReadFile(filepath.Join(d, file))
+			if err != nil {
+				if os.IsNotExist(err) {
+					// resv_hugepages is system-wide only
+					continue
+				}
+				return nil, err
+			}
+			if *counter, err = strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64); err != nil {
+				return nil, fmt.Errorf("invalid content of %q: %v", filepath.Join(d, file), err)
+			}
+		}
+		pools = append(pools, pool)
+	}
+	return pools, nil
+}
+
+// bootStateRead reads the kernel command line and the huge page pools.
+func bootStateRead() (*bootState, error) {
+	cmdline, err := ioutil.ReadFile(procCmdline)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %q: %v", procCmdline, err)
+	}
+	bs := &bootState{Time: time.Now(), Cmdline: strings.TrimSpace(string(cmdline))}
+
+	if bs.Hugepages, err = hugepagesRead(sysHugepagesDir, nil); err != nil {
+		return nil, fmt.Errorf("failed to read the huge pages: %v", err)
+	}
+	nodes, _ := filepath.Glob(sysNUMANodesGlob)
+	for _, dir := range nodes {
+		node, err := strconv.Atoi(strings.TrimPrefix(filepath.Base(dir), "node"))
+		if err != nil {
+			continue
+		}
+		pools, err := hugepagesRead(filepath.Join(dir, "hugepages"), &node)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the huge pages of NUMA node %d: %v", node, err)
+		}
+		bs.Hugepages = append(bs.Hugepages, pools...)
+	}
+	sort.SliceStable(bs.Hugepages, func(i, j int) bool { return bs.Hugepages[i].SizeKB < bs.Hugepages[j].SizeKB })
+
+	defaultSizeKB, err := hugepagesDefaultSizeKB()
+	if err != nil {
+		klog.V(1).Infof("cannot tell the default huge page size: %v", err)
+	}
+	bs.HugepagesRequested = hugepagesRequested(strings.Fields(bs.Cmdline), defaultSizeKB)
+	for size, n := range bs.HugepagesRequested {
+		allocated := int64(0)
+		for _, pool := range bs.Hugepages {
+			if pool.SizeKB == size && pool.NUMANode == nil {
+				allocated = pool.Total
+			}
+		}
+		if allocated < n {
+			bs.HugepagesShortfall = append(bs.HugepagesShortfall,
+				fmt.Sprintf("%dkB: %d of %d requested pages allocated", size, allocated, n))
+		}
+	}
+	sort.Strings(bs.HugepagesShortfall)
+
+	return bs, nil
+}
+
+// bootStateUpdate records the kernel state resulting from the boot-time tuning in
+// the status.
+func (c *Controller) bootStateUpdate() {
+	bs, err := bootStateRead()
+	if err != nil {
+		klog.Errorf("failed to read the boot-time tuning state: %v", err)
+		return
+	}
+	for _, s := range bs.HugepagesShortfall {
+		klog.Warningf("the kernel allocated fewer huge pages than requested on the command line: %s", s)
+	}
+	c.status.setBootState(bs)
+}
+
+// bootMetricsCollect writes the huge pages metrics.
+func (c *Controller) bootMetricsCollect(buf *bytes.Buffer) {
+	var pages, requested []metrics.Sample
+
+	c.status.RLock()
+	bs := c.status.boot
+	c.status.RUnlock()
+	if bs == nil {
+		return
+	}
+
+	for _, pool := range bs.Hugepages {
+		node := ""
+		if pool.NUMANode != nil {
+			node = strconv.Itoa(*pool.NUMANode)
+		}
+		for _, sv := range []struct {
+			state string
+			value int64
+		}{{"total", pool.Total}, {"free", pool.Free}, {"reserved", pool.Reserved}, {"surplus", pool.Surplus}} {
+			if sv.state == "reserved" && pool.NUMANode != nil {
+				continue
+			}
+			pages = append(pages, metrics.Sample{
+				Labels: map[string]string{"size_kb": strconv.FormatInt(pool.SizeKB, 10), "numa_node": node, "state": sv.state},
+				Value:  float64(sv.value),
+			})
+		}
+	}
+	var sizes []int64
+	for size := range bs.HugepagesRequested {
+		sizes = append(sizes, size)
+	}
+	sort.Slice(sizes, func(i, j int) bool { return sizes[i] < sizes[j] })
+	for _, size := range sizes {
+		requested = append(requested, metrics.Sample{
+			Labels: map[string]string{"size_kb": strconv.FormatInt(size, 10)},
+			Value:  float64(bs.HugepagesRequested[size]),
+		})
+	}
+
+	metrics.Write(buf, "hugepages", "gauge", "Huge pages by page size, NUMA node (empty for the system-wide pool) and state.", pages...)
+	metrics.Write(buf, "hugepages_requested", "gauge", "Huge pages requested on the kernel command line by page size.", requested...)
+}
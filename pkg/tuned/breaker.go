@@ -0,0 +1,191 @@
+package tuned
+
+import (
+	"fmt"  // Sprintf()
+	"time" // time.Time, ...
+
+	"k8s.io/klog"
+
+	"github.com/openshift/openshift-tuned/pkg/notify"
+	"github.com/openshift/openshift-tuned/pkg/trace"
+)
+
+// Types
+// reloadVerify is a tuned reload waiting to be verified.
+type reloadVerify struct {
+	profile     string
+	contentHash string
+	key         string
+	deadline    time.Time
+	// for the reload history
+	started         time.Time
+	trigger         string
+	previousProfile string
+	// ends when tuned applied the profile or the reload failed
+	span *trace.Span
+}
+
+// reloadBreaker tracks failed tuned reloads per profile content.  Reloads of content
+// which failed are retried with an exponential backoff; after too many failures
+// openshift-tuned falls back to the last known-good profile.
+type reloadBreaker struct {
+	// consecutive failures per profile content key
+	failures map[string]int
+	// earliest time of the next reload attempt per profile content key
+	retryAt map[string]time.Time
+	// earliest time of the next retry of a failed reload
+	nextRetry time.Time
+	// reload waiting to be verified, nil if none
+	pending *reloadVerify
+	// the last profile tuned applied successfully; its configuration is saved by snapshotTake()
+	lastGoodProfile string
+	lastGoodKey     string
+}
+
+// Constants
+const (
+	reloadBackoffInit = 10 * time.Second
+	reloadBackoffMax  = 10 * time.Minute
+	// tolerance when comparing the active profile file mtime to the reload time
+	activeProfileMtimeSlack = time.Second
+)
+
+// Functions
+// reloadKey returns the key identifying profile content.
+func reloadKey(profile, contentHash string) string {
+	return profile + ":" + contentHash
+}
+
+// backoff returns the time to wait before reloading the content identified by key.
+func (b *reloadBreaker) backoff(key string) time.Duration {
+	if d := b.retryAt[key].Sub(time.Now()); d > 0 {
+		return d
+	}
+	return 0
+}
+
+// reloaded starts the verification of a tuned reload applying profile with content
+// of hash contentHash; tuned needs to apply it within timeout.
+func (b *reloadBreaker) reloaded(profile, contentHash string, timeout time.Duration) {
+	b.pending = &reloadVerify{
+		profile:     profile,
+		contentHash: contentHash,
+		key:         reloadKey(profile, contentHash),
+		deadline:    time.Now().Add(timeout),
+		started:     time.Now(),
+	}
+}
+
+// reloadSucceeded records a successful application of the pending reload.
+func (c *Controller) reloadSucceeded() {
+	b := &c.breaker
+
+	klog.V(1).Infof("tuned applied profile %q", b.pending.profile)
+	delete(b.failures, b.pending.key)
+	delete(b.retryAt, b.pending.key)
+	b.lastGoodProfile = b.pending.profile
+	b.lastGoodKey = b.pending.key
+	c.appliedSave(b.pending.profile, b.pending.contentHash)
+	if err := c.snapshotTake(b.pending.profile); err != nil {
+		klog.Errorf("failed to save the last known-good configuration: %v", err)
+	}
+
+	c.status.RLock()
+	requested := c.status.requestedProfile
+	c.status.RUnlock()
+	if len(requested) == 0 || requested == b.pending.profile {
+		c.status.setState(stateStable, "")
+	} else {
+		c.status.setState(stateDegraded, fmt.Sprintf("running profile %q instead of the requested profile %q", b.pending.profile, requested))
+	}
+	c.historyRecord(b.pending, reloadResultApplied, "")
+	b.pending.span.End()
+	profileName := b.pending.profile
+	if previous := b.pending.previousProfile; profileName != previous {
+		msg := fmt.Sprintf("tuned applied profile %q", profileName)
+		if len(previous) > 0 {
+			msg += fmt.Sprintf(" instead of %q", previous)
+		}
+		c.notify(notify.EventProfileChanged, profileName, previous, msg)
+	}
+	b.pending = nil
+	c.hooksQueueApplied(profileName)
+}
+
+// reloadFailed records a failure of the pending reload.  Returns true if the failure
+// limit was reached and openshift-tuned should fall back to the last known-good profile.
+func (c *Controller) reloadFailed(reason string) bool {
+	b := &c.breaker
+	key := b.pending.key
+	b.failures[key]++
+	n := b.failures[key]
+
+	backoff := reloadBackoffInit << uint(n-1)
+	if backoff > reloadBackoffMax || backoff <= 0 {
+		backoff = reloadBackoffMax
+	}
+	b.retryAt[key] = time.Now().Add(backoff)
+	b.nextRetry = b.retryAt[key]
+
+	msg := fmt.Sprintf("failed to apply profile %q (%d consecutive failures): %s", b.pending.profile, n, reason)
+	klog.Errorf("%s; retrying in %v", msg, backoff)
+	c.status.setState(stateDegraded, msg)
+	c.historyRecord(b.pending, reloadResultFailed, reason)
+	c.notify(notify.EventApplyFailed, b.pending.profile, b.pending.previousProfile, msg)
+	b.pending.span.SetError(fmt.Errorf("%s", reason))
+	b.pending.span.End()
+	b.pending = nil
+
+	return n >= c.opts.ReloadFailuresMax && key != b.lastGoodKey
+}
+
+// reloadVerifyCheck verifies the pending reload, if any.
+func (c *Controller) reloadVerifyCheck(tuned *tunedState) error {
+	if c.breaker.pending == nil {
+		return nil
+	}
+	activeProfile, err := c.store.ActiveProfile()
+	stalled := err == nil && activeProfile == c.breaker.pending.profile && c.activeProfileStale(c.breaker.pending.started)
+	if err == nil && activeProfile == c.breaker.pending.profile && !stalled {
+		applied := *c.breaker.pending
+		if err := c.reloadVerified(tuned); err != nil {
+			return err
+		}
+		nodeAnnotateApplied(tuned, applied.profile, applied.contentHash, c.failingPluginsAnnotation())
+		return nil
+	}
+	if time.Now().Before(c.breaker.pending.deadline) {
+		return nil
+	}
+	reason := fmt.Sprintf("active profile %q after %v", activeProfile, c.opts.ReloadVerifyTimeout)
+	if stalled {
+		reason = fmt.Sprintf("tuned stalled, active profile not reported again within %v of the reload", c.opts.ReloadVerifyTimeout)
+	}
+	if c.reloadFailed(reason) {
+		return c.reloadFallback(tuned)
+	}
+	// Retry the reload after the backoff
+	tuned.change.retry = true
+	return nil
+}
+
+// activeProfileStale returns true if tuned did not report the active profile
+// since started, i.e. it did not (re)apply a profile since a reload at started.
+func (c *Controller) activeProfileStale(started time.Time) bool {
+	if !c.tunedFeatures.ActiveProfileRewrite {
+		return false
+	}
+	changed, err := c.store.ActiveProfileChanged()
+	if err != nil || changed.IsZero() {
+		// Unknown, rely on the active profile name only
+		return false
+	}
+	// Tolerate filesystems with coarse timestamps
+	return changed.Before(started.Add(-activeProfileMtimeSlack))
+}
+
+// reloadFallback rolls tuned back to the last known-good configuration.
+func (c *Controller) reloadFallback(tuned *tunedState) error {
+	klog.Warningf("falling back to the last known-good configuration")
+	return c.snapshotRestore(tuned)
+}
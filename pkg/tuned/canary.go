@@ -0,0 +1,113 @@
+package tuned
+
+import (
+	"bytes"   // bytes.Buffer
+	"fmt"     // Errorf()
+	"os"      // os.Stat()
+	"os/exec" // exec.Command()
+	"strings" // strings.Join()
+	"time"    // time.After()
+
+	"k8s.io/klog"
+
+	"github.com/openshift/openshift-tuned/pkg/process"
+	"github.com/openshift/openshift-tuned/pkg/profile"
+)
+
+// Types
+// probeResult is the outcome of a canary probe run after applying a profile.
+type probeResult struct {
+	profile.Probe
+	Error string `json:"error,omitempty"`
+}
+
+// Constants
+const (
+	probeExecTimeout = 10 * time.Second
+)
+
+// Functions
+// probeRun runs canary probe p.
+func probeRun(p profile.Probe) error {
+	switch p.Kind {
+	case profile.ProbeSysctl:
+		if strings.Contains(p.Args[0]+p.Args[1], "${") {
+			// Variables are expanded by tuned, we cannot check these
+			return nil
+		}
+		value, err := sysctlRead(p.Args[0])
+		if err != nil {
+			return err
+		}
+		if value != p.Args[1] {
+			return fmt.Errorf("sysctl %s is %q, expected %q", p.Args[0], value, p.Args[1])
+		}
+	case profile.ProbeExists:
+		if _, err := os.Stat(p.Args[0]); err != nil {
+			return err
+		}
+	case profile.ProbeExec:
+		var out bytes.Buffer
+
+		cmd := exec.Command(p.Args[0], p.Args[1:]...)
+		cmd.Stdout = &out
+		cmd.Stderr = &out
+		if err := process.RunTimeout(cmd, probeExecTimeout); err != nil {
+			return fmt.Errorf("%v: %s", err, strings.TrimSpace(out.String()))
+		}
+	}
+	return nil
+}
+
+// canaryRun runs the canary probes of tuned profile profileName and the profiles
+// it includes.  Returns the descriptions of the failed probes.
+func (c *Controller) canaryRun(profileName string) []string {
+	var (
+		failed  []string
+		results []probeResult
+	)
+
+	if !c.features.Enabled(featureCanaryProbes) {
+		return nil
+	}
+	probes, err := profile.ChainProbes(c.store, profileName)
+	if err != nil {
+		failed = append(failed, err.Error())
+	}
+	for _, p := range probes {
+		r := probeResult{Probe: p}
+		if err := probeRun(p); err != nil {
+			r.Error = err.Error()
+			failed = append(failed, fmt.Sprintf("%s %s: %v", p.Kind, strings.Join(p.Args, " "), err))
+		}
+		results = append(results, r)
+	}
+	c.status.setProbeResults(results)
+	if len(probes) > 0 {
+		klog.V(1).Infof("%d of %d canary probes of profile %q failed", len(failed), len(probes), profileName)
+	}
+	return failed
+}
+
+// reloadVerified handles a reload tuned reported as applied: it runs the canary
+// probes of the pending profile and, if these fail, marks the apply degraded or,
+// with opts.CanaryRollback, rolls back to the last known-good configuration.
+func (c *Controller) reloadVerified(tuned *tunedState) error {
+	b := &c.breaker
+	profileName := b.pending.profile
+
+	failed := c.canaryRun(profileName)
+	if len(failed) == 0 {
+		c.reloadSucceeded()
+		return nil
+	}
+	reason := fmt.Sprintf("canary probes failed: %s", strings.Join(failed, "; "))
+	if c.opts.CanaryRollback && len(b.lastGoodKey) > 0 && b.pending.key != b.lastGoodKey {
+		c.reloadFailed(reason)
+		return c.reloadFallback(tuned)
+	}
+	c.reloadSucceeded()
+	c.status.setState(stateDegraded, fmt.Sprintf("profile %q applied, but %s", profileName, reason))
+
+	return nil
+}
@@ -0,0 +1,144 @@
+package tuned
+
+import (
+	"encoding/json" // json.Marshal()
+	"fmt"           // Errorf()
+	"time"          // time.Now()
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/rest"
+	"k8s.io/klog"
+)
+
+// Types
+// tuningCondition is the state of the TuningReady node condition.
+type tuningCondition struct {
+	status  corev1.ConditionStatus
+	reason  string
+	message string
+}
+
+// Constants
+const (
+	// node condition reflecting whether the recommended profile is applied
+	// without errors, see Options.NodeCondition
+	nodeConditionTuningReady corev1.NodeConditionType = "TuningReady"
+	// period of checking the daemon state for node status changes; it also
+	// debounces the transient states of a reload
+	nodeStatusInterval = time.Second
+)
+
+// Functions
+// tuningConditionGet returns the TuningReady node condition for the current daemon
+// state.  The transient states of a reload are Unknown.
+func (c *Controller) tuningConditionGet() tuningCondition {
+	c.status.RLock()
+	defer c.status.RUnlock()
+
+	s := &c.status
+	switch s.state {
+	case stateStable:
+		var errs []pluginError
+		for _, e := range s.pluginErrors {
+			if e.Level == "ERROR" {
+				errs = append(errs, e)
+			}
+		}
+		if len(errs) > 0 {
+			return tuningCondition{corev1.ConditionFalse, "PluginErrors",
+				fmt.Sprintf("tuned logged %d plugin errors applying profile %q, e.g. %s: %s", len(errs), s.requestedProfile, errs[0].Plugin, errs[0].Message)}
+		}
+		if len(s.requestedProfile) == 0 {
+			return tuningCondition{corev1.ConditionTrue, "ProfileApplied", "the recommended tuned profile is applied"}
+		}
+		return tuningCondition{corev1.ConditionTrue, "ProfileApplied", fmt.Sprintf("tuned profile %q is applied", s.requestedProfile)}
+	case stateDegraded, stateRollingBack:
+		return tuningCondition{corev1.ConditionFalse, s.state.String(), s.stateReason}
+	}
+	return tuningCondition{corev1.ConditionUnknown, s.state.String(), s.stateReason}
+}
+
+// nodeConditionSet sets the TuningReady condition of node nodeName.
+func nodeConditionSet(client rest.Interface, nodeName string, cond tuningCondition, transition time.Time) error {
+	now := metav1.Now()
+	patch := map[string]interface{}{
+		"status": map[string]interface{}{
+			"conditions": []corev1.NodeCondition{{
+				Type:               nodeConditionTuningReady,
+				Status:             cond.status,
+				Reason:             cond.reason,
+				Message:            cond.message,
+				LastHeartbeatTime:  now,
+				LastTransitionTime: metav1.NewTime(transition),
+			}},
+		},
+	}
+	data, err := json.Marshal(patch)
+	if err != nil {
+		return fmt.Errorf("failed to create condition patch for node %q: %v", nodeName, err)
+	}
+	// A strategic merge patch merges the conditions by type
+	err = client.Patch(types.StrategicMergePatchType).Resource("nodes").Name(nodeName).SubResource("status").Body(data).Do().Error()
+	if err != nil {
+		return fmt.Errorf("failed to set the %s condition of node %q: %v", nodeConditionTuningReady, nodeName, err)
+	}
+	return nil
+}
+
+// nodeStatusRun publishes the changes of the daemon state to the node until stop
+// is closed: the TuningReady condition (opts.NodeCondition) and the taint of a
+// Degraded node (opts.DegradedTaint).  Failed updates are retried on the next check.
+func (c *Controller) nodeStatusRun(client rest.Interface, nodeName string, stop <-chan struct{}) {
+	var (
+		published  *tuningCondition
+		transition time.Time
+		// whether the node carries c.degradedTaint, nil if not known
+		tainted *bool
+	)
+
+	ticker := time.NewTicker(nodeStatusInterval)
+	defer ticker.Stop()
+	for {
+		if wanted, ok := c.degradedTaintWanted(); c.degradedTaint != nil && ok && (tainted == nil || *tainted != wanted) {
+			changed, err := nodeTaintSet(client, nodeName, *c.degradedTaint, wanted)
+			if changed || err != nil {
+				action := auditActionTaint
+				if !wanted {
+					action = auditActionUntaint
+				}
+				c.audit(action, auditActorSelf, fmt.Sprintf("node %s: %s", nodeName, c.degradedTaint.ToString()), err)
+			}
+			if err != nil {
+				klog.Errorf("%s", err.Error())
+			} else {
+				if changed && wanted {
+					klog.Warningf("tuning degraded, tainted node %q with %s", nodeName, c.degradedTaint.ToString())
+				} else if changed {
+					klog.Infof("tuning recovered, removed taint %s from node %q", c.degradedTaint.ToString(), nodeName)
+				}
+				tainted = &wanted
+			}
+		}
+
+		cond := c.tuningConditionGet()
+		if c.opts.NodeCondition && (published == nil || cond != *published) {
+			if published == nil || cond.status != published.status {
+				transition = time.Now()
+			}
+			if err := nodeConditionSet(client, nodeName, cond, transition); err != nil {
+				klog.Errorf("%s", err.Error())
+			} else {
+				klog.V(1).Infof("node condition %s=%s: %s", nodeConditionTuningReady, cond.status, cond.reason)
+				published = &cond
+			}
+		}
+
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,1460 @@
+// Package tuned implements the openshift-tuned daemon: it extracts the tuned profiles
+// rendered by the cluster-node-tuning-operator, selects the profile for the node and
+// runs and reloads tuned.
+package tuned
+
+import (
+	"bytes"         // bytes.Buffer
+	"context"       // context.Context
+	"fmt"           // Printf()
+	"math"          // math.Pow()
+	"net"           // net.Conn
+	"os"            // os.Exit(), os.Stderr, ...
+	"os/exec"       // os.Exec()
+	"os/user"       // user.Current()
+	"path/filepath" // filepath.Join()
+	"reflect"       // DeepEqual()
+	"sort"          // sort.Strings()
+	"strconv"       // strconv
+	"strings"       // strings.Join()
+	"syscall"       // syscall.SIGHUP, ...
+	"time"          // time.Second, ...
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/klog"
+
+	"github.com/fsnotify/fsnotify"
+
+	tunedv1 "github.com/openshift/cluster-node-tuning-operator/pkg/apis/tuned/v1"
+	tunedclientset "github.com/openshift/cluster-node-tuning-operator/pkg/generated/clientset/versioned"
+
+	"github.com/openshift/openshift-tuned/pkg/audit"
+	"github.com/openshift/openshift-tuned/pkg/featuregate"
+	"github.com/openshift/openshift-tuned/pkg/hooks"
+	"github.com/openshift/openshift-tuned/pkg/layout"
+	"github.com/openshift/openshift-tuned/pkg/notify"
+	"github.com/openshift/openshift-tuned/pkg/process"
+	"github.com/openshift/openshift-tuned/pkg/profile"
+	"github.com/openshift/openshift-tuned/pkg/rotate"
+	"github.com/openshift/openshift-tuned/pkg/schedule"
+	"github.com/openshift/openshift-tuned/pkg/systemd"
+	"github.com/openshift/openshift-tuned/pkg/trace"
+)
+
+// Types
+// Options configures a Controller.
+type Options struct {
+	// NodeName is the name of the node openshift-tuned manages.
+	NodeName string
+	// KubeConfig is the kubeconfig file to use; see getConfig() if empty.
+	KubeConfig string
+	// ActiveProfileFile is the tuned active profile file.
+	ActiveProfileFile string
+	// ActiveProfileSource is where the active profile is read from: "file" for
+	// ActiveProfileFile, "tuned-adm" for the tuned control interface.
+	ActiveProfileSource string
+	// ProfilesConfigMap is the tuned profiles ConfigMap file.
+	ProfilesConfigMap string
+	// ProfilesDir is the directory to extract tuned profiles to.
+	ProfilesDir string
+	// SystemProfilesDir is the directory with the profiles shipped with tuned.
+	SystemProfilesDir string
+	// RunDir is the runtime directory for the pid file, the last known-good configuration
+	// and the reload history.
+	RunDir string
+	// Socket is the control socket path; a path starting with "@" is in the
+	// abstract socket namespace, see unix(7).
+	Socket string
+	// WatchFiles are files/directories to watch for changes, "path[=action]";
+	// see watchFilesParse().
+	WatchFiles []string
+	// WatchQuiescence is the time without filesystem events to wait for before
+	// extracting the changed tuned profiles.
+	WatchQuiescence time.Duration
+	// ConfigFile is the openshift-tuned configuration file; OnConfigChange is called
+	// when the directory holding it changes and returns the options to run with.
+	// Only some of them are applied without a restart, see reconfigure().
+	ConfigFile     string
+	OnConfigChange func() Options
+	// OperandConfigMap is the name of the ConfigMap in the operand namespace with
+	// the configuration the operator manages, in the format of ConfigFile; empty
+	// disables it.  OnOperandConfigChange is called with its changed content and
+	// returns the options to run with like OnConfigChange.
+	OperandConfigMap      string
+	OnOperandConfigChange func(data string) Options
+	// SupportConfigMap makes the Controller extract tuned profiles from the tuned-profiles
+	// ConfigMap file too; remove when dropping support for tuned-profiles ConfigMap.
+	SupportConfigMap bool
+	// APIPort is the port to serve the HTTP API on; 0 disables the API.
+	APIPort int
+	// ReloadVerifyTimeout is the time for tuned to apply a profile after a reload.
+	ReloadVerifyTimeout time.Duration
+	// ReloadFailuresMax is the number of failed reloads of the same profile content
+	// after which the Controller falls back to the last known-good profile.
+	ReloadFailuresMax int
+	// RealtimeGating makes the Controller refuse to apply realtime profiles on a
+	// non-realtime kernel.
+	RealtimeGating bool
+	// RevertDelay delays switching back to the tuned profile requested before the
+	// current one; the switch is dropped if the current profile is requested again
+	// within the delay.  0 switches immediately.
+	RevertDelay time.Duration
+	// PartialReload makes the Controller write changed sysctls directly instead of
+	// reloading tuned when only sysctl values of the active profile changed.  Off
+	// by default, every change reloads tuned.
+	PartialReload bool
+	// RequireSignedProfiles makes the Controller refuse unsigned or tampered tuned profiles.
+	RequireSignedProfiles bool
+	// ProfileSigningKey is a PEM-encoded public key (RSA or ECDSA) to verify tuned
+	// profile signatures with.
+	ProfileSigningKey string
+	// WatchdogTimeout is the time the event loop may not tick before it is considered
+	// stuck and /healthz fails; 0 disables the watchdog.
+	WatchdogTimeout time.Duration
+	// HistorySize is the number of reload events kept in the reload history; 0
+	// disables the history.
+	HistorySize int
+	// AuditLog is the file recording the actions taken on the node, see
+	// audit.Log; empty disables the audit log.  It is rotated at AuditLogMaxSize
+	// MiB keeping AuditLogBackups rotated files.
+	AuditLog        string
+	AuditLogMaxSize int
+	AuditLogBackups int
+	// OTLPEndpoint is the OTLP/HTTP endpoint to export traces of the reconcile
+	// pipeline to; empty disables tracing.
+	OTLPEndpoint string
+	// Version is the openshift-tuned version.
+	Version string
+	// GitCommit is the git commit openshift-tuned was built from.
+	GitCommit string
+	// Flags are the effective option values, reported by the /version API.
+	Flags map[string]string
+	// Subreaper makes openshift-tuned the child subreaper of tuned, so that it reaps
+	// the processes orphaned by tuned (e.g. [script] plugin scripts) even if it
+	// does not run as PID 1.
+	Subreaper bool
+	// ForwardSignals are the signals (e.g. SIGUSR1, USR2, 12) openshift-tuned
+	// forwards to tuned; every element may be a comma-separated list.
+	ForwardSignals []string
+	// ProfileSources are additional sources of tuned profiles, "kind:location",
+	// e.g. "dir:/etc/tuned-extra"; see profile.NewSource().
+	ProfileSources []string
+	// Hooks are run in the given order after tuned applied a profile,
+	// "builtin:<name>" or "exec:<path>"; see hooks.New().
+	Hooks []string
+	// Notifiers are informed when the tuned profile changed or failed to apply,
+	// "webhook:<url>", "exec:<path>" or "events"; see notify.New().
+	Notifiers []string
+	// NoRollbackOnExit leaves the node-level tuning in place when openshift-tuned
+	// exits on a termination signal.
+	NoRollbackOnExit bool
+	// DrainAction is what openshift-tuned does while the node is cordoned, e.g.
+	// drained for an upgrade: empty to ignore it, "defer" to defer tuned reloads
+	// or "profile:<name>" to switch to tuned profile <name> until uncordoned.
+	DrainAction string
+	// NodeCondition makes openshift-tuned publish the TuningReady node condition:
+	// True if the recommended profile is applied without errors, False if it
+	// failed to apply, Unknown while it is being applied.
+	NodeCondition bool
+	// DegradedTaint is the taint "key[=value]:effect" openshift-tuned puts on its
+	// node while the tuning is Degraded, e.g. the profile failed to apply, and
+	// removes once the tuning is Stable again; empty disables it.
+	DegradedTaint string
+	// NFDFacts makes openshift-tuned write the Node Feature Discovery labels of
+	// the node (feature.node.kubernetes.io/...) to RunDir/nfd, one file per label
+	// holding its value, for recommend.d rules to match, e.g.
+	// /run/openshift-tuned/nfd/feature.node.kubernetes.io/network-sriov.capable=true
+	NFDFacts bool
+	// NFDVariables map node labels to tuned profile variables, "label=variable".
+	// The variables are written to RunDir/nfd-variables.conf for the profiles to
+	// include by [variables] include=.
+	NFDVariables []string
+	// MaintenanceWindow is when disruptive tuned reloads may be executed, see
+	// schedule.Parse(); other reloads are queued until the window opens.  The
+	// tuned.openshift.io/maintenance-window node annotation overrides it.  Empty
+	// allows reloads at any time.
+	MaintenanceWindow string
+	// CanaryRollback makes openshift-tuned fall back to the last known-good
+	// configuration if the canary probes of an applied profile fail, see
+	// profile.Probe; otherwise the failure only degrades the status.
+	CanaryRollback bool
+	// RetryInitial is the period of retrying the event loop after the first
+	// error; every further error multiplies it by RetryFactor up to RetryMax.
+	RetryInitial time.Duration
+	RetryMax     time.Duration
+	RetryFactor  float64
+	// RetryJitter randomizes every retry period by +/- this fraction.
+	RetryJitter float64
+	// Handoff makes openshift-tuned take over the tuned run by the instance
+	// listening on Socket instead of starting tuned, e.g. on an upgrade.  Both
+	// instances must share the PID namespace (hostPID) and run at the same time,
+	// e.g. with maxSurge; see handoffTake().
+	Handoff bool
+	// SocketAllow are the principals allowed to use the control socket besides
+	// root, "uid:<n>" or "gid:<n>"; other clients are rejected.
+	SocketAllow []string
+	// Standalone makes openshift-tuned work from the local files only, i.e. the
+	// profiles, recommend.d rules and the labels files they match, without any
+	// access to the Kubernetes API; e.g. on bootstrap before the apiserver runs.
+	Standalone bool
+	// AttachInterval is the period of checking whether the apiserver is reachable
+	// when Standalone; once it is, openshift-tuned switches to the Profile of the
+	// node.  Zero keeps openshift-tuned standalone.
+	AttachInterval time.Duration
+	// FeatureGates enables or disables subsystems, "Feature=true|false,...",
+	// see featuresKnown().
+	FeatureGates string
+	// TunedStdoutVerbosity and TunedStderrVerbosity are the klog verbosity the
+	// DEBUG and INFO lines tuned writes to stdout and stderr are logged at;
+	// WARNING and more severe lines are always logged.
+	TunedStdoutVerbosity int
+	TunedStderrVerbosity int
+	// ExecTimeout limits the commands openshift-tuned runs and waits for, i.e.
+	// tuned-adm, "tuned --version" and systemctl; a command still running is
+	// killed with the processes it started.  0 disables the limit.
+	ExecTimeout time.Duration
+	// TunedMaxLineSize is the maximum length of a line tuned logs; longer lines
+	// are truncated.  0 is process.DefaultMaxLineSize.
+	TunedMaxLineSize int
+	// TunedOutputLog is the file capturing everything tuned writes to stdout and
+	// stderr; only the WARNING and more severe lines are logged then.  Empty
+	// disables the capture.  It is rotated at TunedOutputLogMaxSize MiB keeping
+	// TunedOutputLogBackups rotated files.
+	TunedOutputLog        string
+	TunedOutputLogMaxSize int
+	TunedOutputLogBackups int
+	// MockTuned runs an in-process tuned stub instead of /usr/sbin/tuned (for testing).
+	MockTuned bool
+}
+
+// Option customizes a Controller created by New().
+type Option func(*Controller)
+
+// Controller runs and controls tuned on a single node.
+type Controller struct {
+	opts Options
+	// paths derived from opts
+	recommendDir  string
+	recommendFile string
+	pidFile       string
+
+	kubeConfig *rest.Config
+	runner     *process.Manager
+	store      profile.Store
+	priv       privHelper
+	status     daemonStatus
+	breaker    reloadBreaker
+	watchdog   watchdog
+	history    reloadHistory
+	tracer     *trace.Tracer
+	auditLog   *audit.Log
+	// opts.TunedOutputLog, nil if disabled
+	tunedOutput *rotate.File
+	timings     stageTimings
+	// the last lines tuned logged, see mustGather()
+	tunedLog tunedLogRing
+	// see recommendCached()
+	recommendCache recommendCache
+	// serializes extracting profiles and reloading tuned
+	pipeline pipeline
+	// tuned profile sources ordered by priority, see sourcesInit()
+	sources     []profile.Source
+	tunedSource *profile.StaticSource
+	// run after tuned applied a profile, see hooksInit()
+	hooks      []*hooks.Hook
+	hooksQueue hooksQueue
+	// informed of tuning changes, see notifiersInit()
+	notifiers []notify.Notifier
+	notifier  *notify.Dispatcher
+	// opts.WatchFiles and their actions
+	watches []watchFile
+	// opts.DrainAction
+	drain drainAction
+	// opts.SocketAllow
+	sockAllow sockAllow
+	// opts.MaintenanceWindow
+	window *schedule.Window
+	// opts.NFDVariables
+	nfdVariables []nfdVariable
+	// opts.DegradedTaint
+	degradedTaint *corev1.Taint
+	// opts.FeatureGates
+	features *featuregate.Gates
+
+	// detected by tunedVersionDetect()
+	tunedVersion  process.Version
+	tunedFeatures process.Features
+
+	// Stop() requests termination of Run()
+	done chan bool
+	// tuned process exited
+	tunedExit chan bool
+	// tuned was handed over to another instance, see handoffGive()
+	handedOff bool
+	// the changed OperandConfig, see operandConfigQueue()
+	operandConfigC chan string
+	// the informer events, see apiEvent()
+	apiEventC chan func(tuned *tunedState)
+	// a Standalone openshift-tuned attached to the apiserver, see apiAttach()
+	attached bool
+}
+
+// sockAccepted is a control socket command read by sockServe(), or an accept error.
+type sockAccepted struct {
+	conn    net.Conn
+	cred    peerCred
+	command string
+	err     error
+}
+
+type tunedState struct {
+	// node name openshift-tuned manages
+	nodeName string
+	// client for the core API group, used for node annotations
+	coreClient rest.Interface
+	// decides whether tuned needs to be reloaded
+	decider ReloadDecider
+	// tuned profile requested by the Profile object before the current one
+	previousRequested string
+	// switch back to previousRequested delayed by opts.RevertDelay
+	revert *revertPending
+
+	change struct {
+		// did profile change?
+		profile bool
+		// did the "rendered" tuned object change?
+		rendered bool
+		// did tuned profiles/recommend config change on the filesystem?
+		cfg bool
+		// does a failed reload need to be retried?
+		retry bool
+		// must tuned be reloaded even if the profile content did not change?
+		force bool
+		// is a reload queued for the maintenance window?
+		queued bool
+	}
+	// time of the last filesystem event, see Options.WatchQuiescence
+	lastFsEvent time.Time
+	// hooks of the changed watched files, see watchHooksFlush()
+	watchHooks []*hooks.Hook
+	// is the node cordoned, see Options.DrainAction
+	draining bool
+	// maintenance window from the node annotation, see Options.MaintenanceWindow
+	nodeWindow *schedule.Window
+	// NFD labels last written, see Options.NFDFacts
+	nfdFacts map[string]string
+	// execute a reload queued for the maintenance window now
+	applyNow bool
+}
+
+// Constants
+const (
+	operandNamespace       = "openshift-cluster-node-tuning-operator"
+	profileExtractInterval = 1
+	// see Options.ActiveProfileSource
+	activeProfileSourceFile     = "file"
+	activeProfileSourceTunedAdm = "tuned-adm"
+	programName                 = "openshift-tuned"
+)
+
+// Functions
+// DefaultOptions returns the default Controller options.
+func DefaultOptions() Options {
+	return Options{
+		ActiveProfileFile:     "/etc/tuned/active_profile",
+		ActiveProfileSource:   activeProfileSourceFile,
+		ProfilesConfigMap:     "/var/lib/tuned/profiles-data/tuned-profiles.yaml",
+		ProfilesDir:           "/etc/tuned",
+		SystemProfilesDir:     "/usr/lib/tuned",
+		RunDir:                "/run/" + programName,
+		Socket:                "/var/lib/tuned/openshift-tuned.sock",
+		SupportConfigMap:      true,
+		ReloadVerifyTimeout:   60 * time.Second,
+		WatchQuiescence:       2 * time.Second,
+		ReloadFailuresMax:     3,
+		RealtimeGating:        true,
+		WatchdogTimeout:       60 * time.Second,
+		HistorySize:           32,
+		AuditLog:              "/var/log/" + programName + "/audit.jsonl",
+		AuditLogMaxSize:       10,
+		AuditLogBackups:       3,
+		ExecTimeout:           30 * time.Second,
+		TunedMaxLineSize:      process.DefaultMaxLineSize,
+		TunedOutputLogMaxSize: 10,
+		TunedOutputLogBackups: 3,
+		RetryInitial:          10 * time.Second,
+		RetryMax:              300 * time.Second,
+		RetryFactor:           2,
+		AttachInterval:        30 * time.Second,
+		OperandConfigMap:      programName,
+	}
+}
+
+// WithRunner makes the Controller run tuned by r.
+func WithRunner(r process.Runner) Option {
+	return func(c *Controller) {
+		c.runner = process.NewManager(r)
+	}
+}
+
+// WithStore makes the Controller store tuned profiles in s.
+func WithStore(s profile.Store) Option {
+	return func(c *Controller) {
+		c.store = s
+	}
+}
+
+// WithKubeConfig makes the Controller talk to the apiserver using kubeConfig instead
+// of locating a kubeconfig itself, see getConfig().
+func WithKubeConfig(kubeConfig *rest.Config) Option {
+	return func(c *Controller) {
+		c.kubeConfig = kubeConfig
+	}
+}
+
+// New creates a Controller.
+func New(opts Options, options ...Option) *Controller {
+	c := &Controller{
+		opts:          opts,
+		recommendDir:  opts.ProfilesDir + "/recommend.d",
+		recommendFile: opts.ProfilesDir + "/recommend.d/50-openshift.conf",
+		pidFile:       opts.RunDir + "/" + programName + ".pid",
+		priv:          privHelperLocal{},
+		breaker: reloadBreaker{
+			failures: map[string]int{},
+			retryAt:  map[string]time.Time{},
+		},
+		done:           make(chan bool, 1),
+		tunedExit:      make(chan bool, 1),
+		operandConfigC: make(chan string, 1),
+		apiEventC:      make(chan func(tuned *tunedState)),
+		hooksQueue:     hooksQueue{wake: make(chan struct{}, 1)},
+
+		tunedFeatures: process.FeaturesFor(nil),
+		tunedSource:   profile.NewStaticSource(profileSourceTuned, profile.PriorityTuned),
+	}
+	execRunner := process.NewExecRunner(c.priv)
+	execRunner.LogHandler = c.tunedLogLine
+	execRunner.Verbosity = map[process.Stream]klog.Level{
+		process.StreamStdout: klog.Level(opts.TunedStdoutVerbosity),
+		process.StreamStderr: klog.Level(opts.TunedStderrVerbosity),
+	}
+	execRunner.MaxLineSize = opts.TunedMaxLineSize
+	execRunner.Timeout = opts.ExecTimeout
+	if len(opts.TunedOutputLog) > 0 {
+		c.tunedOutput = rotate.New(opts.TunedOutputLog, int64(opts.TunedOutputLogMaxSize)<<20, opts.TunedOutputLogBackups)
+		execRunner.Output = c.tunedOutput
+	}
+	c.runner = process.NewManager(execRunner)
+	if opts.MockTuned {
+		c.runner = process.NewManager(&process.MockRunner{
+			Recommender:       c.recommendEvaluate,
+			ActiveProfileFile: opts.ActiveProfileFile,
+		})
+	}
+	store := &profile.FSStore{
+		Writer:            c.priv,
+		ProfilesDir:       opts.ProfilesDir,
+		SystemProfilesDir: opts.SystemProfilesDir,
+		RecommendFile:     c.recommendFile,
+		ActiveProfileFile: opts.ActiveProfileFile,
+	}
+	if opts.ActiveProfileSource == activeProfileSourceTunedAdm {
+		store.ActiveProfileQuery = func() (string, error) {
+			return process.TunedAdmActive(opts.ExecTimeout)
+		}
+	}
+	c.store = store
+	// Without an endpoint, the spans are timed only
+	c.tracer = trace.NewTracer(opts.OTLPEndpoint, programName)
+	c.tracer.OnEnd(c.timings.observe)
+	for _, option := range options {
+		option(c)
+	}
+
+	return c
+}
+
+// newUnixListener listens on control socket addr.  An addr starting with "@" is
+// in the abstract namespace, see unix(7), and has no file.  Otherwise, the socket
+// file is created with permissions perm in a directory only we can write to; a
+// stale socket file is replaced, but a socket another process listens on is not.
+func newUnixListener(addr string, perm os.FileMode) (net.Listener, error) {
+	if strings.HasPrefix(addr, "@") {
+		return net.Listen("unix", addr)
+	}
+	dir := filepath.Dir(addr)
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		if err := layout.Mkdir(dir); err != nil {
+			return nil, err
+		}
+	}
+	if err := layout.VerifyDir(dir); err != nil {
+		return nil, fmt.Errorf("insecure control socket directory: %v", err)
+	}
+	if err := sockStaleRemove(addr); err != nil {
+		return nil, err
+	}
+	l, err := net.Listen("unix", addr)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(addr, perm); err != nil {
+		l.Close()
+		return nil, err
+	}
+	return l, nil
+}
+
+// sockStaleRemove removes the control socket file addr left behind by an instance
+// which exited.  Fails if addr is not a socket or another process listens on it.
+func sockStaleRemove(addr string) error {
+	fi, err := os.Lstat(addr)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if fi.Mode()&os.ModeSocket == 0 {
+		return fmt.Errorf("%q exists and is not a socket", addr)
+	}
+	if conn, err := net.DialTimeout("unix", addr, sockProbeTimeout); err == nil {
+		conn.Close()
+		return fmt.Errorf("%q is in use by a running process", addr)
+	}
+	return os.Remove(addr)
+}
+
+// getConfig creates a *rest.Config for talking to a Kubernetes apiserver.
+//
+// Config precedence
+//
+// * kubeConfig file if not empty
+// * KUBECONFIG environment variable pointing at a file
+// * In-cluster config if running in cluster
+// * $HOME/.kube/config if exists
+func getConfig(kubeConfig string) (*rest.Config, error) {
+	configFromFlags := func(kubeConfig string) (*rest.Config, error) {
+		if _, err := os.Stat(kubeConfig); err != nil {
+			return nil, fmt.Errorf("cannot stat kubeconfig %q", kubeConfig)
+		}
+		return clientcmd.BuildConfigFromFlags("", kubeConfig)
+	}
+
+	if len(kubeConfig) > 0 {
+		return configFromFlags(kubeConfig)
+	}
+	// If an env variable is specified with the config location, use that
+	kubeConfig = os.Getenv("KUBECONFIG")
+	if len(kubeConfig) > 0 {
+		return configFromFlags(kubeConfig)
+	}
+	// If no explicit location, try the in-cluster config
+	if c, err := rest.InClusterConfig(); err == nil {
+		return c, nil
+	}
+	// If no in-cluster config, try the default location in the user's home directory
+	if usr, err := user.Current(); err == nil {
+		kubeConfig := filepath.Join(usr.HomeDir, ".kube", "config")
+		return configFromFlags(kubeConfig)
+	}
+
+	return nil, fmt.Errorf("could not locate a kubeconfig")
+}
+
+func disableSystemTuned(timeout time.Duration) {
+	var (
+		stdout bytes.Buffer
+		stderr bytes.Buffer
+	)
+	klog.V(1).Infof("disabling system tuned...")
+	cmd := exec.Command("/usr/bin/systemctl", "disable", "tuned", "--now")
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	err := process.RunTimeout(cmd, timeout)
+	if err != nil {
+		klog.V(1).Infof("failed to disable system tuned: %v: %s", err, stderr.String()) // do not use log.Printf(), tuned has its own timestamping
+	}
+}
+
+// tunedProfilesDecode decodes the profiles of the "rendered" Tuned object.
+func tunedProfilesDecode(profiles []tunedv1.TunedProfile) (map[string]string, error) {
+	mProfiles := make(map[string]string)
+	for index, tp := range profiles {
+		if tp.Name == nil {
+			klog.Warningf("tunedProfilesDecode(): profile name missing for profile %v", index)
+			continue
+		}
+		if tp.Data == nil {
+			klog.Warningf("tunedProfilesDecode(): profile data missing for profile %v", index)
+			continue
+		}
+		data, err := profile.DataDecode(*tp.Data)
+		if err != nil {
+			return nil, fmt.Errorf("tuned profile %q: %v", *tp.Name, err)
+		}
+		mProfiles[*tp.Name] = data
+	}
+	return mProfiles, nil
+}
+
+// profilesExtract extracts the profiles of the "rendered" Tuned object along with
+// the profiles of the other sources.
+func (c *Controller) profilesExtract(profiles []tunedv1.TunedProfile) error {
+	mProfiles, err := tunedProfilesDecode(profiles)
+	if err != nil {
+		return err
+	}
+	c.tunedSource.Set(mProfiles)
+	return c.pipeline.do("extract", func() error {
+		return c.profilesSync(nil)
+	})
+}
+
+// profilesWrite writes the verified tuned profiles extracted from sources (profile
+// name -> source name) and logs a unified diff of every profile that changed.
+func (c *Controller) profilesWrite(profiles map[string]string, sources map[string]string) error {
+	var (
+		names, changed []string
+		diffs          bytes.Buffer
+		written        = map[string]string{}
+	)
+
+	for name := range profiles {
+		if !profile.IsSignature(name) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		data := profiles[name]
+		fromFile, old := "/dev/null", ""
+		if c.store.HasProfile(name) {
+			fromFile = fmt.Sprintf("a/%s/%s", name, profile.ConfFile)
+			if d, err := c.store.ReadProfile(name); err == nil {
+				old = d
+			}
+		}
+		if diff := profile.Diff(fromFile, fmt.Sprintf("b/%s/%s", name, profile.ConfFile), old, data); len(diff) > 0 {
+			klog.Infof("tuned profile %q from %s changed:\n%s", name, sources[name], diff)
+			diffs.WriteString(diff)
+			changed = append(changed, name+" ("+sources[name]+")")
+		}
+		written[name] = data
+	}
+	if err := c.store.WriteProfiles(written); err != nil {
+		c.audit(auditActionExtract, auditActorSelf, strings.Join(changed, ", "), err)
+		return err
+	}
+	for _, name := range names {
+		c.status.setProfileSource(name, sources[name])
+	}
+	if diffs.Len() > 0 {
+		c.status.setProfileDiff(strings.Join(changed, ", "), diffs.String())
+		c.audit(auditActionExtract, auditActorSelf, strings.Join(changed, ", "), nil)
+	}
+
+	return nil
+}
+
+func (c *Controller) pidFileWrite() error {
+	if err := layout.Mkdir(c.opts.RunDir); err != nil {
+		return fmt.Errorf("failed to create %s run directory %q: %v", programName, c.opts.RunDir, err)
+	}
+	if err := layout.WriteFile(c.pidFile, []byte(strconv.Itoa(os.Getpid()))); err != nil {
+		return fmt.Errorf("failed to write %s pid file %q: %v", programName, c.pidFile, err)
+	}
+	return nil
+}
+
+func (c *Controller) tunedRecommendFileWrite(profileName string) error {
+	klog.V(2).Infof("tunedRecommendFileWrite(): %s", profileName)
+	return c.store.WriteRecommend(profileName)
+}
+
+// tunedStop stops tuned.  Unless rollback is set, tuned is killed so that the
+// node-level tuning stays in place.  A socket-initiated stop is acknowledged
+// via s with SockAckStop or SockAckStopNoRollback.
+func (c *Controller) tunedStop(s *sockAccepted, rollback bool) error {
+	if c.runner.Pid() != 0 {
+		sig := syscall.SIGTERM
+		if !rollback {
+			// tuned cannot roll back the tuning when killed
+			sig = syscall.SIGKILL
+		}
+		pid := c.runner.Pid()
+		klog.V(1).Infof("sending %s to PID %d", signalName(sig), pid)
+		err := c.runner.Signal(sig)
+		c.audit(auditActionSignal, auditActorSelf, fmt.Sprintf("%s to PID %d", signalName(sig), pid), err)
+		if err != nil {
+			return err
+		}
+		// Wait for tuned process to stop -- this will enable node-level tuning rollback
+		<-c.tunedExit
+		klog.V(1).Infof("tuned process terminated")
+	}
+	// Otherwise, looks like there has been a termination signal prior to starting tuned
+
+	if s != nil {
+		// This was a socket-initiated shutdown; indicate a successful stop
+		ack := SockAckStop
+		if !rollback {
+			ack = SockAckStopNoRollback
+		}
+		if _, err := (*s).conn.Write([]byte(ack)); err != nil {
+			return fmt.Errorf("cannot write a response via %q: %v", c.opts.Socket, err)
+		}
+	}
+
+	return nil
+}
+
+func (c *Controller) tunedReload() error {
+	// Only report the errors of the profile about to be applied
+	c.status.clearPluginErrors()
+
+	if c.runner.Pid() == 0 {
+		// Tuned hasn't been started by openshift-tuned, start it
+		if err := c.tunedProfileModeWrite(); err != nil {
+			return errCategorize(errCategoryFS, fmt.Errorf("failed to set the tuned profile mode: %v", err))
+		}
+		err := c.runner.Start(c.tunedExit)
+		c.audit(auditActionStart, auditActorSelf, "", err)
+		if err != nil {
+			return errCategorize(errCategoryExec, err)
+		}
+		return nil
+	}
+
+	klog.Infof("reloading tuned...")
+
+	pid := c.runner.Pid()
+	klog.Infof("sending HUP to PID %d", pid)
+	err := c.runner.Signal(syscall.SIGHUP)
+	c.audit(auditActionSignal, auditActorSelf, fmt.Sprintf("SIGHUP to PID %d", pid), err)
+	if err != nil {
+		return errCategorize(errCategoryExec, fmt.Errorf("error sending SIGHUP to PID %d: %v", pid, err))
+	}
+
+	return nil
+}
+
+func (c *Controller) timedTunedReloader(tuned *tunedState) (err error) {
+	var in reloadInputs
+
+	if err = c.reloadVerifyCheck(tuned); err != nil {
+		return err
+	}
+	if !(tuned.change.profile || tuned.change.rendered || tuned.change.cfg || tuned.change.retry || tuned.change.force || tuned.change.queued) {
+		return nil
+	}
+	if !(tuned.change.profile || tuned.change.rendered || tuned.change.cfg || tuned.change.retry || tuned.change.force) && !c.windowOpen(tuned) {
+		// Only a reload queued for the maintenance window, which did not open yet
+		return nil
+	}
+	if !(tuned.change.profile || tuned.change.rendered || tuned.change.cfg || tuned.change.force) && time.Now().Before(c.breaker.nextRetry) {
+		// Only a failed reload to retry, wait for the backoff to expire
+		return nil
+	}
+	if c.drainDeferred(tuned) {
+		// Keep the changes for after the node is uncordoned
+		return nil
+	}
+	if tuned.change.cfg && time.Since(tuned.lastFsEvent) < c.opts.WatchQuiescence {
+		// Let a burst of filesystem events, e.g. of a ConfigMap update, settle
+		return nil
+	}
+	trigger := reloadTrigger(tuned)
+
+	span := c.tracer.Start("reconcile")
+	span.SetAttribute("trigger", trigger)
+	defer func() {
+		span.SetError(err)
+		span.End()
+	}()
+	tuned.change.profile = false
+	tuned.change.rendered = false
+	tuned.change.retry = false
+	tuned.change.queued = false
+	force := tuned.change.force
+	tuned.change.force = false
+
+	// Check tuned profiles file changes
+	if tuned.change.cfg {
+		tuned.change.cfg = false
+		if err = c.profilesSync(span); err == errSourcesSettling {
+			tuned.change.cfg = true
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	in.tunedRunning = c.runner.Pid() != 0
+	if in.tunedRunning {
+		if in.activeProfile, err = c.store.ActiveProfile(); err != nil {
+			return errCategorize(errCategoryFS, err)
+		}
+	}
+	s := span.Child("recommend")
+	in.recommendedProfile, err = c.recommendCached()
+	s.SetError(err)
+	s.End()
+	if err != nil {
+		return errCategorize(errCategoryExec, err)
+	}
+	span.SetAttribute("profile", in.recommendedProfile)
+	in.recommendedExists = c.store.HasProfile(in.recommendedProfile)
+	s = span.Child("hash")
+	if in.contentHash, err = profile.ChainHash(c.store, in.recommendedProfile); err != nil {
+		klog.V(1).Infof("failed to hash content of profile %q: %v", in.recommendedProfile, err)
+	}
+	in.chain, _ = profile.ChainLoad(c.store, in.recommendedProfile)
+	s.End()
+
+	reload, reason := tuned.decider.Decide(in)
+	if !reload && force && in.recommendedExists {
+		reload, reason = true, "a watched file changed"
+	}
+	if !reload {
+		c.windowDequeue(tuned)
+		klog.V(1).Infof("not reloading tuned: %s", reason)
+		return nil
+	}
+	if c.opts.RealtimeGating {
+		rt, err := c.profileRequiresRealtime(in.recommendedProfile)
+		if err != nil {
+			klog.V(1).Infof("failed to check whether profile %q requires a realtime kernel: %v", in.recommendedProfile, err)
+		}
+		if rt && !kernelRealtime() {
+			msg := fmt.Sprintf("profile %q requires a realtime kernel, refusing to apply it", in.recommendedProfile)
+			klog.Errorf("%s", msg)
+			c.status.setState(stateDegraded, msg)
+			return nil
+		}
+	}
+
+	sysctls := tuned.decider.SysctlChanges(in)
+	partial := c.opts.PartialReload && sysctls != nil
+	if in.tunedRunning && !partial && !c.windowOpen(tuned) {
+		// Writing sysctls is not disruptive, a full reload is
+		c.windowQueue(tuned, in.recommendedProfile, force)
+		return nil
+	}
+
+	key := reloadKey(in.recommendedProfile, in.contentHash)
+	if d := c.breaker.backoff(key); in.tunedRunning && d > 0 {
+		klog.V(1).Infof("not reloading tuned: %s, but backing off for %v after failures", reason, d)
+		tuned.change.retry = true
+		return nil
+	}
+	c.windowDequeue(tuned)
+	klog.V(1).Infof("reloading tuned: %s", reason)
+	c.sysctlConflictsUpdate(in.recommendedProfile)
+	if err := c.pristineRecord(in.recommendedProfile); err != nil {
+		klog.Errorf("failed to record the pristine state: %v", err)
+	}
+
+	c.breaker.reloaded(in.recommendedProfile, in.contentHash, c.opts.ReloadVerifyTimeout)
+	c.breaker.pending.trigger = trigger
+	c.breaker.pending.previousProfile = in.activeProfile
+	c.breaker.pending.span = span.Child("apply")
+	if partial {
+		s = span.Child("sysctl")
+		err = c.sysctlApply(sysctls)
+		s.SetError(err)
+		s.End()
+		if err == nil {
+			klog.Infof("only sysctls of profile %q changed, applied %d of them without reloading tuned", in.recommendedProfile, len(sysctls))
+			tuned.decider.Reloaded(in.contentHash, in.chain)
+			return c.reloadVerified(tuned)
+		}
+		klog.Warningf("%v; falling back to a full tuned reload", err)
+	}
+	s = span.Child("reload")
+	err = c.tunedReload()
+	s.SetError(err)
+	s.End()
+	if err != nil {
+		c.reloadFailed(err.Error())
+		return err
+	}
+	tuned.decider.Reloaded(in.contentHash, in.chain)
+	c.status.setState(stateApplying, fmt.Sprintf("%s, applying profile %q", reason, in.recommendedProfile))
+	c.rebootRequiredUpdate(tuned, in.recommendedProfile)
+	c.bootStateUpdate()
+
+	return nil
+}
+
+func getTuned(obj interface{}) (tuned *tunedv1.Tuned, err error) {
+	tuned, ok := obj.(*tunedv1.Tuned)
+	if !ok {
+		return nil, fmt.Errorf("could not convert object to a tuned object: %+v", obj)
+	}
+	return tuned, nil
+}
+
+func getTunedProfile(obj interface{}) (profile *tunedv1.Profile, err error) {
+	profile, ok := obj.(*tunedv1.Profile)
+	if !ok {
+		return nil, fmt.Errorf("could not convert object to a tuned profile object: %+v", obj)
+	}
+	return profile, nil
+}
+
+// apiEvent passes the handling of an informer event to the event loop, which
+// owns the tunedState; it is dropped once the informers of workers w stop.
+func (c *Controller) apiEvent(w *workers, f func(tuned *tunedState)) {
+	select {
+	case c.apiEventC <- f:
+	case <-w.stop:
+	}
+}
+
+func (c *Controller) profileEventHandler(w *workers) cache.ResourceEventHandlerFuncs {
+	return cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			p, err := getTunedProfile(obj)
+			if err != nil {
+				klog.Errorf("%s", err.Error())
+				return
+			}
+			klog.V(1).Infof("profile %q added, tuned profile requested: %s", p.ObjectMeta.Name, p.Spec.Config.TunedProfile)
+			// When moving this call elsewhere, remember it is undesirable to disable system tuned
+			// on nodes that should not be managed by openshift-tuned
+			if !c.opts.MockTuned {
+				disableSystemTuned(c.opts.ExecTimeout)
+			}
+			c.apiEvent(w, func(tuned *tunedState) {
+				c.profileRequestApply(tuned, p.ObjectMeta.Name, p.Spec.Config.TunedProfile)
+			})
+		},
+		UpdateFunc: func(objOld, objNew interface{}) {
+			pNew, err := getTunedProfile(objNew)
+			if err != nil {
+				klog.Errorf("%s", err.Error())
+				return
+			}
+			pOld, err := getTunedProfile(objOld)
+			if err != nil {
+				klog.Errorf("%s", err.Error())
+				return
+			}
+			if pNew.Spec.Config.TunedProfile == pOld.Spec.Config.TunedProfile {
+				return
+			}
+			klog.V(1).Infof("profile %q changed, tuned profile requested: %s", pNew.ObjectMeta.Name, pNew.Spec.Config.TunedProfile)
+			c.apiEvent(w, func(tuned *tunedState) {
+				c.profileRequested(tuned, pNew.ObjectMeta.Name, pNew.Spec.Config.TunedProfile)
+			})
+		},
+		DeleteFunc: func(obj interface{}) {
+			p, err := getTunedProfile(obj)
+			if err != nil {
+				klog.Errorf("%s", err.Error())
+				return
+			}
+			klog.V(1).Infof("profile %q deleted, keeping the old tuned profile: %s", p.ObjectMeta.Name, p.Spec.Config.TunedProfile)
+		},
+	}
+}
+
+func (c *Controller) tunedEventHandler(w *workers) cache.ResourceEventHandlerFuncs {
+	return cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			t, err := getTuned(obj)
+			if err != nil {
+				klog.Errorf("%s", err.Error())
+				return
+			}
+			klog.V(1).Infof("tuned %q added", t.ObjectMeta.Name)
+			err = c.profilesExtract(t.Spec.Profile)
+			if err != nil {
+				klog.Errorf("%s", err.Error())
+				return
+			}
+			c.apiEvent(w, func(tuned *tunedState) {
+				tuned.change.rendered = true
+			})
+		},
+		UpdateFunc: func(objOld, objNew interface{}) {
+			tNew, err := getTuned(objNew)
+			if err != nil {
+				klog.Errorf("%s", err.Error())
+				return
+			}
+			tOld, err := getTuned(objOld)
+			if err != nil {
+				klog.Errorf("%s", err.Error())
+				return
+			}
+			// TODO: after merging the operator and operand repos, do not check for equality
+			// and extract the profiles here; just enqueue the change and get the new object
+			// from cache
+			if reflect.DeepEqual(tNew.Spec.Profile, tOld.Spec.Profile) {
+				// Profiles in the cluster-wide "rendered" Tuned CR did not change
+				return
+			}
+			klog.V(1).Infof("tuned %q changed", tNew.ObjectMeta.Name)
+			err = c.profilesExtract(tNew.Spec.Profile)
+			if err != nil {
+				klog.Errorf("%s", err.Error())
+				return
+			}
+			c.apiEvent(w, func(tuned *tunedState) {
+				tuned.change.rendered = true
+			})
+		},
+		DeleteFunc: func(obj interface{}) {
+			t, err := getTuned(obj)
+			if err != nil {
+				klog.Errorf("%s", err.Error())
+				return
+			}
+			klog.V(1).Infof("tuned %q deleted, keeping the old tuned profile", t.ObjectMeta.Name)
+		},
+	}
+}
+
+// apiWatch creates the API clients of tuned and starts the informers of the Profile
+// and the rendered Tuned of the node and of the node itself as workers w.
+func (c *Controller) apiWatch(tuned *tunedState, w *workers) (err error) {
+	var (
+		profileFS fields.Selector = fields.SelectorFromSet(fields.Set{"metadata.name": tuned.nodeName})
+		tunedFS   fields.Selector = fields.SelectorFromSet(fields.Set{"metadata.name": tunedv1.TunedRenderedResourceName})
+	)
+
+	kubeConfig := c.kubeConfig
+	if kubeConfig == nil {
+		if kubeConfig, err = getConfig(c.opts.KubeConfig); err != nil {
+			return errExit(ExitConfig, err)
+		}
+	}
+
+	cs, err := tunedclientset.NewForConfig(kubeConfig)
+	if err != nil {
+		return errExit(ExitConfig, err)
+	}
+
+	coreClient, err := newCoreClient(kubeConfig)
+	if err != nil {
+		return errExit(ExitConfig, err)
+	}
+	if err = nodeValidate(coreClient, tuned.nodeName); err != nil {
+		return err
+	}
+	tuned.coreClient = coreClient
+	c.notifiersClientSet(coreClient)
+
+	// Perform an initial list and start a watch on Profiles in operand namespace
+	profileLW := cache.NewListWatchFromClient(cs.TunedV1().RESTClient(), "Profiles", operandNamespace, profileFS)
+	tunedLW := cache.NewListWatchFromClient(cs.TunedV1().RESTClient(), "Tuneds", operandNamespace, tunedFS)
+
+	siProfile := cache.NewSharedInformer(profileLW, &tunedv1.Profile{}, 0)
+	siProfile.AddEventHandler(c.profileEventHandler(w))
+	w.run(siProfile.Run)
+
+	siTuned := cache.NewSharedInformer(tunedLW, &tunedv1.Tuned{}, 0)
+	siTuned.AddEventHandler(c.tunedEventHandler(w))
+	w.run(siTuned.Run)
+
+	// Watch the node for cordoning and the maintenance window annotation
+	nodeLW := cache.NewListWatchFromClient(tuned.coreClient, "nodes", "", profileFS)
+	siNode := cache.NewSharedInformer(nodeLW, &corev1.Node{}, 0)
+	siNode.AddEventHandler(c.nodeEventHandler(w))
+	w.run(siNode.Run)
+
+	if len(c.opts.OperandConfigMap) > 0 && c.opts.OnOperandConfigChange != nil {
+		c.operandConfigWatch(coreClient, w)
+	}
+	if c.opts.NodeCondition || c.degradedTaint != nil {
+		w.run(func(stop <-chan struct{}) {
+			c.nodeStatusRun(coreClient, tuned.nodeName, stop)
+		})
+	}
+
+	return nil
+}
+
+func (c *Controller) changeWatcher() (err error) {
+	var (
+		tuned    tunedState
+		nodeName string = c.opts.NodeName
+	)
+
+	c.appliedLoad(&tuned)
+	err = c.pipeline.do("extract", func() error {
+		return c.profilesSync(nil)
+	})
+	if err == errSourcesSettling {
+		tuned.change.cfg = true
+	} else if err != nil {
+		return err
+	}
+
+	// Deferred first, so that the listener and watchers are closed before waiting
+	w := newWorkers()
+	defer w.stopWait()
+	w.run(c.hooksWorker)
+
+	tuned.nodeName = nodeName
+	var attach, poll loopTimer
+	defer attach.stop()
+	defer poll.stop()
+	if c.opts.Standalone && !c.attached {
+		// No Profile to wait for; start tuned with the profile the local recommend.d rules select
+		klog.Infof("running standalone, not watching the Kubernetes API")
+		c.status.setStandalone(true)
+		tuned.change.profile = true
+		attach.arm(c.attachInterval())
+	} else if err = c.apiWatch(&tuned, w); err != nil {
+		return err
+	}
+
+	// Create a ticker to extract new profiles and possibly reload tuned;
+	// this also rate-limits reloads to a maximum of profileExtractInterval reloads/s
+	tickerReload := time.NewTicker(time.Second * time.Duration(profileExtractInterval))
+	defer tickerReload.Stop()
+
+	// Poll the remote profile sources, if any
+	poll.arm(c.sourcesPollInterval())
+
+	// Watch for filesystem changes on tuned profiles and recommend.conf file(s)
+	wFs, err := fsnotify.NewWatcher()
+	if err != nil {
+		return errCategorize(errCategoryFS, fmt.Errorf("failed to create filesystem watcher: %v", err))
+	}
+	defer wFs.Close()
+
+	// Register fsnotify watchers
+	if err = c.watchesAdd(wFs); err != nil {
+		return err
+	}
+
+	// Clients other than root need to connect to be authenticated, see sockAllow
+	sockPerm := os.FileMode(0600)
+	if len(c.opts.SocketAllow) > 0 {
+		sockPerm = 0666
+	}
+	l, err := newUnixListener(c.opts.Socket, sockPerm)
+	if err != nil {
+		return errCategorize(errCategoryFS, fmt.Errorf("cannot create %q listener: %v", c.opts.Socket, err))
+	}
+	defer l.Close()
+
+	sockConns := make(chan sockAccepted, 1)
+	w.run(func(stop <-chan struct{}) {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				// The event loop returns on accept errors, or returned and closed
+				// the listener
+				select {
+				case sockConns <- sockAccepted{err: err}:
+				case <-stop:
+				}
+				return
+			}
+			w.run(func(stop <-chan struct{}) {
+				c.sockServe(conn, sockConns, stop)
+			})
+		}
+	})
+
+	c.watchdog.tick()
+	defer c.watchdog.disarm()
+	sdNotify(systemd.Ready)
+
+	for {
+		select {
+		case <-c.done:
+			// Termination signal received, stop
+			klog.V(2).Infof("changeWatcher done")
+			c.terminating("termination signal received")
+			if err := c.tunedStop(nil, !c.opts.NoRollbackOnExit); err != nil {
+				klog.Errorf("%s", err.Error())
+			}
+			return nil
+
+		case s := <-sockConns:
+			if s.err != nil {
+				return fmt.Errorf("connection accept error: %v", s.err)
+			}
+
+			if c.sockHandle(&s, &tuned) {
+				return nil
+			}
+
+		case <-c.tunedExit:
+			if c.breaker.pending != nil && c.reloadFailed("tuned process exited") {
+				err := c.pipeline.do("fallback", func() error {
+					return c.reloadFallback(&tuned)
+				})
+				if err != nil {
+					klog.Errorf("%s", err.Error())
+				}
+			}
+			c.runner.Reset()
+			return errCategorize(errCategoryExec, fmt.Errorf("tuned process exitted"))
+
+		case fsEvent := <-wFs.Events:
+			klog.V(2).Infof("fsEvent")
+			if len(c.opts.ConfigFile) > 0 && filepath.Dir(fsEvent.Name) == filepath.Dir(c.opts.ConfigFile) {
+				if c.opts.OnConfigChange != nil {
+					c.reconfigure(c.opts.OnConfigChange(), &tuned, wFs, &attach, &poll)
+				}
+				continue
+			}
+			watchDirEvent(wFs, fsEvent)
+			c.watchEvent(&tuned, fsEvent)
+
+		case err := <-wFs.Errors:
+			return errCategorize(errCategoryFS, fmt.Errorf("error watching filesystem: %v", err))
+
+		case <-poll.C:
+			klog.V(2).Infof("pollC")
+			tuned.change.cfg = true
+
+		case f := <-c.apiEventC:
+			f(&tuned)
+
+		case data := <-c.operandConfigC:
+			klog.V(2).Infof("operandConfigC")
+			c.reconfigure(c.opts.OnOperandConfigChange(data), &tuned, wFs, &attach, &poll)
+
+		case <-attach.C:
+			klog.V(2).Infof("attach.C")
+			if c.apiAttach(&tuned, w) {
+				attach.stop()
+			}
+
+		case <-tickerReload.C:
+			klog.V(2).Infof("tickerReload.C")
+			c.watchdog.tick()
+			c.revertCheck(&tuned)
+			c.watchHooksFlush(&tuned)
+			err := c.pipeline.do("reload", func() error {
+				return c.timedTunedReloader(&tuned)
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (c *Controller) retryLoop() (err error) {
+	const (
+		errsMax = 5 // the maximum number of consecutive errors within errsMaxWithin
+	)
+	var (
+		errs       int
+		sleepRetry = c.opts.RetryInitial
+		dedup      errDedup
+		// sum of the geometric series of the retry periods: S_n = x(1)*(q^n-1)/(q-1),
+		// or x(1)*n for q=1; add 60s for each changeWatcher() call
+		errsMaxWithin = time.Duration(float64(sleepRetry)*float64(errsMax)) + errsMax*time.Minute
+	)
+	if q := c.opts.RetryFactor; q > 1 {
+		errsMaxWithin = time.Duration(float64(sleepRetry)*(math.Pow(q, errsMax)-1)/(q-1)) + errsMax*time.Minute
+	}
+	errsTimeStart := time.Now()
+	defer dedup.flush()
+	for {
+		err = c.changeWatcher()
+		if err == nil {
+			break
+		}
+
+		select {
+		case <-c.done:
+			return err
+		default:
+		}
+
+		dedup.report(err)
+		c.status.countError(errorCategory(err))
+		if errorFatal(err) {
+			klog.Errorf("%s error, retrying does not help, terminating...", errorCategory(err))
+			break
+		}
+		if sleepRetry < c.opts.RetryMax {
+			sleepRetry = c.retryNext(sleepRetry)
+			klog.V(1).Infof("increased retry period to %v", sleepRetry)
+		}
+		if isTransient(err) {
+			// Never give up on an unreachable apiserver, just report it
+			c.status.setState(stateDegraded, err.Error())
+		} else if errs++; errs >= errsMax {
+			if since := time.Since(errsTimeStart); since <= errsMaxWithin {
+				// Terminating would only make the pod crash-loop; keep tuned running
+				// the last applied profile and keep retrying
+				msg := fmt.Sprintf("seen %d errors in %v (limit was %v), retrying every %v: %v", errs, since.Round(time.Second), errsMaxWithin, c.opts.RetryMax, err)
+				klog.Errorf("%s", msg)
+				c.status.setState(stateDegraded, msg)
+				sleepRetry = c.opts.RetryMax
+			} else {
+				sleepRetry = c.opts.RetryInitial
+				klog.V(1).Infof("initialized retry period to %v", sleepRetry)
+			}
+			errs = 0
+			errsTimeStart = time.Now()
+		}
+
+		select {
+		case <-c.done:
+			return nil
+		case <-time.After(c.retryJittered(sleepRetry)):
+			continue
+		}
+	}
+	return err
+}
+
+// Run runs tuned and reloads it on changes until ctx is cancelled, Stop() is
+// called or a fatal error occurs, see errorFatal(); other errors are retried
+// forever.  See ExitCode() for the exit code matching
+// the error returned.
+func (c *Controller) Run(ctx context.Context) error {
+	if err := process.PlatformCheck(); err != nil {
+		return err
+	}
+	forwardSignals, err := forwardSignalsParse(c.opts.ForwardSignals)
+	if err != nil {
+		return errExit(ExitConfig, err)
+	}
+	if err := c.sourcesInit(); err != nil {
+		return errExit(ExitConfig, err)
+	}
+	if c.sockAllow, err = sockAllowParse(c.opts.SocketAllow); err != nil {
+		return errExit(ExitConfig, err)
+	}
+	if err := c.featuresInit(); err != nil {
+		return errExit(ExitConfig, err)
+	}
+	switch c.opts.ActiveProfileSource {
+	case activeProfileSourceFile, activeProfileSourceTunedAdm:
+	default:
+		return errExit(ExitConfig, fmt.Errorf("unknown active profile source %q, expected %s or %s",
+			c.opts.ActiveProfileSource, activeProfileSourceFile, activeProfileSourceTunedAdm))
+	}
+	if err := c.hooksInit(); err != nil {
+		return errExit(ExitConfig, err)
+	}
+	if err := c.notifiersInit(); err != nil {
+		return errExit(ExitConfig, err)
+	}
+	if c.watches, err = watchFilesParse(c.opts.WatchFiles); err != nil {
+		return errExit(ExitConfig, err)
+	}
+	if c.drain, err = drainActionParse(c.opts.DrainAction); err != nil {
+		return errExit(ExitConfig, err)
+	}
+	if c.nfdVariables, err = nfdVariablesParse(c.opts.NFDVariables); err != nil {
+		return errExit(ExitConfig, err)
+	}
+	if c.degradedTaint, err = taintParse(c.opts.DegradedTaint); err != nil {
+		return errExit(ExitConfig, err)
+	}
+	if err := c.retryValidate(); err != nil {
+		return errExit(ExitConfig, err)
+	}
+	if len(c.opts.MaintenanceWindow) > 0 {
+		if c.window, err = schedule.Parse(c.opts.MaintenanceWindow); err != nil {
+			return errExit(ExitConfig, err)
+		}
+		c.status.setMaintenanceWindow(c.window)
+	}
+	if err := c.preflight(); err != nil {
+		return err
+	}
+	if err := c.pidFileWrite(); err != nil {
+		return errExit(ExitRunDir, err)
+	}
+	if err := c.auditInit(); err != nil {
+		return errExit(ExitRunDir, err)
+	}
+	defer c.auditLog.Close()
+	if c.tunedOutput != nil {
+		defer c.tunedOutput.Close()
+	}
+	if err := c.historyLoad(); err != nil {
+		klog.Errorf("%s", err.Error())
+	}
+	if c.opts.Handoff {
+		if err := c.handoffTake(); err != nil {
+			klog.Warningf("cannot take over tuned from the previous %s instance, starting a new tuned: %v", programName, err)
+		}
+	}
+
+	if c.opts.APIPort > 0 {
+		c.apiServe(c.opts.APIPort)
+	}
+
+	finished := make(chan struct{})
+	defer close(finished)
+	if c.opts.WatchdogTimeout > 0 {
+		go c.watchdogRun(finished)
+	}
+	if c.opts.Subreaper {
+		if err := process.SetSubreaper(); err != nil {
+			klog.Errorf("failed to become a child subreaper: %v", err)
+		}
+	}
+	if process.ReaperNeeded() {
+		go process.Reap(finished)
+	}
+	if len(forwardSignals) > 0 {
+		go c.signalsForward(forwardSignals, finished)
+	}
+	sdTimeout, err := systemd.WatchdogEnabled()
+	if err != nil {
+		klog.Errorf("%s", err.Error())
+	}
+	if sdTimeout > 0 {
+		go c.sdWatchdogRun(sdTimeout/2, finished)
+	}
+	go func() {
+		select {
+		case <-ctx.Done():
+			c.Stop()
+		case <-finished:
+		}
+	}()
+
+	if err := c.retryLoop(); err != nil || !c.handedOff {
+		return err
+	}
+	// Leave tuned running; exiting would make the container restart and start
+	// another tuned
+	klog.Infof("tuned handed over, waiting for termination")
+	<-c.done
+
+	return nil
+}
+
+// terminating records that openshift-tuned is stopping tuned because of reason.
+func (c *Controller) terminating(reason string) {
+	c.status.setState(stateTerminating, reason)
+	sdNotify(systemd.Stopping)
+}
+
+// Stop stops tuned and makes Run() return.  tuned rolls back the node-level
+// tuning unless Options.NoRollbackOnExit is set, in which case it is killed and
+// the tuning stays in place as with the "stop-norollback" socket command.  A
+// tuned handed over to another openshift-tuned is left running.
+func (c *Controller) Stop() {
+	select {
+	case c.done <- true:
+	default:
+		// Already stopping
+	}
+}
@@ -0,0 +1,85 @@
+package tuned
+
+import (
+	"strings" // strings.Contains()
+
+	"github.com/openshift/openshift-tuned/pkg/profile"
+)
+
+// Types
+// reloadInputs are all the inputs of a tuned reload decision.
+type reloadInputs struct {
+	// is tuned running?
+	tunedRunning bool
+	// profile tuned reports as active
+	activeProfile string
+	// profile recommended by tuned
+	recommendedProfile string
+	// does the recommended profile exist among the extracted profiles?
+	recommendedExists bool
+	// hash of the content of the recommended profile and its includes; empty if unknown
+	contentHash string
+	// the recommended profile and its includes; nil if unknown
+	chain []profile.Conf
+}
+
+// ReloadDecider decides whether tuned needs to be reloaded.  Tuned is reloaded only
+// when it would apply a different profile or different profile content than the
+// last time it was (re)loaded; changes of profiles the recommended profile does not
+// include do not cause a reload.
+type ReloadDecider struct {
+	// hash of the profile content tuned was last (re)loaded with
+	appliedHash string
+	// profile content tuned was last (re)loaded with
+	appliedChain []profile.Conf
+}
+
+// Functions
+// Decide returns whether to reload tuned and the reason for the decision.
+func (d *ReloadDecider) Decide(in reloadInputs) (bool, string) {
+	if !in.tunedRunning {
+		return true, "tuned is not running"
+	}
+	if in.activeProfile != in.recommendedProfile {
+		if !in.recommendedExists {
+			// Workaround for tuned BZ1774645; do not send SIGHUP to tuned if the profile directory doesn't exist
+			return false, "recommended profile " + in.recommendedProfile + " does not exist"
+		}
+		return true, "active profile " + in.activeProfile + " != recommended profile " + in.recommendedProfile
+	}
+	if len(in.contentHash) == 0 {
+		return true, "content of profile " + in.recommendedProfile + " unknown"
+	}
+	if in.contentHash != d.appliedHash {
+		return true, "content of profile " + in.recommendedProfile + " changed"
+	}
+	return false, "active and recommended profile " + in.activeProfile + " match and its content did not change"
+}
+
+// SysctlChanges returns the sysctls to write to apply the content of the
+// recommended profile without reloading tuned, or nil if tuned needs a full
+// reload.  This is the case unless tuned runs the recommended profile and only
+// sysctl values changed since it was last (re)loaded.
+func (d *ReloadDecider) SysctlChanges(in reloadInputs) map[string]string {
+	if !in.tunedRunning || in.activeProfile != in.recommendedProfile || d.appliedChain == nil || in.chain == nil {
+		return nil
+	}
+	sysctls, ok := profile.ChainSysctlChanges(d.appliedChain, in.chain)
+	if !ok || len(sysctls) == 0 {
+		return nil
+	}
+	for _, value := range sysctls {
+		if strings.Contains(value, "${") {
+			// Variables are expanded by tuned
+			return nil
+		}
+	}
+	return sysctls
+}
+
+// Reloaded records that tuned was (re)loaded with profile content chain of hash
+// contentHash.
+func (d *ReloadDecider) Reloaded(contentHash string, chain []profile.Conf) {
+	d.appliedHash = contentHash
+	d.appliedChain = chain
+}
@@ -0,0 +1,145 @@
+package tuned
+
+import (
+	"reflect" // reflect.DeepEqual()
+	"testing"
+
+	"github.com/openshift/openshift-tuned/pkg/profile"
+)
+
+func TestReloadDeciderDecide(t *testing.T) {
+	tests := []struct {
+		name        string
+		appliedHash string
+		in          reloadInputs
+		want        bool
+	}{
+		{
+			name: "tuned not running",
+			in:   reloadInputs{activeProfile: "a", recommendedProfile: "a", recommendedExists: true, contentHash: "h"},
+			want: true,
+		},
+		{
+			name: "recommended profile changed",
+			in:   reloadInputs{tunedRunning: true, activeProfile: "a", recommendedProfile: "b", recommendedExists: true, contentHash: "h"},
+			want: true,
+		},
+		{
+			name: "recommended profile not extracted",
+			in:   reloadInputs{tunedRunning: true, activeProfile: "a", recommendedProfile: "b", contentHash: "h"},
+			want: false,
+		},
+		{
+			name: "content unknown",
+			in:   reloadInputs{tunedRunning: true, activeProfile: "a", recommendedProfile: "a", recommendedExists: true},
+			want: true,
+		},
+		{
+			name:        "content changed",
+			appliedHash: "h1",
+			in:          reloadInputs{tunedRunning: true, activeProfile: "a", recommendedProfile: "a", recommendedExists: true, contentHash: "h2"},
+			want:        true,
+		},
+		{
+			name:        "nothing changed",
+			appliedHash: "h",
+			in:          reloadInputs{tunedRunning: true, activeProfile: "a", recommendedProfile: "a", recommendedExists: true, contentHash: "h"},
+			want:        false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := &ReloadDecider{appliedHash: tt.appliedHash}
+			got, reason := d.Decide(tt.in)
+			if got != tt.want {
+				t.Errorf("Decide() = %v (%s), want %v", got, reason, tt.want)
+			}
+			if len(reason) == 0 {
+				t.Errorf("Decide() returned no reason")
+			}
+		})
+	}
+}
+
+func TestReloadDeciderSysctlChanges(t *testing.T) {
+	const base = "[main]\nsummary=base\n[sysctl]\nvm.swappiness=10\n"
+
+	tests := []struct {
+		name    string
+		applied string
+		current string
+		running bool
+		want    map[string]string
+	}{
+		{
+			name:    "sysctl value changed",
+			applied: "[main]\ninclude=base\n[sysctl]\nnet.core.somaxconn=1024\n",
+			current: "[main]\ninclude=base\n[sysctl]\nnet.core.somaxconn=2048\n",
+			running: true,
+			want:    map[string]string{"net.core.somaxconn": "2048"},
+		},
+		{
+			name:    "sysctl key in the slash form",
+			applied: "[main]\ninclude=base\n[sysctl]\nnet/core/somaxconn=1024\n",
+			current: "[main]\ninclude=base\n[sysctl]\nnet/core/somaxconn=2048\n",
+			running: true,
+			want:    map[string]string{"net.core.somaxconn": "2048"},
+		},
+		{
+			name:    "other plugin changed",
+			applied: "[main]\ninclude=base\n[vm]\ntransparent_hugepages=always\n",
+			current: "[main]\ninclude=base\n[vm]\ntransparent_hugepages=never\n",
+			running: true,
+		},
+		{
+			name:    "sysctl added",
+			applied: "[main]\ninclude=base\n[sysctl]\nnet.core.somaxconn=1024\n",
+			current: "[main]\ninclude=base\n[sysctl]\nnet.core.somaxconn=1024\nkernel.pid_max=65536\n",
+			running: true,
+		},
+		{
+			name:    "variable in the value",
+			applied: "[main]\ninclude=base\n[sysctl]\nnet.core.somaxconn=1024\n",
+			current: "[main]\ninclude=base\n[sysctl]\nnet.core.somaxconn=${somaxconn}\n",
+			running: true,
+		},
+		{
+			name:    "tuned not running",
+			applied: "[main]\ninclude=base\n[sysctl]\nnet.core.somaxconn=1024\n",
+			current: "[main]\ninclude=base\n[sysctl]\nnet.core.somaxconn=2048\n",
+		},
+	}
+
+	chainLoad := func(t *testing.T, data string) []profile.Conf {
+		store := profile.NewMemStore()
+		store.WriteProfiles(map[string]string{"base": base, "p": data})
+		chain, err := profile.ChainLoad(store, "p")
+		if err != nil {
+			t.Fatalf("ChainLoad() error = %v", err)
+		}
+		return chain
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := &ReloadDecider{}
+			d.Reloaded("h1", chainLoad(t, tt.applied))
+			in := reloadInputs{
+				tunedRunning:       tt.running,
+				activeProfile:      "p",
+				recommendedProfile: "p",
+				recommendedExists:  true,
+				contentHash:        "h2",
+				chain:              chainLoad(t, tt.current),
+			}
+			got := d.SysctlChanges(in)
+			if len(got) == 0 && len(tt.want) == 0 {
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("SysctlChanges() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,148 @@
+package tuned
+
+import (
+	"fmt"     // Errorf()
+	"strings" // strings.HasPrefix()
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog"
+)
+
+// Types
+// drainAction is what openshift-tuned does while the node is cordoned or drained,
+// see Options.DrainAction.
+type drainAction struct {
+	// defer reloads until the node is uncordoned
+	deferReloads bool
+	// tuned profile to apply while the node is draining; empty keeps the current one
+	profile string
+}
+
+// Constants
+const (
+	drainActionDefer   = "defer"
+	drainActionProfile = "profile:"
+	// taint the node lifecycle controller sets on cordoned nodes
+	taintNodeUnschedulable = "node.kubernetes.io/unschedulable"
+)
+
+// Functions
+// drainActionParse parses Options.DrainAction: empty (ignore draining), "defer"
+// or "profile:<name>".
+func drainActionParse(s string) (drainAction, error) {
+	switch {
+	case len(s) == 0:
+		return drainAction{}, nil
+	case s == drainActionDefer:
+		return drainAction{deferReloads: true}, nil
+	case strings.HasPrefix(s, drainActionProfile) && len(s) > len(drainActionProfile):
+		return drainAction{profile: strings.TrimPrefix(s, drainActionProfile)}, nil
+	}
+	return drainAction{}, fmt.Errorf("invalid drain action %q, expected %s or %s<name>", s, drainActionDefer, drainActionProfile)
+}
+
+// nodeDraining returns true if node is cordoned, e.g. being drained for an upgrade.
+func nodeDraining(node *corev1.Node) bool {
+	if node.Spec.Unschedulable {
+		return true
+	}
+	for _, t := range node.Spec.Taints {
+		if t.Key == taintNodeUnschedulable {
+			return true
+		}
+	}
+	return false
+}
+
+// drainUpdate handles the change of the draining state of the node.
+func (c *Controller) drainUpdate(tuned *tunedState, draining bool) {
+	if draining == tuned.draining {
+		return
+	}
+	tuned.draining = draining
+	c.status.setDraining(draining)
+
+	if !draining {
+		klog.Infof("node %q uncordoned, resuming tuning", tuned.nodeName)
+		if len(c.drain.profile) > 0 {
+			c.status.RLock()
+			requested := c.status.requestedProfile
+			c.status.RUnlock()
+			if len(requested) > 0 {
+				if err := c.tunedRecommendFileWrite(requested); err != nil {
+					klog.Errorf("%s", err.Error())
+					return
+				}
+			}
+		}
+		tuned.change.profile = true
+		return
+	}
+
+	if len(c.drain.profile) > 0 {
+		klog.Infof("node %q is draining, switching to profile %q", tuned.nodeName, c.drain.profile)
+		if err := c.tunedRecommendFileWrite(c.drain.profile); err != nil {
+			klog.Errorf("%s", err.Error())
+			return
+		}
+		tuned.change.profile = true
+		return
+	}
+	klog.Infof("node %q is draining, deferring tuned reloads until it is uncordoned", tuned.nodeName)
+}
+
+// drainDeferred returns true if tuned must not be reloaded because the node is
+// draining.
+func (c *Controller) drainDeferred(tuned *tunedState) bool {
+	return tuned.draining && c.drain.deferReloads
+}
+
+// drainProfileActive returns true if the maintenance profile replaces the
+// requested one.
+func (c *Controller) drainProfileActive(tuned *tunedState) bool {
+	return tuned.draining && len(c.drain.profile) > 0
+}
+
+func getNode(obj interface{}) (node *corev1.Node, err error) {
+	node, ok := obj.(*corev1.Node)
+	if !ok {
+		return nil, fmt.Errorf("could not convert object to a node object: %+v", obj)
+	}
+	return node, nil
+}
+
+// nodeUpdate handles a change of the node openshift-tuned runs on; it runs on
+// the event loop, see apiEvent().
+func (c *Controller) nodeUpdate(tuned *tunedState, node *corev1.Node) {
+	if len(c.opts.DrainAction) > 0 {
+		c.drainUpdate(tuned, nodeDraining(node))
+	}
+	c.windowUpdate(tuned, node)
+	c.nfdUpdate(tuned, node)
+}
+
+func (c *Controller) nodeEventHandler(w *workers) cache.ResourceEventHandlerFuncs {
+	return cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			node, err := getNode(obj)
+			if err != nil {
+				klog.Errorf("%s", err.Error())
+				return
+			}
+			c.apiEvent(w, func(tuned *tunedState) {
+				c.nodeUpdate(tuned, node)
+			})
+		},
+		UpdateFunc: func(objOld, objNew interface{}) {
+			node, err := getNode(objNew)
+			if err != nil {
+				klog.Errorf("%s", err.Error())
+				return
+			}
+			c.apiEvent(w, func(tuned *tunedState) {
+				c.nodeUpdate(tuned, node)
+			})
+		},
+	}
+}
@@ -0,0 +1,24 @@
+package tuned
+
+import (
+	"io/ioutil" // ioutil.ReadFile()
+
+	"github.com/openshift/openshift-tuned/pkg/profile"
+)
+
+// Functions
+// EffectiveProfile returns the effective configuration of tuned profile
+// profileName, or of the active profile if profileName is empty.
+func (c *Controller) EffectiveProfile(profileName string) profile.Effective {
+	if len(profileName) == 0 {
+		active, err := c.store.ActiveProfile()
+		if err != nil {
+			return profile.Effective{Error: err.Error()}
+		}
+		profileName = active
+	}
+	return profile.ChainEffective(c.store, profileName, func(path string) (string, error) {
+		data, err := ioutil.ReadFile(path)
+		return string(data), err
+	})
+}
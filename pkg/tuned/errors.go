@@ -0,0 +1,175 @@
+package tuned
+
+import (
+	"net"     // net.Error
+	"net/url" // url.Error
+	"os"      // os.PathError
+	"os/exec" // exec.Error
+	"time"    // time.Time
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/klog"
+
+	"github.com/openshift/openshift-tuned/pkg/process"
+)
+
+// Types
+// transientError is an error openshift-tuned can recover from by retrying, e.g.
+// an apiserver which is temporarily unreachable.
+type transientError struct {
+	err error
+}
+
+// categoryError is an error of a known category, see errorCategory().
+type categoryError struct {
+	category string
+	err      error
+}
+
+// errDedup deduplicates repeated errors in the log.
+type errDedup struct {
+	last  string
+	count int
+	since time.Time
+}
+
+// Constants
+// Error categories, see errorCategory().
+const (
+	// the apiserver returned an error or could not be reached
+	errCategoryAPI = "api"
+	// tuned or tuned-adm could not be run or failed
+	errCategoryExec = "exec"
+	// files or sockets could not be read, written or watched
+	errCategoryFS = "filesystem"
+	// invalid options or kubeconfig; retrying does not help
+	errCategoryConfig = "config"
+	errCategoryOther  = "other"
+)
+
+// Functions
+func (e *transientError) Error() string {
+	return e.err.Error()
+}
+
+// Unwrap returns the underlying error.
+func (e *transientError) Unwrap() error {
+	return e.err
+}
+
+func (e *categoryError) Error() string {
+	return e.err.Error()
+}
+
+// Unwrap returns the underlying error.
+func (e *categoryError) Unwrap() error {
+	return e.err
+}
+
+// errCategorize returns err marked as an error of category.
+func errCategorize(category string, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &categoryError{category: category, err: err}
+}
+
+// errorCategory returns the category of err: the category it was marked with by
+// errCategorize() or errExit(), or the category of the underlying error.
+func errorCategory(err error) string {
+	for err != nil {
+		switch e := err.(type) {
+		case *categoryError:
+			return e.category
+		case *transientError:
+			return errCategoryAPI
+		case *exitError:
+			switch e.code {
+			case ExitConfig:
+				return errCategoryConfig
+			case ExitAPIUnreachable:
+				return errCategoryAPI
+			case ExitTunedMissing:
+				return errCategoryExec
+			case ExitRunDir:
+				return errCategoryFS
+			}
+			err = e.err
+		case *process.MissingError, *exec.Error, *exec.ExitError:
+			return errCategoryExec
+		case *os.PathError, *os.LinkError, *os.SyscallError:
+			return errCategoryFS
+		case apierrors.APIStatus, *url.Error:
+			return errCategoryAPI
+		default:
+			return errCategoryOther
+		}
+	}
+	return errCategoryOther
+}
+
+// errorFatal returns true if retrying cannot recover from err.
+func errorFatal(err error) bool {
+	return errorCategory(err) == errCategoryConfig
+}
+
+// errTransient returns err marked as transient if apiErr, the cause of err, is
+// a transient apiserver or network error.
+func errTransient(err error, apiErr error) error {
+	if err == nil || !apiErrorTransient(apiErr) {
+		return err
+	}
+	return &transientError{err: err}
+}
+
+// isTransient returns true if err was marked as transient by errTransient().
+func isTransient(err error) bool {
+	_, ok := err.(*transientError)
+	return ok
+}
+
+// apiErrorTransient returns true if err is an apiserver or network error that
+// goes away once the apiserver becomes available.
+func apiErrorTransient(err error) bool {
+	if err == nil {
+		return false
+	}
+	if apierrors.IsServerTimeout(err) || apierrors.IsTimeout(err) ||
+		apierrors.IsTooManyRequests(err) || apierrors.IsServiceUnavailable(err) ||
+		apierrors.IsInternalError(err) || apierrors.IsUnexpectedServerError(err) {
+		return true
+	}
+	if ue, ok := err.(*url.Error); ok {
+		err = ue.Err
+	}
+	_, ok := err.(net.Error)
+	return ok
+}
+
+// report logs err unless it is the same as the previous error.  Repeated errors
+// are logged with their occurrence count at exponentially growing intervals.
+func (d *errDedup) report(err error) {
+	msg := err.Error()
+	if msg != d.last {
+		d.flush()
+		d.last = msg
+		d.count = 1
+		d.since = time.Now()
+		klog.Errorf("%s", msg)
+		return
+	}
+	d.count++
+	if d.count&(d.count-1) == 0 {
+		// A power of two
+		klog.Errorf("%s (seen %d times since %s)", msg, d.count, d.since.Format(time.RFC3339))
+	}
+}
+
+// flush logs the occurrence count of the last error not logged yet and forgets it.
+func (d *errDedup) flush() {
+	if d.count > 1 && d.count&(d.count-1) != 0 {
+		klog.Errorf("%s (seen %d times since %s)", d.last, d.count, d.since.Format(time.RFC3339))
+	}
+	d.last = ""
+	d.count = 0
+}
@@ -0,0 +1,67 @@
+package tuned
+
+import (
+	"github.com/openshift/openshift-tuned/pkg/process"
+)
+
+// Types
+// exitError is an error terminating openshift-tuned with a specific exit code.
+type exitError struct {
+	code int
+	err  error
+}
+
+// Constants
+// Exit codes of openshift-tuned, for the DaemonSet and the operator to tell why it
+// terminated.
+const (
+	// ExitOK: terminated on request
+	ExitOK = 0
+	// ExitFailure: an unexpected error
+	ExitFailure = 1
+	// ExitConfig: invalid options, kubeconfig or node name
+	ExitConfig = 2
+	// ExitAPIUnreachable: the apiserver could not be reached
+	ExitAPIUnreachable = 3
+	// ExitTunedMissing: the tuned binaries are missing
+	ExitTunedMissing = 4
+	// ExitRunDir: the runtime files could not be written
+	ExitRunDir = 5
+	// ExitCapabilities: required capabilities are missing, see CapabilitiesCheck()
+	ExitCapabilities = 6
+)
+
+// Functions
+func (e *exitError) Error() string {
+	return e.err.Error()
+}
+
+// Unwrap returns the underlying error.
+func (e *exitError) Unwrap() error {
+	return e.err
+}
+
+// errExit returns err terminating openshift-tuned with exit code code.
+func errExit(code int, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &exitError{code: code, err: err}
+}
+
+// ExitCode returns the exit code of openshift-tuned terminated by err.
+func ExitCode(err error) int {
+	switch e := err.(type) {
+	case nil:
+		return ExitOK
+	case *exitError:
+		return e.code
+	case *transientError:
+		return ExitAPIUnreachable
+	case *process.MissingError:
+		return ExitTunedMissing
+	case *categoryError:
+		return ExitCode(e.err)
+	}
+	return ExitFailure
+}
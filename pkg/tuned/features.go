@@ -0,0 +1,36 @@
+package tuned
+
+import (
+	"github.com/openshift/openshift-tuned/pkg/featuregate"
+)
+
+// Constants
+const (
+	// featureRecommendCache caches the profile recommended by tuned, see
+	// recommendCached()
+	featureRecommendCache featuregate.Feature = "RecommendCache"
+	// featureCanaryProbes runs the canary probes of applied profiles, see
+	// canaryRun()
+	featureCanaryProbes featuregate.Feature = "CanaryProbes"
+	// featureStandaloneAttach switches a standalone openshift-tuned to the Profile
+	// of the node once the apiserver is reachable, see apiAttach()
+	featureStandaloneAttach featuregate.Feature = "StandaloneAttach"
+)
+
+// Functions
+// featuresKnown returns the feature gates of openshift-tuned and their defaults.
+// New subsystems are added disabled as Alpha, so that they can be enabled per
+// cluster by opts.FeatureGates before they are enabled by default.
+func featuresKnown() map[featuregate.Feature]featuregate.Spec {
+	return map[featuregate.Feature]featuregate.Spec{
+		featureRecommendCache:   {Default: true, Stage: featuregate.Beta},
+		featureCanaryProbes:     {Default: true, Stage: featuregate.Beta},
+		featureStandaloneAttach: {Default: true, Stage: featuregate.Beta},
+	}
+}
+
+// featuresInit sets the feature gates of opts.FeatureGates.
+func (c *Controller) featuresInit() error {
+	c.features = featuregate.New(featuresKnown())
+	return c.features.Set(c.opts.FeatureGates)
+}
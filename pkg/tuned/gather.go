@@ -0,0 +1,200 @@
+package tuned
+
+import (
+	"archive/tar"   // tar.NewWriter()
+	"compress/gzip" // gzip.NewWriter()
+	"encoding/json" // json.MarshalIndent()
+	"fmt"           // Errorf()
+	"io"            // io.Writer
+	"io/ioutil"     // ioutil.ReadDir()
+	"os"            // os.IsNotExist()
+	"path/filepath" // filepath.Join()
+	"strings"       // strings.Join()
+	"sync"          // sync.Mutex
+	"time"          // time.Now()
+)
+
+// Types
+// tunedLogRing keeps the last lines tuned logged for must-gather.
+type tunedLogRing struct {
+	sync.Mutex
+	lines []string
+}
+
+// gatherWriter writes the files of a must-gather tarball.
+type gatherWriter struct {
+	tw   *tar.Writer
+	time time.Time
+	// files which could not be collected, recorded in gatherErrorsFile
+	errors []string
+}
+
+// Constants
+const (
+	// top-level directory of the must-gather tarball
+	gatherDir = programName + "-must-gather"
+	// tuned log lines kept for must-gather
+	tunedLogLinesMax = 1000
+	// the tuned log file, collected if tuned logs to a file
+	tunedLogFile = "/var/log/tuned/tuned.log"
+	// the tail of the log files collected
+	gatherLogMax = 4 << 20
+	// the files that could not be collected
+	gatherErrorsFile = "errors.txt"
+)
+
+// Functions
+// add appends line to the ring, dropping the oldest line if full.
+func (r *tunedLogRing) add(line string) {
+	r.Lock()
+	defer r.Unlock()
+
+	if len(r.lines) >= tunedLogLinesMax {
+		r.lines = r.lines[1:]
+	}
+	r.lines = append(r.lines, line)
+}
+
+// get returns the lines in the ring, oldest first.
+func (r *tunedLogRing) get() []string {
+	r.Lock()
+	defer r.Unlock()
+
+	return append([]string{}, r.lines...)
+}
+
+func (g *gatherWriter) write(name string, data []byte) error {
+	hdr := &tar.Header{
+		Name:    gatherDir + "/" + name,
+		Mode:    0644,
+		Size:    int64(len(data)),
+		ModTime: g.time,
+	}
+	if err := g.tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	_, err := g.tw.Write(data)
+	return err
+}
+
+// writeJSON writes v as the JSON file name.
+func (g *gatherWriter) writeJSON(name string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		g.errors = append(g.errors, fmt.Sprintf("%s: %v", name, err))
+		return nil
+	}
+	return g.write(name, append(data, '\n'))
+}
+
+// writeFile writes the last max bytes of file path as name.  A missing file is
+// skipped.
+func (g *gatherWriter) writeFile(name, path string, max int64) error {
+	f, err := os.Open(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			g.errors = append(g.errors, fmt.Sprintf("%s: %v", name, err))
+		}
+		return nil
+	}
+	defer f.Close()
+	if fi, err := f.Stat(); err == nil && max > 0 && fi.Size() > max {
+		f.Seek(fi.Size()-max, io.SeekStart)
+	}
+	data, err := ioutil.ReadAll(f)
+	if err != nil {
+		g.errors = append(g.errors, fmt.Sprintf("%s: %v", name, err))
+		return nil
+	}
+	return g.write(name, data)
+}
+
+// writeDir writes the regular files in directory dir below name.
+func (g *gatherWriter) writeDir(name, dir string) error {
+	fis, err := ioutil.ReadDir(dir)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			g.errors = append(g.errors, fmt.Sprintf("%s: %v", name, err))
+		}
+		return nil
+	}
+	for _, fi := range fis {
+		if !fi.Mode().IsRegular() {
+			continue
+		}
+		if err := g.writeFile(name+"/"+fi.Name(), filepath.Join(dir, fi.Name()), 0); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// mustGather writes a gzipped tarball with the state of openshift-tuned and tuned
+// for support cases to w: the daemon status, the active and recommended profile,
+// the extracted profiles and recommend.d rules, the labels files, the reload
+// history, the audit log and the tuned logs.  The files that cannot be collected
+// are listed in gatherErrorsFile rather than failing the collection.
+func (c *Controller) mustGather(w io.Writer) error {
+	zw := gzip.NewWriter(w)
+	g := &gatherWriter{tw: tar.NewWriter(zw), time: time.Now()}
+
+	steps := []func() error{
+		func() error { return g.writeJSON("version.json", c.versionGet()) },
+		func() error { return g.writeJSON("status.json", c.status.get()) },
+		func() error { return g.writeJSON("recommended_profile.json", c.recommendedProfileGet(true)) },
+		func() error { return g.writeJSON("profiles.json", c.profilesGet()) },
+		func() error { return g.writeJSON("history.json", c.historyGet()) },
+		func() error { return g.writeJSON("timings.json", c.timingsGet()) },
+		func() error { return g.writeJSON("pristine.json", c.pristineGet()) },
+		func() error { return g.writeFile("active_profile", c.opts.ActiveProfileFile, 0) },
+		func() error { return g.writeDir("recommend.d/system", c.recommendDirs()[0]) },
+		func() error { return g.writeDir("recommend.d", c.recommendDir) },
+		func() error { return g.writeDir("labels/nfd", c.nfdFactsDir()) },
+		func() error { return g.writeFile("labels/"+nfdVariablesFile, c.nfdVariablesFile(), 0) },
+		func() error { return c.mustGatherProfiles(g) },
+		func() error { return g.writeFile("audit.jsonl", c.opts.AuditLog, gatherLogMax) },
+		func() error { return g.writeFile("tuned.log", tunedLogFile, gatherLogMax) },
+		func() error { return g.writeFile("tuned-output-file.log", c.opts.TunedOutputLog, gatherLogMax) },
+		func() error {
+			lines := c.tunedLog.get()
+			if len(lines) == 0 {
+				return nil
+			}
+			return g.write("tuned-output.log", []byte(strings.Join(lines, "\n")+"\n"))
+		},
+	}
+	for _, step := range steps {
+		if err := step(); err != nil {
+			return fmt.Errorf("failed to write the must-gather tarball: %v", err)
+		}
+	}
+	if len(g.errors) > 0 {
+		if err := g.write(gatherErrorsFile, []byte(strings.Join(g.errors, "\n")+"\n")); err != nil {
+			return fmt.Errorf("failed to write the must-gather tarball: %v", err)
+		}
+	}
+	if err := g.tw.Close(); err != nil {
+		return fmt.Errorf("failed to write the must-gather tarball: %v", err)
+	}
+	return zw.Close()
+}
+
+// mustGatherProfiles writes the extracted tuned profiles.
+func (c *Controller) mustGatherProfiles(g *gatherWriter) error {
+	written, _, err := c.store.ListProfiles()
+	if err != nil {
+		g.errors = append(g.errors, fmt.Sprintf("profiles: %v", err))
+		return nil
+	}
+	for _, name := range written {
+		data, err := c.store.ReadProfile(name)
+		if err != nil {
+			g.errors = append(g.errors, fmt.Sprintf("profiles/%s: %v", name, err))
+			continue
+		}
+		if err := g.write("profiles/"+name+"/tuned.conf", []byte(data)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,170 @@
+package tuned
+
+import (
+	"bufio"         // bufio.NewReader()
+	"encoding/json" // json.Unmarshal()
+	"fmt"           // Errorf()
+	"net"           // net.DialTimeout()
+	"os"            // os.Readlink()
+	"strings"       // strings.TrimSpace()
+	"syscall"       // syscall.Signal()
+	"time"          // time.Second
+
+	"k8s.io/klog"
+)
+
+// Types
+// handoffState is the state an openshift-tuned instance hands over to its
+// successor together with the running tuned process.
+type handoffState struct {
+	TunedPid    int    `json:"tunedPid"`
+	Profile     string `json:"profile"`
+	ContentHash string `json:"contentHash"`
+	// PidNamespace identifies the PID namespace TunedPid is valid in
+	PidNamespace string `json:"pidNamespace,omitempty"`
+	Error        string `json:"error,omitempty"`
+}
+
+// Constants
+const (
+	handoffTimeout = 10 * time.Second // for the handoff via the control socket
+	handoffAccept  = "ok"             // the successor's reply taking over tuned
+)
+
+// Functions
+// The handoff works only if both instances share the PID namespace of tuned,
+// i.e. run with hostPID, and the successor can signal tuned.  The successor
+// checks this before it accepts tuned, otherwise it starts a tuned of its own
+// and the previous instance keeps managing its tuned until it is stopped.
+//
+// tuned stays in the pod of the previous instance; it is killed together with
+// that pod.  The handoff thus only avoids the tuned restart while both pods
+// run, e.g. during a DaemonSet rolling update with maxSurge; once the previous
+// pod is deleted, the successor sees the adopted tuned exit and starts tuned.
+
+// pidNamespace returns the identifier of the PID namespace of this process or
+// an empty string if it cannot be determined.
+func pidNamespace() string {
+	ns, err := os.Readlink("/proc/self/ns/pid")
+	if err != nil {
+		return ""
+	}
+	return ns
+}
+
+// handoffGive hands the running tuned over to the openshift-tuned instance
+// connected via s.  Returns true if the instance accepted tuned; this instance
+// must then leave tuned running and stop managing it.
+func (c *Controller) handoffGive(s *sockAccepted) bool {
+	var st handoffState
+
+	if pid := c.runner.Pid(); pid == 0 {
+		st.Error = "tuned does not run"
+	} else {
+		applied, err := c.appliedRead()
+		if err != nil {
+			klog.Errorf("%s", err.Error())
+		}
+		st = handoffState{TunedPid: pid, Profile: applied.Profile, ContentHash: applied.ContentHash, PidNamespace: pidNamespace()}
+	}
+	c.sockWriteJSON(s, st)
+	if len(st.Error) > 0 {
+		klog.Errorf("refused a handoff: %s", st.Error)
+		return false
+	}
+
+	// Keep managing tuned unless the new instance confirms it took tuned over
+	s.conn.SetReadDeadline(time.Now().Add(handoffTimeout))
+	reply, err := bufio.NewReader(s.conn).ReadString('\n')
+	if reply = strings.TrimSpace(reply); reply != handoffAccept {
+		if len(reply) == 0 && err != nil {
+			reply = err.Error()
+		}
+		klog.Warningf("the new %s instance did not take over tuned PID %d, keeping it: %s", programName, st.TunedPid, reply)
+		return false
+	}
+
+	klog.Infof("handed tuned PID %d over to a new %s instance", st.TunedPid, programName)
+	c.handedOff = true
+	c.terminating("tuned handed over to a new " + programName + " instance")
+
+	return true
+}
+
+// handoffCheck returns an error if this instance cannot manage the tuned
+// described by st.
+func handoffCheck(st *handoffState) error {
+	if ns := pidNamespace(); len(ns) == 0 || ns != st.PidNamespace {
+		return fmt.Errorf("tuned PID %d is in PID namespace %q, not %q; is hostPID set?", st.TunedPid, st.PidNamespace, ns)
+	}
+	p, err := os.FindProcess(st.TunedPid)
+	if err == nil {
+		err = p.Signal(syscall.Signal(0))
+	}
+	if err != nil {
+		return fmt.Errorf("cannot signal tuned PID %d: %v", st.TunedPid, err)
+	}
+	return nil
+}
+
+// handoffTake takes over the tuned run by the openshift-tuned instance
+// listening on the control socket, so that tuned is not restarted on an
+// upgrade of openshift-tuned.  On an error, the previous instance keeps
+// managing its tuned.
+func (c *Controller) handoffTake() error {
+	var st handoffState
+
+	conn, err := net.DialTimeout("unix", c.opts.Socket, handoffTimeout)
+	if err != nil {
+		return fmt.Errorf("cannot connect to %q: %v", c.opts.Socket, err)
+	}
+	conn.SetDeadline(time.Now().Add(handoffTimeout))
+	_, err = conn.Write([]byte("handoff\n"))
+	if err == nil {
+		var data []byte
+		if data, err = bufio.NewReader(conn).ReadBytes('\n'); err == nil {
+			err = json.Unmarshal(data, &st)
+		}
+	}
+	if err == nil && len(st.Error) == 0 {
+		reply := handoffAccept
+		if err = handoffCheck(&st); err != nil {
+			reply = err.Error()
+		}
+		if _, werr := conn.Write([]byte(reply + "\n")); werr != nil && err == nil {
+			err = werr
+		}
+	}
+	conn.Close()
+	if err != nil {
+		return fmt.Errorf("handoff via %q failed: %v", c.opts.Socket, err)
+	}
+	if len(st.Error) > 0 {
+		return fmt.Errorf("handoff refused: %s", st.Error)
+	}
+
+	// Closing the listener removes the socket; wait for it, so that the previous
+	// instance does not remove the socket this instance creates
+	for deadline := time.Now().Add(handoffTimeout); ; {
+		conn, err := net.Dial("unix", c.opts.Socket)
+		if err != nil {
+			break
+		}
+		conn.Close()
+		if time.Now().After(deadline) {
+			return fmt.Errorf("the previous %s instance still listens on %q", programName, c.opts.Socket)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	if err = c.runner.Adopt(st.TunedPid, c.tunedExit); err != nil {
+		return err
+	}
+	if len(st.ContentHash) > 0 {
+		// Do not reload tuned if the profile content did not change
+		c.appliedSave(st.Profile, st.ContentHash)
+	}
+	klog.Infof("took over tuned PID %d running profile %q", st.TunedPid, st.Profile)
+
+	return nil
+}
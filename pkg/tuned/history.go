@@ -0,0 +1,137 @@
+package tuned
+
+import (
+	"encoding/json" // json.Marshal()
+	"fmt"           // Errorf()
+	"io/ioutil"     // ioutil.ReadFile()
+	"os"            // os.IsNotExist()
+	"path/filepath" // filepath.Join()
+	"strings"       // strings.Join()
+	"sync"          // sync.Mutex
+	"time"          // time.Time
+
+	"k8s.io/klog"
+
+	"github.com/openshift/openshift-tuned/pkg/layout"
+)
+
+// Types
+// reloadEvent is a tuned reload recorded in the reload history.
+type reloadEvent struct {
+	Time time.Time `json:"time"`
+	// what caused the reload, see reloadTrigger()
+	Trigger         string  `json:"trigger"`
+	PreviousProfile string  `json:"previousProfile,omitempty"`
+	Profile         string  `json:"profile"`
+	DurationSeconds float64 `json:"durationSeconds"`
+	// "applied" or "failed"
+	Result string `json:"result"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// reloadHistory is a ring buffer of the last reload events persisted across
+// openshift-tuned restarts.
+type reloadHistory struct {
+	sync.Mutex
+	events []reloadEvent
+}
+
+// Constants
+const (
+	historyFile = "history.json"
+
+	reloadResultApplied = "applied"
+	reloadResultFailed  = "failed"
+)
+
+// Functions
+// reloadTrigger returns what caused the pending changes of tuned.
+func reloadTrigger(tuned *tunedState) string {
+	var triggers []string
+
+	if tuned.change.profile {
+		triggers = append(triggers, "profile")
+	}
+	if tuned.change.rendered {
+		triggers = append(triggers, "tuned")
+	}
+	if tuned.change.cfg {
+		triggers = append(triggers, "config")
+	}
+	if tuned.change.retry {
+		triggers = append(triggers, "retry")
+	}
+	if tuned.change.force {
+		triggers = append(triggers, "file")
+	}
+	return strings.Join(triggers, ",")
+}
+
+func (c *Controller) historyFile() string {
+	return filepath.Join(c.opts.RunDir, historyFile)
+}
+
+// historyLoad loads the reload history saved by a previous openshift-tuned run.
+func (c *Controller) historyLoad() error {
+	h := &c.history
+
+	data, err := ioutil.ReadFile(c.historyFile())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to read reload history: %v", err)
+	}
+
+	h.Lock()
+	defer h.Unlock()
+	if err = json.Unmarshal(data, &h.events); err != nil {
+		return fmt.Errorf("failed to parse reload history %q: %v", c.historyFile(), err)
+	}
+	if n := len(h.events) - c.opts.HistorySize; n > 0 {
+		h.events = h.events[n:]
+	}
+	return nil
+}
+
+// historyRecord adds the result of the reload pending verification to the reload
+// history and saves it.
+func (c *Controller) historyRecord(pending *reloadVerify, result string, reason string) {
+	h := &c.history
+
+	if c.opts.HistorySize <= 0 {
+		return
+	}
+
+	h.Lock()
+	defer h.Unlock()
+	h.events = append(h.events, reloadEvent{
+		Time:            pending.started,
+		Trigger:         pending.trigger,
+		PreviousProfile: pending.previousProfile,
+		Profile:         pending.profile,
+		DurationSeconds: time.Since(pending.started).Seconds(),
+		Result:          result,
+		Reason:          reason,
+	})
+	if n := len(h.events) - c.opts.HistorySize; n > 0 {
+		h.events = append([]reloadEvent(nil), h.events[n:]...)
+	}
+
+	data, err := json.Marshal(h.events)
+	if err == nil {
+		err = layout.WriteFile(c.historyFile(), data)
+	}
+	if err != nil {
+		klog.Errorf("failed to save reload history: %v", err)
+	}
+}
+
+// historyGet returns the reload history, oldest event first.
+func (c *Controller) historyGet() []reloadEvent {
+	h := &c.history
+
+	h.Lock()
+	defer h.Unlock()
+	return append([]reloadEvent{}, h.events...)
+}
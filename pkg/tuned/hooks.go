@@ -0,0 +1,182 @@
+package tuned
+
+import (
+	"context"   // context.WithCancel()
+	"fmt"       // Sprintf()
+	"io/ioutil" // ioutil.ReadFile()
+	"strings"   // strings.Contains()
+	"sync"      // sync.Mutex
+
+	"k8s.io/klog"
+
+	"github.com/openshift/openshift-tuned/pkg/hooks"
+	"github.com/openshift/openshift-tuned/pkg/profile"
+)
+
+// Types
+// hooksQueue are the hooks waiting to be run by hooksWorker(), off the event loop:
+// hooks may run for up to hooks.Timeout each.
+type hooksQueue struct {
+	sync.Mutex
+	// the profile applied last whose hooks did not run yet
+	applied *string
+	// the hooks of the changed watched files, see Options.WatchFiles
+	watched []*hooks.Hook
+	// a value is sent when hooks are queued
+	wake chan struct{}
+}
+
+// Constants
+const (
+	cpuIsolatedFile = "/sys/devices/system/cpu/isolated"
+)
+
+// Functions
+// hooksInit creates the hooks of opts.Hooks in the order given.
+func (c *Controller) hooksInit() error {
+	for _, spec := range c.opts.Hooks {
+		h, err := hooks.New(spec)
+		if err != nil {
+			return err
+		}
+		c.hooks = append(c.hooks, h)
+	}
+	return nil
+}
+
+// isolatedCPUs returns the cpulist of the CPUs isolated by tuned profile
+// profileName: its isolated_cores variable if set to a plain cpulist, otherwise
+// the CPUs isolated by the kernel.
+func (c *Controller) isolatedCPUs(profileName string) string {
+	chain, err := profile.ChainLoad(c.store, profileName)
+	if err == nil {
+		isolated := ""
+		for _, conf := range chain {
+			if v, ok := conf["variables"]["isolated_cores"]; ok {
+				isolated = strings.TrimSpace(v)
+			}
+		}
+		if len(isolated) > 0 && !strings.Contains(isolated, "${") {
+			if _, err := hooks.CPUListParse(isolated); err == nil {
+				return isolated
+			}
+		}
+	}
+	data, err := ioutil.ReadFile(cpuIsolatedFile)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// hooksQueueApplied queues the hooks to run after tuned applied profile profileName.
+// Only the latest profile matters, a profile whose hooks did not run yet is replaced.
+func (c *Controller) hooksQueueApplied(profileName string) {
+	if len(c.hooks) == 0 {
+		return
+	}
+	c.hooksQueue.Lock()
+	c.hooksQueue.applied = &profileName
+	c.hooksQueue.Unlock()
+	c.hooksWake()
+}
+
+// hooksQueueWatched queues the hooks of changed watched files; hooks already
+// queued are not queued again.
+func (c *Controller) hooksQueueWatched(hs []*hooks.Hook) {
+	c.hooksQueue.Lock()
+	c.hooksQueue.watched = hooksAppend(c.hooksQueue.watched, hs...)
+	c.hooksQueue.Unlock()
+	c.hooksWake()
+}
+
+// hooksAppend appends the hooks hs not in queued yet to queued.
+func hooksAppend(queued []*hooks.Hook, hs ...*hooks.Hook) []*hooks.Hook {
+next:
+	for _, h := range hs {
+		for _, q := range queued {
+			if q == h {
+				continue next
+			}
+		}
+		queued = append(queued, h)
+	}
+	return queued
+}
+
+func (c *Controller) hooksWake() {
+	select {
+	case c.hooksQueue.wake <- struct{}{}:
+	default:
+		// Already woken
+	}
+}
+
+// hooksWorker runs the queued hooks until stop is closed, which cancels the
+// running hook; hooks not run to completion stay queued for the next
+// changeWatcher() iteration.
+func (c *Controller) hooksWorker(stop <-chan struct{}) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-stop:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	for {
+		select {
+		case <-c.hooksQueue.wake:
+		case <-stop:
+			return
+		}
+		c.hooksQueue.Lock()
+		applied, watched := c.hooksQueue.applied, c.hooksQueue.watched
+		c.hooksQueue.applied, c.hooksQueue.watched = nil, nil
+		c.hooksQueue.Unlock()
+
+		if applied != nil {
+			c.hooksRun(ctx, *applied)
+		}
+		if len(watched) > 0 && ctx.Err() == nil {
+			active, _ := c.store.ActiveProfile()
+			hooks.Run(ctx, watched, hooks.Context{Profile: active, IsolatedCPUs: c.isolatedCPUs(active)})
+		}
+		if ctx.Err() != nil {
+			// Cancelled, run again in the next iteration
+			c.hooksQueue.Lock()
+			if c.hooksQueue.applied == nil {
+				c.hooksQueue.applied = applied
+			}
+			c.hooksQueue.watched = hooksAppend(c.hooksQueue.watched, watched...)
+			c.hooksQueue.Unlock()
+			c.hooksWake()
+			return
+		}
+	}
+}
+
+// hooksRun runs the hooks after tuned applied profile profileName and reports
+// failing hooks via the API and the Degraded state.
+func (c *Controller) hooksRun(ctx context.Context, profileName string) {
+	var failed []string
+
+	hc := hooks.Context{Profile: profileName, IsolatedCPUs: c.isolatedCPUs(profileName)}
+	klog.V(1).Infof("running %d hooks for profile %q, isolated CPUs %q", len(c.hooks), profileName, hc.IsolatedCPUs)
+	results := hooks.Run(ctx, c.hooks, hc)
+	if ctx.Err() != nil {
+		// Cancelled, run again
+		return
+	}
+	for _, r := range results {
+		if len(r.Error) > 0 {
+			failed = append(failed, r.Name)
+		}
+	}
+	c.status.setHookResults(results)
+	if len(failed) > 0 {
+		c.status.setState(stateDegraded, fmt.Sprintf("hooks failed after applying profile %q: %s", profileName, strings.Join(failed, ", ")))
+	}
+}
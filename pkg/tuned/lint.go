@@ -0,0 +1,16 @@
+package tuned
+
+import (
+	"github.com/openshift/openshift-tuned/pkg/profile"
+)
+
+// Functions
+// Lint runs the checks of the tuned profiles extraction on profiles, profile
+// name -> data, offline.  Included profiles missing from profiles are looked
+// up in the configured profile directories.
+func (c *Controller) Lint(profiles map[string]string) ([]profile.Problem, error) {
+	if err := profile.Verify(profiles, c.opts.ProfileSigningKey, c.opts.RequireSignedProfiles); err != nil {
+		return nil, err
+	}
+	return profile.Validate(profiles, c.store)
+}
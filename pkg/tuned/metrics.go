@@ -0,0 +1,87 @@
+package tuned
+
+import (
+	"bytes" // bytes.Buffer
+	"sort"  // sort.Strings()
+
+	"github.com/openshift/openshift-tuned/pkg/metrics"
+	"github.com/openshift/openshift-tuned/pkg/process"
+)
+
+// Functions
+// stateMetricsCollect writes the daemon state metrics.
+func (c *Controller) stateMetricsCollect(buf *bytes.Buffer) {
+	var (
+		state       []metrics.Sample
+		transitions []metrics.Sample
+	)
+
+	c.status.RLock()
+	for i := range daemonStateNames {
+		st := daemonState(i)
+		labels := map[string]string{"state": st.String()}
+		value := 0.0
+		if c.status.state == st {
+			value = 1
+		}
+		state = append(state, metrics.Sample{Labels: labels, Value: value})
+		transitions = append(transitions, metrics.Sample{Labels: labels, Value: float64(c.status.stateTransitions[st])})
+	}
+	c.status.RUnlock()
+
+	metrics.Write(buf, "state", "gauge", "The current state of the daemon.", state...)
+	metrics.Write(buf, "state_transitions_total", "counter", "Number of transitions into each daemon state.", transitions...)
+}
+
+// errorMetricsCollect writes the error metrics.
+func (c *Controller) errorMetricsCollect(buf *bytes.Buffer) {
+	var errors []metrics.Sample
+
+	c.status.RLock()
+	for _, category := range []string{errCategoryAPI, errCategoryExec, errCategoryFS, errCategoryConfig, errCategoryOther} {
+		errors = append(errors, metrics.Sample{
+			Labels: map[string]string{"category": category},
+			Value:  float64(c.status.errorCounts[category]),
+		})
+	}
+	c.status.RUnlock()
+
+	metrics.Write(buf, "errors_total", "counter", "Number of errors which restarted the event loop by category.", errors...)
+}
+
+// execMetricsCollect writes the metrics of the commands run.
+func (c *Controller) execMetricsCollect(buf *bytes.Buffer) {
+	var (
+		names   []string
+		samples []metrics.Sample
+	)
+
+	counts := process.Timeouts()
+	for name := range counts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		samples = append(samples, metrics.Sample{
+			Labels: map[string]string{"command": name},
+			Value:  float64(counts[name]),
+		})
+	}
+
+	metrics.Write(buf, "exec_timeouts_total", "counter", "Number of commands killed for exceeding the exec timeout by command.", samples...)
+}
+
+// metricsCollectors returns the collectors of the metrics served by /metrics.
+func (c *Controller) metricsCollectors() []metrics.Collector {
+	return []metrics.Collector{
+		c.stateMetricsCollect,
+		c.errorMetricsCollect,
+		c.buildInfoMetricsCollect,
+		c.retryMetricsCollect,
+		c.timingMetricsCollect,
+		c.recommendMetricsCollect,
+		c.pipelineMetricsCollect,
+		c.bootMetricsCollect,
+		c.execMetricsCollect,
+	}
+}
@@ -0,0 +1,166 @@
+package tuned
+
+import (
+	"fmt"           // Errorf()
+	"io/ioutil"     // ioutil.ReadFile()
+	"os"            // os.RemoveAll()
+	"path/filepath" // filepath.Join()
+	"regexp"        // regexp.MustCompile()
+	"sort"          // sort.Strings()
+	"strings"       // strings.SplitN()
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/klog"
+
+	"github.com/openshift/openshift-tuned/pkg/layout"
+)
+
+// Types
+// nfdVariable maps a node label to a tuned profile variable, see
+// Options.NFDVariables.
+type nfdVariable struct {
+	label    string
+	variable string
+}
+
+// Global variables
+var (
+	// the names of tuned profile variables
+	nfdVariableName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+)
+
+// Constants
+const (
+	// the label namespace of Node Feature Discovery; custom feature namespaces
+	// are below it, e.g. "vendor.feature.node.kubernetes.io"
+	nfdLabelNamespace = "feature.node.kubernetes.io"
+	nfdFactsDir       = "nfd"                // in RunDir
+	nfdVariablesFile  = "nfd-variables.conf" // in RunDir
+)
+
+// Functions
+// nfdVariablesParse parses the label to variable mappings "label=variable".
+func nfdVariablesParse(specs []string) ([]nfdVariable, error) {
+	var vars []nfdVariable
+
+	for _, spec := range specs {
+		parts := strings.SplitN(spec, "=", 2)
+		if len(parts) != 2 || len(parts[0]) == 0 || !nfdVariableName.MatchString(parts[1]) {
+			return nil, fmt.Errorf("invalid NFD variable %q, expected <label>=<variable name>", spec)
+		}
+		vars = append(vars, nfdVariable{label: parts[0], variable: parts[1]})
+	}
+	return vars, nil
+}
+
+// nfdLabel returns true if node label key was set by Node Feature Discovery.
+func nfdLabel(key string) bool {
+	i := strings.Index(key, "/")
+	if i < 0 {
+		return false
+	}
+	ns := key[:i]
+	return ns == nfdLabelNamespace || strings.HasSuffix(ns, "."+nfdLabelNamespace)
+}
+
+// nfdFactsDir returns the directory of the NFD label files.
+func (c *Controller) nfdFactsDir() string {
+	return filepath.Join(c.opts.RunDir, nfdFactsDir)
+}
+
+// nfdVariablesFile returns the tuned variables file of the mapped node labels.
+func (c *Controller) nfdVariablesFile() string {
+	return filepath.Join(c.opts.RunDir, nfdVariablesFile)
+}
+
+// nfdFactsWrite replaces the NFD label files by facts, label -> value.
+func (c *Controller) nfdFactsWrite(facts map[string]string) error {
+	dir := c.nfdFactsDir()
+	tmp := dir + ".tmp"
+	if err := os.RemoveAll(tmp); err != nil {
+		return err
+	}
+	if err := layout.Mkdir(tmp); err != nil {
+		return fmt.Errorf("failed to create NFD facts directory %q: %v", tmp, err)
+	}
+	for label, value := range facts {
+		// The label prefix is a subdirectory, label names cannot contain "/"
+		path := filepath.Join(tmp, label)
+		if err := layout.Mkdir(filepath.Dir(path)); err != nil {
+			return err
+		}
+		if err := layout.WriteFile(path, []byte(value+"\n")); err != nil {
+			return err
+		}
+	}
+	if err := layout.Swap(tmp, dir); err != nil {
+		return err
+	}
+	return os.RemoveAll(tmp)
+}
+
+// nfdVariablesData returns the tuned variables file content of the mapped labels
+// of node.  Variables of labels the node does not have are empty, so that the
+// profiles can rely on them being defined.
+func (c *Controller) nfdVariablesData(node *corev1.Node) string {
+	var lines []string
+
+	for _, v := range c.nfdVariables {
+		lines = append(lines, fmt.Sprintf("%s=%s\n", v.variable, node.Labels[v.label]))
+	}
+	sort.Strings(lines)
+	return "# written by " + programName + " from the labels of node " + node.Name + "\n" + strings.Join(lines, "")
+}
+
+// nfdUpdate writes the NFD facts and variables of node.  Changed facts make tuned
+// re-evaluate the recommend rules, changed variables reload tuned if the active
+// profile includes them.
+func (c *Controller) nfdUpdate(tuned *tunedState, node *corev1.Node) {
+	if c.opts.NFDFacts {
+		facts := map[string]string{}
+		for k, v := range node.Labels {
+			if nfdLabel(k) {
+				facts[k] = v
+			}
+		}
+		if tuned.nfdFacts == nil || !stringMapsEqual(facts, tuned.nfdFacts) {
+			if err := c.nfdFactsWrite(facts); err != nil {
+				klog.Errorf("failed to write the NFD facts: %v", err)
+				return
+			}
+			klog.Infof("node %q has %d NFD labels, re-evaluating the recommend rules", tuned.nodeName, len(facts))
+			tuned.nfdFacts = facts
+			tuned.change.profile = true
+		}
+	}
+
+	if len(c.nfdVariables) > 0 {
+		path := c.nfdVariablesFile()
+		data := c.nfdVariablesData(node)
+		if old, err := ioutil.ReadFile(path); err == nil && string(old) == data {
+			return
+		}
+		if err := layout.WriteFile(path, []byte(data)); err != nil {
+			klog.Errorf("failed to write the NFD variables: %v", err)
+			return
+		}
+		active, err := c.store.ActiveProfile()
+		if err != nil || c.profileIncludesVariables(active, path) {
+			klog.Infof("NFD variables of node %q changed, reloading tuned", tuned.nodeName)
+			tuned.change.force = true
+		}
+	}
+}
+
+// stringMapsEqual returns true if a and b hold the same keys and values.
+func stringMapsEqual(a, b map[string]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for k, v := range a {
+		if w, ok := b[k]; !ok || v != w {
+			return false
+		}
+	}
+	return true
+}
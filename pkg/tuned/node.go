@@ -0,0 +1,100 @@
+package tuned
+
+import (
+	"encoding/json" // json.Marshal()
+	"fmt"           // Errorf()
+	"os"            // os.Hostname()
+	"strings"       // strings.EqualFold()
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"k8s.io/klog"
+)
+
+// Constants
+const (
+	nodeAnnotationRebootRequired   = "tuned.openshift.io/reboot-required"
+	nodeAnnotationActiveProfile    = "tuned.openshift.io/active-profile"
+	nodeAnnotationConfigGeneration = "tuned.openshift.io/config-generation"
+	nodeAnnotationFailingPlugins   = "tuned.openshift.io/failing-plugins"
+)
+
+// Functions
+// newCoreClient creates a REST client for the core ("v1") API group.
+func newCoreClient(kubeConfig *rest.Config) (*rest.RESTClient, error) {
+	config := *kubeConfig
+	config.GroupVersion = &corev1.SchemeGroupVersion
+	config.APIPath = "/api"
+	config.NegotiatedSerializer = scheme.Codecs.WithoutConversion()
+	if config.UserAgent == "" {
+		config.UserAgent = rest.DefaultKubernetesUserAgent()
+	}
+
+	return rest.RESTClientFor(&config)
+}
+
+// nodeValidate verifies that node nodeName exists, i.e. openshift-tuned was given
+// the name the kubelet registered the node with.
+func nodeValidate(c rest.Interface, nodeName string) error {
+	node := &corev1.Node{}
+	err := c.Get().Resource("nodes").Name(nodeName).Do().Into(node)
+	if apierrors.IsNotFound(err) {
+		return errExit(ExitConfig, fmt.Errorf("node %q not found; set NODE_NAME from spec.nodeName via the downward API or pass the name the node is registered with by -node-name", nodeName))
+	}
+	if err != nil {
+		return errTransient(fmt.Errorf("failed to get node %q: %v", nodeName, err), err)
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil {
+		return nil
+	}
+	for _, addr := range node.Status.Addresses {
+		if addr.Type == corev1.NodeHostName && !strings.EqualFold(addr.Address, hostname) {
+			// Not fatal, the pod may not run in the host UTS namespace
+			klog.Warningf("node %q reports hostname %q, but running on host %q", nodeName, addr.Address, hostname)
+		}
+	}
+
+	return nil
+}
+
+// nodeAnnotate sets annotations on node nodeName.
+func nodeAnnotate(c rest.Interface, nodeName string, annotations map[string]string) error {
+	patch := map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": annotations,
+		},
+	}
+	data, err := json.Marshal(patch)
+	if err != nil {
+		return fmt.Errorf("failed to create annotation patch for node %q: %v", nodeName, err)
+	}
+
+	err = c.Patch(types.MergePatchType).Resource("nodes").Name(nodeName).Body(data).Do().Error()
+	if err != nil {
+		return fmt.Errorf("failed to annotate node %q: %v", nodeName, err)
+	}
+
+	return nil
+}
+
+// nodeAnnotateApplied publishes the profile tuned applied, a hash of its content
+// (the configuration generation) and the plugins which failed to apply it as node
+// annotations.
+func nodeAnnotateApplied(tuned *tunedState, profileName string, contentHash string, failingPlugins string) {
+	if tuned.coreClient == nil {
+		return
+	}
+	err := nodeAnnotate(tuned.coreClient, tuned.nodeName, map[string]string{
+		nodeAnnotationActiveProfile:    profileName,
+		nodeAnnotationConfigGeneration: contentHash,
+		nodeAnnotationFailingPlugins:   failingPlugins,
+	})
+	if err != nil {
+		klog.Errorf("%s", err.Error())
+	}
+}
@@ -0,0 +1,46 @@
+package tuned
+
+import (
+	"k8s.io/client-go/rest"
+
+	"github.com/openshift/openshift-tuned/pkg/notify"
+)
+
+// Functions
+// notifiersInit creates the notifiers of opts.Notifiers.
+func (c *Controller) notifiersInit() error {
+	if len(c.opts.Notifiers) == 0 {
+		return nil
+	}
+	var notifiers []notify.Notifier
+	for _, spec := range c.opts.Notifiers {
+		n, err := notify.New(spec)
+		if err != nil {
+			return err
+		}
+		notifiers = append(notifiers, n)
+	}
+	c.notifiers = notifiers
+	c.notifier = notify.NewDispatcher(notifiers)
+	return nil
+}
+
+// notifiersClientSet sets the API client of the notifiers creating Kubernetes Events.
+func (c *Controller) notifiersClientSet(client rest.Interface) {
+	for _, n := range c.notifiers {
+		if k, ok := n.(*notify.KubeEvents); ok {
+			k.SetClient(client)
+		}
+	}
+}
+
+// notify informs the notifiers of a tuning change of type eventType.
+func (c *Controller) notify(eventType string, profileName string, previousProfile string, message string) {
+	c.notifier.Dispatch(notify.Event{
+		Node:            c.opts.NodeName,
+		Type:            eventType,
+		Profile:         profileName,
+		PreviousProfile: previousProfile,
+		Message:         message,
+	})
+}
@@ -0,0 +1,95 @@
+package tuned
+
+import (
+	"fmt"  // Errorf()
+	"time" // time.Second
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog"
+)
+
+// Constants
+const (
+	// key of the OperandConfig ConfigMap holding the configuration, the YAML of
+	// Options.ConfigFile
+	operandConfigKey = "config.yaml"
+	// time to wait for the OperandConfig at startup
+	operandConfigGetTimeout = 10 * time.Second
+)
+
+// Functions
+// OperandConfigGet returns the configuration in ConfigMap opts.OperandConfigMap
+// of the operand namespace, the OperandConfig the operator manages; empty if the
+// ConfigMap does not exist.
+func OperandConfigGet(opts Options) (string, error) {
+	kubeConfig, err := getConfig(opts.KubeConfig)
+	if err != nil {
+		return "", err
+	}
+	kubeConfig.Timeout = operandConfigGetTimeout
+	client, err := newCoreClient(kubeConfig)
+	if err != nil {
+		return "", err
+	}
+
+	cm := &corev1.ConfigMap{}
+	err = client.Get().Namespace(operandNamespace).Resource("configmaps").Name(opts.OperandConfigMap).Do().Into(cm)
+	if apierrors.IsNotFound(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get ConfigMap %s/%s: %v", operandNamespace, opts.OperandConfigMap, err)
+	}
+	return cm.Data[operandConfigKey], nil
+}
+
+func getConfigMap(obj interface{}) (*corev1.ConfigMap, error) {
+	cm, ok := obj.(*corev1.ConfigMap)
+	if !ok {
+		return nil, fmt.Errorf("could not convert object to a ConfigMap object: %+v", obj)
+	}
+	return cm, nil
+}
+
+// operandConfigWatch starts an informer passing the changes of the OperandConfig
+// to the event loop as one of workers w.
+func (c *Controller) operandConfigWatch(client cache.Getter, w *workers) {
+	fs := fields.SelectorFromSet(fields.Set{"metadata.name": c.opts.OperandConfigMap})
+	lw := cache.NewListWatchFromClient(client, "configmaps", operandNamespace, fs)
+	si := cache.NewSharedInformer(lw, &corev1.ConfigMap{}, 0)
+	si.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			cm, err := getConfigMap(obj)
+			if err != nil {
+				klog.Errorf("%s", err.Error())
+				return
+			}
+			c.operandConfigQueue(cm.Data[operandConfigKey])
+		},
+		UpdateFunc: func(objOld, objNew interface{}) {
+			cm, err := getConfigMap(objNew)
+			if err != nil {
+				klog.Errorf("%s", err.Error())
+				return
+			}
+			c.operandConfigQueue(cm.Data[operandConfigKey])
+		},
+		DeleteFunc: func(obj interface{}) {
+			c.operandConfigQueue("")
+		},
+	})
+	w.run(si.Run)
+}
+
+// operandConfigQueue passes OperandConfig data to the event loop.  Only the
+// latest data matters, data not yet handled is replaced.
+func (c *Controller) operandConfigQueue(data string) {
+	select {
+	case <-c.operandConfigC:
+	default:
+	}
+	c.operandConfigC <- data
+}
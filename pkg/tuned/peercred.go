@@ -0,0 +1,58 @@
+package tuned
+
+import (
+	"fmt"     // Errorf()
+	"strconv" // strconv.Atoi()
+	"strings" // strings.SplitN()
+)
+
+// Types
+// peerCred are the credentials of a control socket client.
+type peerCred struct {
+	pid int
+	uid int
+	gid int
+}
+
+// sockAllow are the principals allowed to use the control socket besides root,
+// see Options.SocketAllow.
+type sockAllow struct {
+	uids map[int]bool
+	gids map[int]bool
+}
+
+// Functions
+func (p peerCred) String() string {
+	return fmt.Sprintf("uid=%d gid=%d pid=%d", p.uid, p.gid, p.pid)
+}
+
+// sockAllowParse parses the principals allowed to use the control socket,
+// "uid:<n>" or "gid:<n>".
+func sockAllowParse(specs []string) (sockAllow, error) {
+	a := sockAllow{uids: map[int]bool{}, gids: map[int]bool{}}
+
+	for _, spec := range specs {
+		parts := strings.SplitN(spec, ":", 2)
+		if len(parts) != 2 {
+			return a, fmt.Errorf("invalid socket principal %q, expected uid:<n> or gid:<n>", spec)
+		}
+		id, err := strconv.Atoi(parts[1])
+		if err != nil || id < 0 {
+			return a, fmt.Errorf("invalid socket principal %q, expected uid:<n> or gid:<n>", spec)
+		}
+		switch parts[0] {
+		case "uid":
+			a.uids[id] = true
+		case "gid":
+			a.gids[id] = true
+		default:
+			return a, fmt.Errorf("invalid socket principal %q, expected uid:<n> or gid:<n>", spec)
+		}
+	}
+	return a, nil
+}
+
+// allowed returns true if the client with credentials p may use the control socket.
+func (a sockAllow) allowed(p peerCred) bool {
+	return p.uid == 0 || a.uids[p.uid] || a.gids[p.gid]
+}
@@ -0,0 +1,39 @@
+// +build linux
+
+package tuned
+
+import (
+	"fmt" // Errorf()
+	"net" // net.UnixConn
+
+	"golang.org/x/sys/unix"
+)
+
+// Functions
+// sockPeerCred returns the credentials of the process which connected to the
+// control socket by conn, see SO_PEERCRED in unix(7).
+func sockPeerCred(conn net.Conn) (peerCred, error) {
+	var (
+		ucred *unix.Ucred
+		err   error
+	)
+
+	uc, ok := conn.(*net.UnixConn)
+	if !ok {
+		return peerCred{}, fmt.Errorf("not a unix socket connection: %T", conn)
+	}
+	raw, err := uc.SyscallConn()
+	if err != nil {
+		return peerCred{}, err
+	}
+	cerr := raw.Control(func(fd uintptr) {
+		ucred, err = unix.GetsockoptUcred(int(fd), unix.SOL_SOCKET, unix.SO_PEERCRED)
+	})
+	if cerr != nil {
+		return peerCred{}, cerr
+	}
+	if err != nil {
+		return peerCred{}, fmt.Errorf("failed to get the peer credentials: %v", err)
+	}
+	return peerCred{pid: int(ucred.Pid), uid: int(ucred.Uid), gid: int(ucred.Gid)}, nil
+}
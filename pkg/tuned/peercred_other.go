@@ -0,0 +1,15 @@
+// +build !linux
+
+package tuned
+
+import (
+	"net" // net.Conn
+
+	"github.com/openshift/openshift-tuned/pkg/process"
+)
+
+// Functions
+// sockPeerCred returns process.ErrUnsupportedPlatform, SO_PEERCRED is Linux-only.
+func sockPeerCred(conn net.Conn) (peerCred, error) {
+	return peerCred{}, process.ErrUnsupportedPlatform
+}
@@ -0,0 +1,68 @@
+package tuned
+
+import (
+	"bytes" // bytes.Buffer
+	"sync"  // sync.Mutex
+
+	"github.com/openshift/openshift-tuned/pkg/metrics"
+)
+
+// Types
+// pipeline serializes the operations extracting the tuned profiles and reloading
+// tuned, which are triggered from the event loop as well as the informers.  Only
+// one operation runs at a time; triggers of an operation which is already waiting
+// to run are coalesced into that run.
+type pipeline struct {
+	sync.Mutex
+	// held while an operation runs
+	busy sync.Mutex
+	// operations waiting to run by name
+	queued map[string]*flight
+	// triggers coalesced into a queued run
+	coalesced int
+}
+
+// flight is a queued run of a pipeline operation.
+type flight struct {
+	done chan struct{}
+	err  error
+}
+
+// Functions
+// do runs operation name by f once no other operation runs.  If a run of name is
+// already queued, do waits for that run instead and returns its error.
+func (p *pipeline) do(name string, f func() error) error {
+	p.Lock()
+	if fl, ok := p.queued[name]; ok {
+		p.coalesced++
+		p.Unlock()
+		<-fl.done
+		return fl.err
+	}
+	if p.queued == nil {
+		p.queued = map[string]*flight{}
+	}
+	fl := &flight{done: make(chan struct{})}
+	p.queued[name] = fl
+	p.Unlock()
+
+	p.busy.Lock()
+	p.Lock()
+	// Started: triggers from now on need another run
+	delete(p.queued, name)
+	p.Unlock()
+	fl.err = f()
+	p.busy.Unlock()
+	close(fl.done)
+
+	return fl.err
+}
+
+// pipelineMetricsCollect writes the pipeline metrics.
+func (c *Controller) pipelineMetricsCollect(buf *bytes.Buffer) {
+	c.pipeline.Lock()
+	coalesced := c.pipeline.coalesced
+	c.pipeline.Unlock()
+
+	metrics.Write(buf, "reloads_coalesced_total", "counter", "Number of extract and reload triggers coalesced into an already queued run.", metrics.Sample{Value: float64(coalesced)})
+}
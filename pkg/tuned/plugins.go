@@ -0,0 +1,78 @@
+package tuned
+
+import (
+	"fmt"     // Sprintf()
+	"sort"    // sort.Strings()
+	"strings" // strings.Join()
+
+	"github.com/openshift/openshift-tuned/pkg/process"
+)
+
+// Types
+// pluginError is an error tuned logged while applying the current profile.
+type pluginError struct {
+	process.LogError
+	// number of times tuned logged the error
+	Count int `json:"count"`
+}
+
+// Constants
+const (
+	pluginErrorsMax = 100
+)
+
+// Functions
+// addPluginError records an error logged by tuned.
+func (s *daemonStatus) addPluginError(e process.LogError) {
+	s.Lock()
+	defer s.Unlock()
+
+	for i := range s.pluginErrors {
+		if s.pluginErrors[i].LogError == e {
+			s.pluginErrors[i].Count++
+			return
+		}
+	}
+	if len(s.pluginErrors) < pluginErrorsMax {
+		s.pluginErrors = append(s.pluginErrors, pluginError{LogError: e, Count: 1})
+	}
+}
+
+// clearPluginErrors forgets the errors tuned logged, e.g. before applying a new profile.
+func (s *daemonStatus) clearPluginErrors() {
+	s.Lock()
+	defer s.Unlock()
+
+	s.pluginErrors = nil
+}
+
+// failingPlugins returns the sorted names of the plugins which logged errors.
+func (s *daemonStatus) failingPlugins() []string {
+	var plugins []string
+
+	s.RLock()
+	defer s.RUnlock()
+
+	seen := map[string]bool{}
+	for _, e := range s.pluginErrors {
+		if e.Level == "ERROR" && !seen[e.Plugin] {
+			seen[e.Plugin] = true
+			plugins = append(plugins, e.Plugin)
+		}
+	}
+	sort.Strings(plugins)
+	return plugins
+}
+
+// tunedLogLine records a line tuned logged to stream and the errors and warnings in it.
+func (c *Controller) tunedLogLine(stream process.Stream, line string) {
+	c.tunedLog.add(fmt.Sprintf("%s: %s", stream, line))
+	if e, ok := process.LogErrorParse(line); ok {
+		c.status.addPluginError(e)
+	}
+}
+
+// failingPluginsAnnotation returns the value of the failing plugins node annotation.
+func (c *Controller) failingPluginsAnnotation() string {
+	return strings.Join(c.status.failingPlugins(), ",")
+}
@@ -0,0 +1,128 @@
+package tuned
+
+import (
+	"fmt"       // Errorf()
+	"io/ioutil" // ioutil.TempFile()
+	"os"        // os.Stat()
+	"strings"   // strings.Join()
+
+	"k8s.io/klog"
+
+	"github.com/openshift/openshift-tuned/pkg/layout"
+	"github.com/openshift/openshift-tuned/pkg/process"
+)
+
+// Types
+// preflightResult is the result of a single startup check.
+type preflightResult struct {
+	Check string `json:"check"`
+	Error string `json:"error,omitempty"`
+	// exit code if the check fails
+	code int
+}
+
+// Global variables
+var (
+	// the oldest tuned release openshift-tuned works with (recommend.d)
+	tunedVersionMin = process.Version{2, 8, 0}
+)
+
+// Functions
+// executableCheck returns an error if path is not an executable file.
+func executableCheck(path string) error {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if !fi.Mode().IsRegular() || fi.Mode().Perm()&0111 == 0 {
+		return fmt.Errorf("%s is not an executable file", path)
+	}
+	return nil
+}
+
+// writableCheck returns an error if files cannot be created in directory dir.
+func writableCheck(dir string) error {
+	if err := layout.Mkdir(dir); err != nil {
+		return err
+	}
+	f, err := ioutil.TempFile(dir, ".preflight.")
+	if err != nil {
+		return fmt.Errorf("cannot write to %s: %v", dir, err)
+	}
+	f.Close()
+	return os.Remove(f.Name())
+}
+
+// tunedVersionCheck detects the version of tuned and returns an error if it is too old.
+func (c *Controller) tunedVersionCheck() error {
+	if err := c.tunedVersionDetect(); err != nil {
+		return err
+	}
+	if c.tunedVersion.Less(tunedVersionMin) {
+		return fmt.Errorf("tuned %s is older than the oldest supported release %s", c.tunedVersion, tunedVersionMin)
+	}
+	return nil
+}
+
+// preflightFailed returns the failed checks of results.
+func preflightFailed(results []preflightResult) []preflightResult {
+	var failed []preflightResult
+
+	for _, r := range results {
+		if len(r.Error) > 0 {
+			failed = append(failed, r)
+		}
+	}
+	return failed
+}
+
+// preflight verifies the tuned binaries and the environment openshift-tuned runs in
+// before entering the main loop.  Returns an error carrying the exit code of the
+// first failed check.
+func (c *Controller) preflight() error {
+	var (
+		results []preflightResult
+		failed  []string
+		code    int
+	)
+
+	check := func(name string, exitCode int, f func() error) {
+		r := preflightResult{Check: name, code: exitCode}
+		if err := f(); err != nil {
+			r.Error = err.Error()
+		}
+		results = append(results, r)
+	}
+
+	if !c.opts.MockTuned {
+		for _, bin := range []string{process.TunedBinary, process.TunedAdmBinary} {
+			bin := bin
+			check(bin+" executable", ExitTunedMissing, func() error { return executableCheck(bin) })
+		}
+		check("tuned version", ExitTunedMissing, c.tunedVersionCheck)
+	}
+	for _, dir := range []string{c.opts.ProfilesDir, c.opts.RunDir} {
+		dir := dir
+		check(dir+" writable", ExitRunDir, func() error { return writableCheck(dir) })
+	}
+
+	for _, r := range results {
+		if len(r.Error) == 0 {
+			klog.V(1).Infof("preflight: %s: ok", r.Check)
+			continue
+		}
+		klog.Errorf("preflight: %s: %s", r.Check, r.Error)
+		failed = append(failed, r.Check)
+		if code == 0 {
+			code = r.code
+		}
+	}
+	c.status.setPreflight(results)
+	if len(failed) == 0 {
+		return nil
+	}
+
+	err := fmt.Errorf("preflight checks failed: %s", strings.Join(failed, ", "))
+	c.status.setState(stateDegraded, err.Error())
+	return errExit(code, err)
+}
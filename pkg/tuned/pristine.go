@@ -0,0 +1,177 @@
+package tuned
+
+import (
+	"encoding/json" // json.Marshal()
+	"fmt"           // Errorf()
+	"io/ioutil"     // ioutil.ReadFile()
+	"os"            // os.IsNotExist()
+	"path/filepath" // filepath.Glob()
+	"sort"          // sort.Strings()
+	"strings"       // strings.Index()
+
+	"k8s.io/klog"
+
+	"github.com/openshift/openshift-tuned/pkg/layout"
+	"github.com/openshift/openshift-tuned/pkg/profile"
+)
+
+// Types
+// pristineState holds the values of the sysctl and sysfs settings before tuned
+// first modified them.
+type pristineState struct {
+	// sysctl key -> value
+	Sysctl map[string]string `json:"sysctl"`
+	// sysfs path -> value
+	Sysfs map[string]string `json:"sysfs"`
+	Error string            `json:"error,omitempty"`
+}
+
+// Constants
+const (
+	pristineFile = "pristine.json" // in RunDir
+)
+
+// Functions
+func (c *Controller) pristineFile() string {
+	return filepath.Join(c.opts.RunDir, pristineFile)
+}
+
+// pristineLoad returns the pristine state recorded so far since boot.
+func (c *Controller) pristineLoad() (pristineState, error) {
+	ps := pristineState{Sysctl: map[string]string{}, Sysfs: map[string]string{}}
+
+	data, err := ioutil.ReadFile(c.pristineFile())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return ps, nil
+		}
+		return ps, fmt.Errorf("failed to read the pristine state: %v", err)
+	}
+	if err = json.Unmarshal(data, &ps); err != nil {
+		return ps, fmt.Errorf("failed to parse the pristine state %q: %v", c.pristineFile(), err)
+	}
+	if ps.Sysctl == nil {
+		ps.Sysctl = map[string]string{}
+	}
+	if ps.Sysfs == nil {
+		ps.Sysfs = map[string]string{}
+	}
+	return ps, nil
+}
+
+// sysfsValue returns the value to write back to a sysfs file read as v; for
+// multiple-choice files such as "always [madvise] never" this is the selected one.
+func sysfsValue(v string) string {
+	i := strings.Index(v, "[")
+	j := strings.Index(v, "]")
+	if i >= 0 && j > i {
+		return v[i+1 : j]
+	}
+	return v
+}
+
+// pristineRecord records the current values of the sysctl and sysfs settings of
+// tuned profile profileName (and the profiles it includes) not recorded yet.
+// Settings recorded before are kept, so the pristine state holds the values
+// prior to the first profile applied since boot.
+func (c *Controller) pristineRecord(profileName string) error {
+	ps, err := c.pristineLoad()
+	if err != nil {
+		return err
+	}
+	chain, err := profile.ChainLoad(c.store, profileName)
+	if err != nil {
+		return err
+	}
+
+	added := 0
+	for key := range profile.ChainSysctls(chain) {
+		if _, ok := ps.Sysctl[key]; ok || strings.Contains(key, "${") {
+			continue
+		}
+		value, err := sysctlRead(key)
+		if err != nil {
+			klog.V(1).Infof("cannot record the pristine value of sysctl %s: %v", key, err)
+			continue
+		}
+		ps.Sysctl[key] = value
+		added++
+	}
+	for pattern := range profile.ChainSysfs(chain) {
+		if strings.Contains(pattern, "${") {
+			continue
+		}
+		paths, _ := filepath.Glob(pattern)
+		for _, path := range paths {
+			if _, ok := ps.Sysfs[path]; ok {
+				continue
+			}
+			data, err := ioutil.ReadFile(path)
+			if err != nil {
+				klog.V(1).Infof("cannot record the pristine value of %s: %v", path, err)
+				continue
+			}
+			ps.Sysfs[path] = sysfsValue(sysctlValueNormalize(string(data)))
+			added++
+		}
+	}
+	if added == 0 {
+		return nil
+	}
+
+	data, err := json.Marshal(ps)
+	if err != nil {
+		return err
+	}
+	if err = layout.WriteFile(c.pristineFile(), data); err != nil {
+		return fmt.Errorf("failed to save the pristine state: %v", err)
+	}
+	klog.V(1).Infof("recorded the pristine values of %d settings of profile %q", added, profileName)
+
+	return nil
+}
+
+// pristineRestore writes back all the recorded pristine settings.  It does not
+// rely on tuned, so it works even if tuned's own rollback is incomplete.
+func (c *Controller) pristineRestore() error {
+	var failed []string
+
+	ps, err := c.pristineLoad()
+	if err != nil {
+		return err
+	}
+
+	settings := map[string]string{}
+	for key, value := range ps.Sysctl {
+		settings[filepath.Join(procSys, strings.Replace(key, ".", "/", -1))] = value
+	}
+	for path, value := range ps.Sysfs {
+		settings[path] = value
+	}
+	paths := make([]string, 0, len(settings))
+	for path := range settings {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		if err := c.priv.WriteSetting(path, settings[path]); err != nil {
+			klog.Errorf("failed to restore %s to %q: %v", path, settings[path], err)
+			failed = append(failed, path)
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("failed to restore %d of %d settings: %s", len(failed), len(paths), strings.Join(failed, ", "))
+	}
+	klog.Infof("restored the pristine values of %d settings", len(paths))
+
+	return nil
+}
+
+// pristineGet returns the pristine state as served by the /pristine API.
+func (c *Controller) pristineGet() pristineState {
+	ps, err := c.pristineLoad()
+	if err != nil {
+		ps.Error = err.Error()
+	}
+	return ps
+}
@@ -0,0 +1,174 @@
+package tuned
+
+import (
+	"bufio"     // scanner
+	"fmt"       // Errorf()
+	"io/ioutil" // ioutil.WriteFile()
+	"os"        // os.Process
+	"strconv"   // strconv.ParseUint()
+	"strings"   // strings.HasPrefix()
+	"syscall"   // syscall.Signal
+
+	"k8s.io/klog"
+
+	"github.com/openshift/openshift-tuned/pkg/layout"
+	"github.com/openshift/openshift-tuned/pkg/process"
+)
+
+// Types
+// privHelper performs all operations of openshift-tuned which need privileges.
+// Keeping them behind a single interface allows them to be moved to a separate
+// helper so that the main process can run with a reduced capability set.
+type privHelper interface {
+	// Mkdir creates directory dir under the tuned configuration tree.
+	Mkdir(dir string) error
+	// WriteFile writes a file under the tuned configuration tree.
+	WriteFile(path string, data []byte) error
+	// Swap replaces target by staged under the tuned configuration tree.
+	Swap(staged, target string) error
+	// RemoveAll removes path under the tuned configuration tree.
+	RemoveAll(path string) error
+	// WriteSetting writes value to the existing sysctl or sysfs file path.
+	WriteSetting(path string, value string) error
+	// Signal sends signal sig to the tuned process p.
+	Signal(p *os.Process, sig syscall.Signal) error
+}
+
+// privHelperLocal performs the privileged operations in-process.
+type privHelperLocal struct{}
+
+type capability struct {
+	bit  uint
+	name string
+	why  string
+}
+
+// Constants
+const (
+	procSelfStatus = "/proc/self/status"
+)
+
+// Global variables
+var (
+	// requiredCapabilities is the exact set of capabilities openshift-tuned needs.
+	// Note tuned itself is a child of openshift-tuned and needs further capabilities
+	// depending on the plugins used by the profiles, see tunedCapabilities.
+	requiredCapabilities = []capability{
+		{1, "CAP_DAC_OVERRIDE", "write tuned profiles and recommend.d files"},
+		{5, "CAP_KILL", "signal the tuned process"},
+		{21, "CAP_SYS_ADMIN", "let tuned write sysctl and sysfs settings"},
+	}
+	// tunedCapabilities are the further capabilities the tuned plugins need; these
+	// are kept in the bounding set for tuned, see CapabilitiesDrop().
+	tunedCapabilities = []capability{
+		{12, "CAP_NET_ADMIN", "let the tuned net plugin configure network devices"},
+		{16, "CAP_SYS_MODULE", "let the tuned modules plugin load kernel modules"},
+		{17, "CAP_SYS_RAWIO", "let the tuned cpu and disk plugins access MSRs and disks"},
+		{23, "CAP_SYS_NICE", "let the tuned scheduler plugin tune other processes"},
+	}
+)
+
+// Functions
+func (privHelperLocal) Mkdir(dir string) error {
+	return layout.Mkdir(dir)
+}
+
+func (privHelperLocal) WriteFile(path string, data []byte) error {
+	return layout.WriteFile(path, data)
+}
+
+func (privHelperLocal) Swap(staged, target string) error {
+	return layout.Swap(staged, target)
+}
+
+func (privHelperLocal) RemoveAll(path string) error {
+	return os.RemoveAll(path)
+}
+
+func (privHelperLocal) WriteSetting(path string, value string) error {
+	// Kernel settings files cannot be replaced, write them in place
+	return ioutil.WriteFile(path, []byte(value+"\n"), 0644)
+}
+
+func (privHelperLocal) Signal(p *os.Process, sig syscall.Signal) error {
+	return p.Signal(sig)
+}
+
+// capSet returns the capability set field of /proc/self/status, e.g. CapEff.
+func capSet(field string) (uint64, error) {
+	f, err := os.Open(procSelfStatus)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, field+":") {
+			continue
+		}
+		return strconv.ParseUint(strings.TrimSpace(strings.TrimPrefix(line, field+":")), 16, 64)
+	}
+	return 0, fmt.Errorf("%s not found in %q", field, procSelfStatus)
+}
+
+// CapabilitiesCheck verifies openshift-tuned runs with all the required capabilities.
+func CapabilitiesCheck() error {
+	var missing []string
+
+	if err := process.PlatformCheck(); err != nil {
+		return err
+	}
+	capEff, err := capSet("CapEff")
+	if err != nil {
+		return fmt.Errorf("failed to get effective capabilities: %v", err)
+	}
+	for _, rc := range requiredCapabilities {
+		if capEff&(1<<rc.bit) == 0 {
+			klog.Errorf("missing capability %s needed to %s", rc.name, rc.why)
+			missing = append(missing, rc.name)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("missing required capabilities: %s", strings.Join(missing, ", "))
+	}
+
+	return nil
+}
+
+// CapabilitiesDrop drops the capabilities neither openshift-tuned nor the tuned
+// plugins need, see requiredCapabilities and tunedCapabilities, from the bounding
+// set and executes openshift-tuned again, so that every thread starts with the
+// reduced set; running as root, a program is granted the bounding set on exec.
+// tuned and the other commands openshift-tuned runs inherit the bounding set.
+// Capabilities are per thread, and the threads the Go runtime starts early cannot
+// all be changed in-process.  CapabilitiesDrop returns nil without executing
+// openshift-tuned again if nothing is left to drop, e.g. in the executed
+// openshift-tuned, or when not running as root.
+func CapabilitiesDrop() error {
+	var keep uint64
+
+	if os.Geteuid() != 0 {
+		klog.V(1).Infof("not running as root, keeping the capabilities for tuned")
+		return nil
+	}
+	for _, caps := range [][]capability{requiredCapabilities, tunedCapabilities} {
+		for _, c := range caps {
+			keep |= 1 << c.bit
+		}
+	}
+	bounding, err := capSet("CapBnd")
+	if err != nil {
+		return fmt.Errorf("failed to get the capability bounding set: %v", err)
+	}
+	if bounding&^keep == 0 {
+		klog.V(1).Infof("capability bounding set %#x holds the required capabilities only", bounding)
+		return nil
+	}
+	klog.Infof("dropping capabilities %#x from the bounding set and restarting", bounding&^keep)
+	if err := capabilitiesExec(bounding &^ keep); err != nil {
+		return fmt.Errorf("failed to drop the unneeded capabilities: %v", err)
+	}
+	return nil
+}
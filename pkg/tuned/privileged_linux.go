@@ -0,0 +1,40 @@
+// +build linux
+
+package tuned
+
+import (
+	"fmt"     // Errorf()
+	"os"      // os.Executable()
+	"runtime" // runtime.LockOSThread()
+	"syscall" // syscall.Exec()
+
+	"golang.org/x/sys/unix"
+)
+
+// Functions
+// capabilitiesExec drops the capabilities drop from the bounding set of the
+// current thread, see prctl(2) PR_CAPBSET_DROP, and executes openshift-tuned
+// again from that thread.  Returns only on failure.
+func capabilitiesExec(drop uint64) error {
+	// The bounding set is per thread; drop it and exec from the same one
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	for bit := uint(0); bit < 64; bit++ {
+		if drop&(1<<bit) == 0 {
+			continue
+		}
+		if err := unix.Prctl(unix.PR_CAPBSET_DROP, uintptr(bit), 0, 0, 0); err != nil {
+			return fmt.Errorf("failed to drop capability %d from the bounding set: %v", bit, err)
+		}
+	}
+	// Not /proc/self/exe, which would become the command name
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	if err := syscall.Exec(exe, os.Args, os.Environ()); err != nil {
+		return fmt.Errorf("failed to execute %s: %v", exe, err)
+	}
+	return nil
+}
@@ -0,0 +1,14 @@
+// +build !linux
+
+package tuned
+
+import (
+	"github.com/openshift/openshift-tuned/pkg/process"
+)
+
+// Functions
+// capabilitiesExec returns process.ErrUnsupportedPlatform, capabilities are
+// Linux-specific.
+func capabilitiesExec(drop uint64) error {
+	return process.ErrUnsupportedPlatform
+}
@@ -0,0 +1,76 @@
+package tuned
+
+import (
+	"github.com/openshift/openshift-tuned/pkg/profile"
+)
+
+// Types
+// profileInfo describes a tuned profile available on the node.
+type profileInfo struct {
+	Name   string `json:"name"`
+	Source string `json:"source"`
+	Hash   string `json:"hash,omitempty"`
+	// the profile is the active profile or included by it
+	InActiveChain bool `json:"inActiveChain"`
+}
+
+// profilesResponse is the response of the /profiles API.
+type profilesResponse struct {
+	ActiveProfile string        `json:"activeProfile,omitempty"`
+	Profiles      []profileInfo `json:"profiles"`
+	Error         string        `json:"error,omitempty"`
+}
+
+// Constants
+const (
+	profileSourceTuned     = "tuned"     // extracted from the rendered Tuned object
+	profileSourceExtracted = "extracted" // extracted before openshift-tuned started
+	profileSourceSystem    = "system"    // shipped with tuned
+)
+
+// Functions
+// profilesGet returns the inventory of the tuned profiles available on the node.
+func (c *Controller) profilesGet() profilesResponse {
+	r := profilesResponse{Profiles: []profileInfo{}}
+
+	written, system, err := c.store.ListProfiles()
+	if err != nil {
+		r.Error = err.Error()
+		return r
+	}
+
+	inChain := map[string]bool{}
+	if r.ActiveProfile, err = c.store.ActiveProfile(); err != nil {
+		r.Error = err.Error()
+	} else if len(r.ActiveProfile) > 0 {
+		names, err := profile.ChainNames(c.store, r.ActiveProfile)
+		if err != nil {
+			r.Error = err.Error()
+		}
+		for _, name := range names {
+			inChain[name] = true
+		}
+	}
+
+	seen := map[string]bool{}
+	add := func(name, source string) {
+		if seen[name] {
+			// Shadowed by an extracted profile
+			return
+		}
+		seen[name] = true
+		pi := profileInfo{Name: name, Source: source, InActiveChain: inChain[name]}
+		if data, err := c.store.ReadProfile(name); err == nil {
+			pi.Hash = profile.Hash(data)
+		}
+		r.Profiles = append(r.Profiles, pi)
+	}
+	for _, name := range written {
+		add(name, c.status.profileSource(name))
+	}
+	for _, name := range system {
+		add(name, profileSourceSystem)
+	}
+
+	return r
+}
@@ -0,0 +1,41 @@
+package tuned
+
+import (
+	"io/ioutil" // ioutil.ReadFile()
+	"strings"   // strings.HasPrefix()
+
+	"github.com/openshift/openshift-tuned/pkg/profile"
+)
+
+// Constants
+const (
+	sysKernelRealtime = "/sys/kernel/realtime"
+	procKernelVersion = "/proc/sys/kernel/version"
+)
+
+// Functions
+// kernelRealtime returns true if the node runs a realtime (PREEMPT_RT) kernel.
+func kernelRealtime() bool {
+	if data, err := ioutil.ReadFile(sysKernelRealtime); err == nil {
+		return strings.TrimSpace(string(data)) == "1"
+	}
+	if data, err := ioutil.ReadFile(procKernelVersion); err == nil {
+		return strings.Contains(string(data), "PREEMPT_RT") || strings.Contains(string(data), "PREEMPT RT")
+	}
+	return false
+}
+
+// profileRequiresRealtime returns true if tuned profile profileName includes one of
+// the realtime profiles shipped with tuned, which only work on a realtime kernel.
+func (c *Controller) profileRequiresRealtime(profileName string) (bool, error) {
+	names, err := profile.ChainNames(c.store, profileName)
+	if err != nil {
+		return false, err
+	}
+	for _, name := range names {
+		if strings.HasPrefix(name, "realtime") {
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,87 @@
+package tuned
+
+import (
+	"fmt"       // Errorf()
+	"io/ioutil" // ioutil.ReadFile()
+	"strings"   // strings.Fields()
+
+	"k8s.io/klog"
+
+	"github.com/openshift/openshift-tuned/pkg/profile"
+)
+
+// Constants
+const (
+	procCmdline = "/proc/cmdline"
+)
+
+// Functions
+// rebootRequiredCheck returns the kernel command-line parameters requested by the
+// [bootloader] sections of tuned profile profileName (and the profiles it includes)
+// which are missing on the running kernel.  A non-empty list means the node needs
+// to be rebooted for the profile to be fully applied.
+func (c *Controller) rebootRequiredCheck(profileName string) ([]string, error) {
+	var missing []string
+
+	chain, err := profile.ChainLoad(c.store, profileName)
+	if err != nil {
+		return nil, err
+	}
+
+	cmdline, err := ioutil.ReadFile(procCmdline)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %q: %v", procCmdline, err)
+	}
+	running := map[string]bool{}
+	for _, param := range strings.Fields(string(cmdline)) {
+		running[param] = true
+	}
+
+	for _, conf := range chain {
+		bootloader, ok := conf["bootloader"]
+		if !ok {
+			continue
+		}
+		for option, value := range bootloader {
+			if !strings.HasPrefix(option, "cmdline") {
+				continue
+			}
+			for _, param := range strings.Fields(value) {
+				if strings.Contains(param, "${") {
+					// Variables are expanded by tuned, we cannot check these
+					continue
+				}
+				if !running[param] {
+					missing = append(missing, param)
+				}
+			}
+		}
+	}
+
+	return missing, nil
+}
+
+// rebootRequiredUpdate checks whether tuned profile profileName needs a reboot to be
+// fully applied and surfaces the result via the API and a node annotation.
+func (c *Controller) rebootRequiredUpdate(tuned *tunedState, profileName string) {
+	missing, err := c.rebootRequiredCheck(profileName)
+	if err != nil {
+		klog.Errorf("failed to check whether profile %q requires a reboot: %v", profileName, err)
+		return
+	}
+	rebootRequired := len(missing) > 0
+	if rebootRequired {
+		klog.Warningf("profile %q requires a reboot, kernel parameters missing: %s", profileName, strings.Join(missing, " "))
+	}
+
+	changed := c.status.setRebootRequired(profileName, missing)
+	if !changed || tuned.coreClient == nil {
+		return
+	}
+	err = nodeAnnotate(tuned.coreClient, tuned.nodeName, map[string]string{
+		nodeAnnotationRebootRequired: fmt.Sprintf("%t", rebootRequired),
+	})
+	if err != nil {
+		klog.Errorf("%s", err.Error())
+	}
+}
@@ -0,0 +1,138 @@
+package tuned
+
+import (
+	"bytes"         // bytes.Buffer
+	"fmt"           // Sprintf()
+	"path/filepath" // filepath.Join()
+	"sync"          // sync.Mutex
+
+	"k8s.io/klog"
+
+	"github.com/openshift/openshift-tuned/pkg/metrics"
+	"github.com/openshift/openshift-tuned/pkg/recommend"
+)
+
+// Types
+// recommendedProfileStatus describes the recommended profile and the reason for it.
+type recommendedProfileStatus struct {
+	// profile recommended by tuned
+	Profile string `json:"profile"`
+	// the node's Profile object and the tuned profile it requests
+	ProfileObject    string `json:"profileObject,omitempty"`
+	RequestedProfile string `json:"requestedProfile,omitempty"`
+	// the recommend rule which matched
+	RuleFile       string            `json:"ruleFile,omitempty"`
+	RuleConditions map[string]string `json:"ruleConditions,omitempty"`
+	// all the recommend rules evaluated; only set on request
+	Explanation *recommend.Explanation `json:"explanation,omitempty"`
+	Error       string                 `json:"error,omitempty"`
+}
+
+// recommendCache is the profile tuned recommended for the recommend inputs
+// hashed by key, see recommendCached().
+type recommendCache struct {
+	sync.Mutex
+	key     string
+	profile string
+	hits    int
+	misses  int
+}
+
+// Functions
+// recommendDirs returns the recommend.d directories in the order tuned reads them.
+func (c *Controller) recommendDirs() []string {
+	return []string{filepath.Join(c.opts.SystemProfilesDir, "recommend.d"), c.recommendDir}
+}
+
+// RecommendExplain evaluates the recommend rules like tuned does, i.e. until the
+// first matching rule, and returns the results of all evaluated rules.
+func (c *Controller) RecommendExplain() recommend.Explanation {
+	return recommend.Explain(c.recommendDirs()...)
+}
+
+// recommendEvaluate returns the profile recommended by the first matching recommend rule.
+func (c *Controller) recommendEvaluate() (string, error) {
+	return recommend.Evaluate(c.recommendDirs()...)
+}
+
+// recommendedProfileGet returns the profile recommended by tuned and the reason
+// why it is recommended.  With explain, the results of all recommend rules
+// evaluated are returned as well.
+func (c *Controller) recommendedProfileGet(explain bool) recommendedProfileStatus {
+	var rps recommendedProfileStatus
+
+	if explain {
+		re := c.RecommendExplain()
+		rps.Explanation = &re
+	}
+
+	c.status.RLock()
+	rps.ProfileObject = c.status.profileObject
+	rps.RequestedProfile = c.status.requestedProfile
+	c.status.RUnlock()
+
+	profile, err := c.runner.Recommend()
+	if err != nil {
+		rps.Error = err.Error()
+		return rps
+	}
+	rps.Profile = profile
+
+	rule, err := recommend.Match(c.recommendDirs()...)
+	if err != nil {
+		rps.Error = err.Error()
+		return rps
+	}
+	rps.RuleFile = rule.File
+	rps.RuleConditions = rule.Options
+	if rule.Profile != profile {
+		rps.Error = fmt.Sprintf("matching recommend rule selects profile %q, tuned recommends %q", rule.Profile, profile)
+	}
+
+	return rps
+}
+
+// recommendCached returns the profile recommended by tuned.  tuned is asked only
+// if the recommend rules or the files they read changed since it was last asked.
+func (c *Controller) recommendCached() (string, error) {
+	rc := &c.recommendCache
+
+	if !c.features.Enabled(featureRecommendCache) {
+		return c.runner.Recommend()
+	}
+
+	key, err := recommend.InputsHash(c.recommendDirs()...)
+	if err != nil {
+		klog.V(1).Infof("not caching the recommended profile: %v", err)
+	}
+	rc.Lock()
+	if len(key) > 0 && key == rc.key {
+		rc.hits++
+		profile := rc.profile
+		rc.Unlock()
+		klog.V(2).Infof("recommend inputs unchanged, recommended profile %q", profile)
+		return profile, nil
+	}
+	rc.Unlock()
+
+	profile, err := c.runner.Recommend()
+	rc.Lock()
+	defer rc.Unlock()
+	rc.misses++
+	rc.key, rc.profile = "", ""
+	if err == nil {
+		rc.key, rc.profile = key, profile
+	}
+	return profile, err
+}
+
+// recommendMetricsCollect writes the recommend cache metrics.
+func (c *Controller) recommendMetricsCollect(buf *bytes.Buffer) {
+	rc := &c.recommendCache
+	rc.Lock()
+	hits, misses := rc.hits, rc.misses
+	rc.Unlock()
+
+	metrics.Write(buf, "recommend_cache_hits_total", "counter", "Number of recommended profiles served from the cache.", metrics.Sample{Value: float64(hits)})
+	metrics.Write(buf, "recommend_cache_misses_total", "counter", "Number of times tuned was asked for the recommended profile.", metrics.Sample{Value: float64(misses)})
+}
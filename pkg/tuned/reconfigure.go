@@ -0,0 +1,166 @@
+package tuned
+
+import (
+	"fmt"           // Errorf()
+	"os"            // os.Stat()
+	"path/filepath" // filepath.Walk()
+	"reflect"       // reflect.DeepEqual()
+	"time"          // time.NewTicker()
+
+	"github.com/fsnotify/fsnotify"
+	"k8s.io/klog"
+)
+
+// Types
+// loopTimer is a ticker of the event loop whose period depends on Options, so
+// that reconfigure() can re-arm it.  A stopped loopTimer never fires.
+type loopTimer struct {
+	ticker *time.Ticker
+	C      <-chan time.Time
+}
+
+// Functions
+// arm (re)starts t with period d; 0 stops it.
+func (t *loopTimer) arm(d time.Duration) {
+	t.stop()
+	if d > 0 {
+		t.ticker = time.NewTicker(d)
+		t.C = t.ticker.C
+	}
+}
+
+func (t *loopTimer) stop() {
+	if t.ticker != nil {
+		t.ticker.Stop()
+		t.ticker = nil
+	}
+	t.C = nil
+}
+
+// attachInterval returns the period of checking whether a standalone
+// openshift-tuned can attach to the apiserver, 0 if it does not check.
+func (c *Controller) attachInterval() time.Duration {
+	if !c.opts.Standalone || c.attached || !c.features.Enabled(featureStandaloneAttach) {
+		return 0
+	}
+	return c.opts.AttachInterval
+}
+
+// watchRemove forgets path and the directories below it, see watchAdd().
+func watchRemove(wFs *fsnotify.Watcher, path string) {
+	// Fails for files and directories not watched
+	wFs.Remove(path)
+	fi, err := os.Stat(path)
+	if err != nil || !fi.IsDir() {
+		return
+	}
+	filepath.Walk(path, func(p string, fi os.FileInfo, err error) error {
+		if err == nil && fi.IsDir() && p != path {
+			wFs.Remove(p)
+		}
+		return nil
+	})
+}
+
+// watchesAdd watches the files of Options.WatchFiles, the local profile sources
+// and the directory of the configuration file.
+func (c *Controller) watchesAdd(wFs *fsnotify.Watcher) error {
+	for _, w := range c.watches {
+		if err := watchAdd(wFs, w.path); err != nil {
+			return errCategorize(errCategoryFS, fmt.Errorf("failed to start watching %q: %v", w.path, err))
+		}
+	}
+	for _, path := range c.sourcesPaths() {
+		// Profile sources may be missing, e.g. the ConfigMap file with the latest NTO
+		if err := watchAdd(wFs, path); err != nil {
+			klog.V(1).Infof("not watching profile source %q: %v", path, err)
+		}
+	}
+	if len(c.opts.ConfigFile) > 0 {
+		// Watch the directory, the configuration file may be replaced (e.g. a ConfigMap volume)
+		configDir := filepath.Dir(c.opts.ConfigFile)
+		if err := wFs.Add(configDir); err != nil {
+			return errCategorize(errCategoryFS, fmt.Errorf("failed to start watching %q: %v", configDir, err))
+		}
+	}
+	return nil
+}
+
+// watchesRemove forgets the files watchesAdd() watches.
+func (c *Controller) watchesRemove(wFs *fsnotify.Watcher) {
+	for _, w := range c.watches {
+		watchRemove(wFs, w.path)
+	}
+	for _, path := range c.sourcesPaths() {
+		watchRemove(wFs, path)
+	}
+}
+
+// reconfigure applies the options of o which are safe to change while the
+// event loop runs; it runs on the event loop.  These are FeatureGates,
+// AttachInterval, WatchFiles, WatchQuiescence, the profile sources
+// (ProfileSources, SupportConfigMap and ProfilesConfigMap), ReloadVerifyTimeout,
+// ReloadFailuresMax, RevertDelay and PartialReload.  Invalid values are logged
+// and the running ones kept.  The other options take effect on a restart.
+func (c *Controller) reconfigure(o Options, tuned *tunedState, wFs *fsnotify.Watcher, attach, poll *loopTimer) {
+	// Read by the event loop only
+	c.opts.WatchQuiescence = o.WatchQuiescence
+	c.opts.ReloadVerifyTimeout = o.ReloadVerifyTimeout
+	c.opts.ReloadFailuresMax = o.ReloadFailuresMax
+	c.opts.RevertDelay = o.RevertDelay
+	c.opts.PartialReload = o.PartialReload
+
+	if o.FeatureGates != c.opts.FeatureGates {
+		if err := c.features.Reset(o.FeatureGates); err != nil {
+			klog.Errorf("keeping feature gates %q: %v", c.opts.FeatureGates, err)
+		} else {
+			c.opts.FeatureGates = o.FeatureGates
+			klog.Infof("feature gates set to %s", c.features)
+		}
+	}
+	if o.AttachInterval != c.opts.AttachInterval || (attach.C == nil) != (c.attachInterval() == 0) {
+		c.opts.AttachInterval = o.AttachInterval
+		attach.arm(c.attachInterval())
+	}
+
+	if reflect.DeepEqual(o.WatchFiles, c.opts.WatchFiles) && reflect.DeepEqual(o.ProfileSources, c.opts.ProfileSources) &&
+		o.SupportConfigMap == c.opts.SupportConfigMap && o.ProfilesConfigMap == c.opts.ProfilesConfigMap {
+		return
+	}
+	watches, errWatches := watchFilesParse(o.WatchFiles)
+	if errWatches != nil {
+		klog.Errorf("keeping the watched files: %v", errWatches)
+	}
+	c.watchesRemove(wFs)
+	// profilesSync() reads the sources in the pipeline, also on the informer
+	// goroutines
+	err := c.pipeline.do("sources", func() error {
+		sources, supportConfigMap, profilesConfigMap := c.opts.ProfileSources, c.opts.SupportConfigMap, c.opts.ProfilesConfigMap
+		c.opts.ProfileSources, c.opts.SupportConfigMap, c.opts.ProfilesConfigMap = o.ProfileSources, o.SupportConfigMap, o.ProfilesConfigMap
+		if err := c.sourcesInit(); err != nil {
+			c.opts.ProfileSources, c.opts.SupportConfigMap, c.opts.ProfilesConfigMap = sources, supportConfigMap, profilesConfigMap
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		klog.Errorf("keeping the tuned profile sources: %v", err)
+	}
+	previous := c.watches
+	if errWatches == nil {
+		c.watches = watches
+	}
+	if err := c.watchesAdd(wFs); err != nil {
+		klog.Errorf("keeping the watched files: %v", err)
+		c.watchesRemove(wFs)
+		c.watches = previous
+		if err := c.watchesAdd(wFs); err != nil {
+			klog.Errorf("%s", err.Error())
+		}
+	} else if errWatches == nil {
+		c.opts.WatchFiles = o.WatchFiles
+	}
+	poll.arm(c.sourcesPollInterval())
+	// Extract the profiles of the changed sources
+	tuned.change.cfg = true
+}
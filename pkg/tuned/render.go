@@ -0,0 +1,47 @@
+package tuned
+
+import (
+	"github.com/openshift/openshift-tuned/pkg/profile"
+	"github.com/openshift/openshift-tuned/pkg/recommend"
+)
+
+// Types
+// RenderResult is the outcome of Render().
+type RenderResult struct {
+	// Problems are the problems the profiles extraction checks found.
+	Problems []profile.Problem
+	// Recommendation explains the profile tuned would recommend.
+	Recommendation recommend.Explanation
+}
+
+// Functions
+// Render extracts profiles like the profiles extraction does, but without
+// running tuned, and makes tuned recommend profile requested if not empty.  It
+// writes to opts.ProfilesDir, i.e. it is meant to run on a scratch directory.
+// The files of the recommend rule conditions are read by read.
+func (c *Controller) Render(profiles map[string]string, requested string, read recommend.FileReader) (RenderResult, error) {
+	var r RenderResult
+
+	problems, err := c.Lint(profiles)
+	r.Problems = problems
+	if err != nil {
+		return r, err
+	}
+	written := map[string]string{}
+	for name, data := range profiles {
+		if !profile.IsSignature(name) {
+			written[name] = data
+		}
+	}
+	if err = c.store.WriteProfiles(written); err != nil {
+		return r, err
+	}
+	if len(requested) > 0 {
+		if err = c.tunedRecommendFileWrite(requested); err != nil {
+			return r, err
+		}
+	}
+	r.Recommendation = recommend.ExplainWith(read, c.recommendDirs()...)
+
+	return r, nil
+}
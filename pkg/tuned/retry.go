@@ -0,0 +1,56 @@
+package tuned
+
+import (
+	"bytes"     // bytes.Buffer
+	"fmt"       // Errorf()
+	"math/rand" // rand.Float64()
+	"time"      // time.Duration
+
+	"github.com/openshift/openshift-tuned/pkg/metrics"
+)
+
+// Functions
+// retryValidate checks the retry options of the event loop.
+func (c *Controller) retryValidate() error {
+	switch {
+	case c.opts.RetryInitial < time.Second:
+		return fmt.Errorf("retry initial period %v must be at least 1s", c.opts.RetryInitial)
+	case c.opts.RetryMax < c.opts.RetryInitial:
+		return fmt.Errorf("retry maximum period %v must not be shorter than the initial period %v", c.opts.RetryMax, c.opts.RetryInitial)
+	case c.opts.RetryFactor < 1:
+		return fmt.Errorf("retry factor %v must be at least 1", c.opts.RetryFactor)
+	case c.opts.RetryJitter < 0 || c.opts.RetryJitter >= 1:
+		return fmt.Errorf("retry jitter %v must be at least 0 and less than 1", c.opts.RetryJitter)
+	}
+	return nil
+}
+
+// retryNext returns the retry period following period.
+func (c *Controller) retryNext(period time.Duration) time.Duration {
+	next := time.Duration(float64(period) * c.opts.RetryFactor)
+	if next > c.opts.RetryMax || next < period {
+		next = c.opts.RetryMax
+	}
+	return next
+}
+
+// retryJittered returns period randomized by +/- opts.RetryJitter, so that the
+// daemons of a large cluster do not retry in lockstep.
+func (c *Controller) retryJittered(period time.Duration) time.Duration {
+	if c.opts.RetryJitter == 0 {
+		return period
+	}
+	return time.Duration(float64(period) * (1 + c.opts.RetryJitter*(2*rand.Float64()-1)))
+}
+
+// retryMetricsCollect writes the effective retry options.
+func (c *Controller) retryMetricsCollect(buf *bytes.Buffer) {
+	metrics.Write(buf, "retry_initial_seconds", "gauge", "Initial period of retrying the event loop after an error.",
+		metrics.Sample{Value: c.opts.RetryInitial.Seconds()})
+	metrics.Write(buf, "retry_max_seconds", "gauge", "Maximum period of retrying the event loop after an error.",
+		metrics.Sample{Value: c.opts.RetryMax.Seconds()})
+	metrics.Write(buf, "retry_factor", "gauge", "Factor the retry period grows by after every error.",
+		metrics.Sample{Value: c.opts.RetryFactor})
+	metrics.Write(buf, "retry_jitter", "gauge", "Fraction the retry period is randomized by.",
+		metrics.Sample{Value: c.opts.RetryJitter})
+}
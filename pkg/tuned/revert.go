@@ -0,0 +1,77 @@
+package tuned
+
+import (
+	"time" // time.Time
+
+	"k8s.io/klog"
+)
+
+// Types
+// revertPending is a switch back to the previously requested tuned profile
+// delayed by opts.RevertDelay.
+type revertPending struct {
+	Profile string    `json:"profile"`
+	At      time.Time `json:"at"`
+	object  string
+}
+
+// Functions
+// profileRequested handles tuned profile profileName requested by Profile object.
+// A switch back to the profile requested before the current one is delayed by
+// opts.RevertDelay and dropped if the current profile is requested again in the
+// meantime, so that nodes running recurring workloads do not thrash.
+func (c *Controller) profileRequested(tuned *tunedState, object string, profileName string) {
+	c.status.RLock()
+	current := c.status.requestedProfile
+	c.status.RUnlock()
+
+	if profileName == current {
+		if tuned.revert != nil {
+			klog.Infof("profile %q requested again, cancelled the revert to profile %q", profileName, tuned.revert.Profile)
+			tuned.revert = nil
+			c.status.setRevertPending(nil)
+		}
+		return
+	}
+	if c.opts.RevertDelay > 0 && len(current) > 0 && profileName == tuned.previousRequested {
+		if tuned.revert != nil && tuned.revert.Profile == profileName {
+			return
+		}
+		tuned.revert = &revertPending{Profile: profileName, At: time.Now().Add(c.opts.RevertDelay), object: object}
+		c.status.setRevertPending(tuned.revert)
+		klog.Infof("delaying the revert from profile %q to profile %q by %v", current, profileName, c.opts.RevertDelay)
+		return
+	}
+	tuned.revert = nil
+	c.status.setRevertPending(nil)
+	c.profileRequestApply(tuned, object, profileName)
+}
+
+// profileRequestApply makes tuned recommend profile profileName requested by
+// Profile object.
+func (c *Controller) profileRequestApply(tuned *tunedState, object string, profileName string) {
+	if c.drainProfileActive(tuned) {
+		// Recommended once the node is uncordoned
+		klog.Infof("node is draining, postponing profile %q", profileName)
+	} else if err := c.tunedRecommendFileWrite(profileName); err != nil {
+		klog.Errorf("%s", err.Error())
+		return
+	}
+	c.status.RLock()
+	tuned.previousRequested = c.status.requestedProfile
+	c.status.RUnlock()
+	c.status.setRequestedProfile(object, profileName)
+	tuned.change.profile = true
+}
+
+// revertCheck applies a delayed revert once its delay expired.
+func (c *Controller) revertCheck(tuned *tunedState) {
+	if tuned.revert == nil || time.Now().Before(tuned.revert.At) {
+		return
+	}
+	r := tuned.revert
+	tuned.revert = nil
+	c.status.setRevertPending(nil)
+	klog.Infof("reverting to profile %q after %v", r.Profile, c.opts.RevertDelay)
+	c.profileRequestApply(tuned, r.object, r.Profile)
+}
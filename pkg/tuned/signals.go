@@ -0,0 +1,63 @@
+package tuned
+
+import (
+	"fmt"       // Errorf()
+	"os"        // os.Signal
+	"os/signal" // signal.Notify()
+	"strings"   // strings.ToUpper()
+	"syscall"   // syscall.Signal
+
+	"k8s.io/klog"
+)
+
+// Functions
+// forwardSignalsParse parses the signals to forward to tuned.
+func forwardSignalsParse(names []string) ([]syscall.Signal, error) {
+	var sigs []syscall.Signal
+
+	for _, name := range names {
+		for _, n := range strings.Split(name, ",") {
+			if len(strings.TrimSpace(n)) == 0 {
+				continue
+			}
+			sig, err := signalParse(n)
+			if err != nil {
+				return nil, err
+			}
+			if signalsNotForwardable[sig] {
+				return nil, fmt.Errorf("signal %s cannot be forwarded to tuned", signalName(sig))
+			}
+			sigs = append(sigs, sig)
+		}
+	}
+	return sigs, nil
+}
+
+// signalsForward forwards sigs received by openshift-tuned to tuned until stop is closed.
+func (c *Controller) signalsForward(sigs []syscall.Signal, stop <-chan struct{}) {
+	ch := make(chan os.Signal, 1)
+	for _, sig := range sigs {
+		signal.Notify(ch, sig)
+	}
+	defer signal.Stop(ch)
+
+	for {
+		select {
+		case <-stop:
+			return
+		case s := <-ch:
+			sig := s.(syscall.Signal)
+			pid := c.runner.Pid()
+			if pid == 0 {
+				klog.Warningf("received %s, but tuned does not run", signalName(sig))
+				continue
+			}
+			klog.Infof("forwarding %s to tuned PID %d", signalName(sig), pid)
+			err := c.runner.Signal(sig)
+			c.audit(auditActionSignal, auditActorSignalForward, fmt.Sprintf("%s to PID %d", signalName(sig), pid), err)
+			if err != nil {
+				klog.Errorf("failed to forward %s to tuned: %v", signalName(sig), err)
+			}
+		}
+	}
+}
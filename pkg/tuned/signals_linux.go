@@ -0,0 +1,51 @@
+// +build linux
+
+package tuned
+
+import (
+	"fmt"     // Errorf()
+	"strconv" // strconv.Atoi()
+	"strings" // strings.ToUpper()
+	"syscall" // syscall.Signal
+
+	"golang.org/x/sys/unix"
+)
+
+// Global variables
+var (
+	// signals openshift-tuned handles itself or which cannot be caught
+	signalsNotForwardable = map[syscall.Signal]bool{
+		syscall.SIGHUP:  true,
+		syscall.SIGINT:  true,
+		syscall.SIGTERM: true,
+		syscall.SIGQUIT: true,
+		syscall.SIGKILL: true,
+		syscall.SIGSTOP: true,
+		syscall.SIGCHLD: true,
+	}
+)
+
+// Functions
+// signalParse parses a signal name ("SIGUSR1", "USR1") or number.
+func signalParse(s string) (syscall.Signal, error) {
+	if n, err := strconv.Atoi(s); err == nil {
+		if unix.SignalName(syscall.Signal(n)) == "" {
+			return 0, fmt.Errorf("unknown signal %q", s)
+		}
+		return syscall.Signal(n), nil
+	}
+	name := strings.ToUpper(strings.TrimSpace(s))
+	if !strings.HasPrefix(name, "SIG") {
+		name = "SIG" + name
+	}
+	sig := unix.SignalNum(name)
+	if sig == 0 {
+		return 0, fmt.Errorf("unknown signal %q", s)
+	}
+	return sig, nil
+}
+
+// signalName returns the name of sig, e.g. "SIGUSR1".
+func signalName(sig syscall.Signal) string {
+	return unix.SignalName(sig)
+}
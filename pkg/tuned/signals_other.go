@@ -0,0 +1,25 @@
+// +build !linux
+
+package tuned
+
+import (
+	"syscall" // syscall.Signal
+
+	"github.com/openshift/openshift-tuned/pkg/process"
+)
+
+// Global variables
+var (
+	signalsNotForwardable = map[syscall.Signal]bool{}
+)
+
+// Functions
+// signalParse returns process.ErrUnsupportedPlatform, signals are only forwarded on Linux.
+func signalParse(s string) (syscall.Signal, error) {
+	return 0, process.ErrUnsupportedPlatform
+}
+
+// signalName returns the description of sig.
+func signalName(sig syscall.Signal) string {
+	return sig.String()
+}
@@ -0,0 +1,111 @@
+package tuned
+
+import (
+	"fmt"           // Errorf()
+	"io/ioutil"     // ioutil.ReadFile()
+	"os"            // os.RemoveAll()
+	"path/filepath" // filepath.Join()
+	"strings"       // strings.TrimSpace()
+
+	"k8s.io/klog"
+
+	"github.com/openshift/openshift-tuned/pkg/layout"
+	"github.com/openshift/openshift-tuned/pkg/profile"
+)
+
+// Constants
+const (
+	snapshotProfileFile = "profile"
+)
+
+// Functions
+// snapshotDir returns the directory holding the last known-good configuration.
+func (c *Controller) snapshotDir() string {
+	return filepath.Join(c.opts.RunDir, "last-known-good")
+}
+
+// snapshotTake saves the content of the extracted profiles profileName consists of,
+// so that tuned can be rolled back to profileName even if the profiles are later
+// overwritten by a bad rollout.  Profiles shipped with tuned are not saved.
+func (c *Controller) snapshotTake(profileName string) error {
+	names, err := profile.ChainNames(c.store, profileName)
+	if err != nil {
+		return err
+	}
+
+	dir := c.snapshotDir()
+	tmp := dir + ".tmp"
+	if err = os.RemoveAll(tmp); err != nil {
+		return err
+	}
+	if err = layout.Mkdir(tmp); err != nil {
+		return fmt.Errorf("failed to create snapshot directory %q: %v", tmp, err)
+	}
+	for _, name := range names {
+		if !c.store.HasProfile(name) {
+			continue
+		}
+		data, err := c.store.ReadProfile(name)
+		if err != nil {
+			return err
+		}
+		if err = layout.Mkdir(filepath.Join(tmp, name)); err != nil {
+			return err
+		}
+		if err = layout.WriteFile(filepath.Join(tmp, name, profile.ConfFile), []byte(data)); err != nil {
+			return err
+		}
+	}
+	if err = layout.WriteFile(filepath.Join(tmp, snapshotProfileFile), []byte(profileName+"\n")); err != nil {
+		return err
+	}
+
+	// Replace the previous snapshot
+	if err = os.RemoveAll(dir); err != nil {
+		return err
+	}
+	if err = os.Rename(tmp, dir); err != nil {
+		return err
+	}
+	klog.V(1).Infof("saved last known-good profile %q", profileName)
+
+	return nil
+}
+
+// snapshotRestore restores the profiles of the last known-good configuration and
+// makes tuned recommend and apply its profile.
+func (c *Controller) snapshotRestore(tuned *tunedState) error {
+	dir := c.snapshotDir()
+
+	c.status.setState(stateRollingBack, "restoring the last known-good configuration")
+
+	data, err := ioutil.ReadFile(filepath.Join(dir, snapshotProfileFile))
+	if err != nil {
+		return fmt.Errorf("no last known-good configuration to roll back to: %v", err)
+	}
+	profileName := strings.TrimSpace(string(data))
+
+	fis, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, fi := range fis {
+		if !fi.IsDir() {
+			continue
+		}
+		data, err := ioutil.ReadFile(filepath.Join(dir, fi.Name(), profile.ConfFile))
+		if err != nil {
+			return err
+		}
+		if err = c.store.WriteProfile(fi.Name(), string(data)); err != nil {
+			return err
+		}
+	}
+	if err = c.tunedRecommendFileWrite(profileName); err != nil {
+		return err
+	}
+	tuned.change.profile = true
+	klog.Infof("rolled back to the last known-good profile %q", profileName)
+
+	return nil
+}
@@ -0,0 +1,173 @@
+package tuned
+
+import (
+	"encoding/json" // json.Marshal()
+	"fmt"           // Errorf()
+	"io"            // io.EOF
+	"net"           // net.Conn
+	"strings"       // strings.TrimSpace()
+	"time"          // time.Second
+
+	"k8s.io/klog"
+)
+
+// Constants
+const (
+	sockCommandMax = 64 // maximum length of a control socket command
+
+	// SockAckStop and SockAckStopNoRollback acknowledge the "stop" and
+	// "stop-norollback" control socket commands.
+	SockAckStop           = "ok"
+	SockAckStopNoRollback = "ok-norollback"
+
+	// response to the clients not allowed to use the control socket
+	sockRejected = "unauthorized"
+	// time to wait for another instance listening on the control socket
+	sockProbeTimeout = time.Second
+	// time a client has to send its command
+	sockReadTimeout = 5 * time.Second
+)
+
+// Functions
+func (c *Controller) sockWriteJSON(s *sockAccepted, v interface{}) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		klog.Errorf("failed to encode a response via %q: %v", c.opts.Socket, err)
+		return
+	}
+	if _, err = s.conn.Write(append(data, '\n')); err != nil {
+		klog.Errorf("cannot write a response via %q: %v", c.opts.Socket, err)
+	}
+}
+
+// sockServe authenticates the client of conn and reads its command, which is
+// sent to the event loop on requests.  Clients not allowed to use the control
+// socket are rejected before anything is read from them.  It runs in a worker,
+// so that slow clients do not block the event loop.
+func (c *Controller) sockServe(conn net.Conn, requests chan<- sockAccepted, stop <-chan struct{}) {
+	cred, err := sockPeerCred(conn)
+	if err == nil && !c.sockAllow.allowed(cred) {
+		err = fmt.Errorf("%s is not allowed to use the control socket", cred)
+	}
+	if err != nil {
+		klog.Warningf("rejecting control socket client: %v", err)
+		c.audit(auditActionSocketCommand, cred.String(), "", err)
+		if _, err := conn.Write([]byte(sockRejected + "\n")); err != nil {
+			klog.Errorf("cannot write a response via %q: %v", c.opts.Socket, err)
+		}
+		conn.Close()
+		return
+	}
+
+	// Do not delay the end of the changeWatcher() iteration by a slow client
+	read := make(chan struct{})
+	go func() {
+		select {
+		case <-stop:
+			conn.SetReadDeadline(time.Now())
+		case <-read:
+		}
+	}()
+	conn.SetReadDeadline(time.Now().Add(sockReadTimeout))
+	buf := make([]byte, sockCommandMax)
+	nr, err := conn.Read(buf)
+	close(read)
+	if err != nil && err != io.EOF {
+		klog.Warningf("failed to read a control socket command of %s: %v", cred, err)
+		conn.Close()
+		return
+	}
+	conn.SetReadDeadline(time.Time{})
+
+	select {
+	case requests <- sockAccepted{conn: conn, cred: cred, command: strings.TrimSpace(string(buf[0:nr]))}:
+	case <-stop:
+		conn.Close()
+	}
+}
+
+// sockHandle executes a single control socket command read by sockServe().
+// Returns true if openshift-tuned should terminate.
+func (c *Controller) sockHandle(s *sockAccepted, tuned *tunedState) bool {
+	command := s.command
+	c.audit(auditActionSocketCommand, s.cred.String(), command, nil)
+
+	switch command {
+	case "":
+		// Another instance checking whether we listen, see sockStaleRemove()
+
+	case "stop":
+		c.terminating("stop requested via the socket")
+		if err := c.tunedStop(s, true); err != nil {
+			klog.Errorf("%s", err.Error())
+		}
+		return true
+
+	case "stop-norollback":
+		c.terminating("stop without rollback requested via the socket")
+		if err := c.tunedStop(s, false); err != nil {
+			klog.Errorf("%s", err.Error())
+		}
+		return true
+
+	case "handoff":
+		if c.handoffGive(s) {
+			s.conn.Close()
+			return true
+		}
+
+	case "status":
+		c.sockWriteJSON(s, c.status.get())
+
+	case "recommended_profile":
+		c.sockWriteJSON(s, c.recommendedProfileGet(false))
+
+	case "recommended_profile explain":
+		c.sockWriteJSON(s, c.recommendedProfileGet(true))
+
+	case "profiles":
+		c.sockWriteJSON(s, c.profilesGet())
+
+	case "must-gather":
+		if err := c.mustGather(s.conn); err != nil {
+			klog.Errorf("%s", err.Error())
+		}
+
+	case "rollback":
+		response := "ok"
+		// Serialized with the extract and reload writing the same profiles
+		err := c.pipeline.do("rollback", func() error {
+			return c.snapshotRestore(tuned)
+		})
+		if err != nil {
+			klog.Errorf("%s", err.Error())
+			response = err.Error()
+		}
+		if _, err := s.conn.Write([]byte(response + "\n")); err != nil {
+			klog.Errorf("cannot write a response via %q: %v", c.opts.Socket, err)
+		}
+
+	case "apply-now":
+		// Execute a reload queued for the maintenance window on the next tick
+		tuned.applyNow = true
+		if _, err := s.conn.Write([]byte("ok\n")); err != nil {
+			klog.Errorf("cannot write a response via %q: %v", c.opts.Socket, err)
+		}
+
+	case "restore-pristine":
+		response := "ok"
+		if err := c.pristineRestore(); err != nil {
+			klog.Errorf("%s", err.Error())
+			response = err.Error()
+		}
+		if _, err := s.conn.Write([]byte(response + "\n")); err != nil {
+			klog.Errorf("cannot write a response via %q: %v", c.opts.Socket, err)
+		}
+
+	default:
+		klog.Warningf("unknown control socket command: %q", command)
+	}
+	s.conn.Close()
+
+	return false
+}
@@ -0,0 +1,139 @@
+package tuned
+
+import (
+	"errors"        // errors.New()
+	"fmt"           // Errorf()
+	"path/filepath" // filepath.Join()
+	"reflect"       // reflect.DeepEqual()
+	"time"          // time.Duration
+
+	"k8s.io/klog"
+
+	"github.com/openshift/openshift-tuned/pkg/profile"
+	"github.com/openshift/openshift-tuned/pkg/trace"
+)
+
+// Global variables
+var (
+	// errSourcesSettling is returned by profilesSync() if a local profile source
+	// changed while being read, e.g. during a ConfigMap volume update
+	errSourcesSettling = errors.New("tuned profile sources are being updated")
+)
+
+// Constants
+const (
+	sourcesCacheDir = "sources" // in RunDir
+)
+
+// Functions
+// sourcesInit sets up the tuned profile sources: the "rendered" Tuned object, the
+// tuned profiles ConfigMap file (unless disabled) and opts.ProfileSources.
+func (c *Controller) sourcesInit() error {
+	sources := []profile.Source{c.tunedSource}
+	if c.opts.SupportConfigMap {
+		// This is for backward-compatibility with older versions of NTO, it will be removed
+		sources = append(sources, &profile.ConfigMapSource{Path: c.opts.ProfilesConfigMap})
+	}
+	for _, spec := range c.opts.ProfileSources {
+		s, err := profile.NewSource(spec)
+		if err != nil {
+			return err
+		}
+		if hs, ok := s.(*profile.HTTPSource); ok {
+			hs.CacheDir = filepath.Join(c.opts.RunDir, sourcesCacheDir)
+		}
+		sources = append(sources, s)
+	}
+	profile.SortSources(sources)
+	c.sources = sources
+
+	return nil
+}
+
+// sourcesPollInterval returns the shortest polling interval of the remote profile
+// sources or 0 if there are none.
+func (c *Controller) sourcesPollInterval() time.Duration {
+	var interval time.Duration
+
+	for _, s := range c.sources {
+		if p, ok := s.(profile.Polled); ok && (interval == 0 || p.Interval() < interval) {
+			interval = p.Interval()
+		}
+	}
+	return interval
+}
+
+// sourcesPaths returns the local files/directories the profile sources read.
+func (c *Controller) sourcesPaths() []string {
+	var paths []string
+
+	for _, s := range c.sources {
+		if w, ok := s.(profile.Watched); ok {
+			paths = append(paths, w.Paths()...)
+		}
+	}
+	return paths
+}
+
+// profilesSync extracts the profiles of all sources.  Profiles of sources with
+// a higher priority override profiles of the same name from other sources.  The
+// extraction is traced as a child of parent, if set.
+func (c *Controller) profilesSync(parent *trace.Span) (err error) {
+	klog.Infof("extracting tuned profiles")
+	defer c.status.enter(stateExtracting)()
+
+	span := parent.Child("extract")
+	if parent == nil {
+		span = c.tracer.Start("extract")
+	}
+	defer func() {
+		span.SetError(err)
+		span.End()
+	}()
+
+	merged := map[string]string{}
+	origin := map[string]string{}
+	for _, s := range c.sources {
+		profiles, err := s.Profiles()
+		if err != nil {
+			return fmt.Errorf("profile source %s: %v", s.Name(), err)
+		}
+		if _, ok := s.(profile.Watched); ok {
+			// Read local sources again to catch a half-swapped ConfigMap volume
+			again, err := s.Profiles()
+			if err != nil || !reflect.DeepEqual(profiles, again) {
+				klog.V(1).Infof("profile source %s changed while being read", s.Name())
+				return errSourcesSettling
+			}
+		}
+		if profiles == nil {
+			// This is not an error, e.g. the ConfigMap file does not exist when
+			// running the latest NTO
+			continue
+		}
+		if err = profile.Verify(profiles, c.opts.ProfileSigningKey, c.opts.RequireSignedProfiles); err != nil {
+			return fmt.Errorf("refusing to extract tuned profiles from %s: %v", s.Name(), err)
+		}
+		for name, data := range profiles {
+			if profile.IsSignature(name) {
+				continue
+			}
+			if prev, ok := origin[name]; ok {
+				klog.V(1).Infof("tuned profile %q from %s overrides the one from %s", name, s.Name(), prev)
+			}
+			merged[name] = data
+			origin[name] = s.Name()
+		}
+	}
+	problems, err := profile.Validate(merged, c.store)
+	for _, p := range problems {
+		if p.Warning {
+			klog.Warningf("%s", p)
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("refusing to extract tuned profiles: %v", err)
+	}
+
+	return c.profilesWrite(merged, origin)
+}
@@ -0,0 +1,97 @@
+package tuned
+
+import (
+	"time" // time.Time
+
+	"k8s.io/klog"
+)
+
+// Types
+// daemonState is the state of the openshift-tuned daemon.
+type daemonState int
+
+// Constants
+const (
+	// starting up, tuned has not been started yet
+	stateInitializing daemonState = iota
+	// extracting tuned profiles
+	stateExtracting
+	// tuned was (re)loaded, waiting for it to apply the recommended profile
+	stateApplying
+	// tuned applied the requested profile
+	stateStable
+	// tuned failed to apply the requested profile or runs a fallback profile
+	stateDegraded
+	// restoring the last known-good configuration
+	stateRollingBack
+	// stopping tuned and rolling back the node-level tuning
+	stateTerminating
+)
+
+// Global variables
+var (
+	daemonStateNames = []string{
+		stateInitializing: "Initializing",
+		stateExtracting:   "Extracting",
+		stateApplying:     "Applying",
+		stateStable:       "Stable",
+		stateDegraded:     "Degraded",
+		stateRollingBack:  "RollingBack",
+		stateTerminating:  "Terminating",
+	}
+)
+
+// Functions
+func (st daemonState) String() string {
+	if st < 0 || int(st) >= len(daemonStateNames) {
+		return "Unknown"
+	}
+	return daemonStateNames[st]
+}
+
+// setState transitions the daemon to state st; reason describes why.
+func (s *daemonStatus) setState(st daemonState, reason string) {
+	s.Lock()
+	defer s.Unlock()
+
+	s.setStateLocked(st, reason)
+}
+
+func (s *daemonStatus) setStateLocked(st daemonState, reason string) {
+	if s.state == st && s.stateReason == reason {
+		return
+	}
+	if len(reason) > 0 {
+		klog.Infof("state %s -> %s: %s", s.state, st, reason)
+	} else {
+		klog.Infof("state %s -> %s", s.state, st)
+	}
+	if s.state != st {
+		s.stateSince = time.Now()
+		if s.stateTransitions == nil {
+			s.stateTransitions = map[daemonState]int{}
+		}
+		s.stateTransitions[st]++
+	}
+	s.state = st
+	s.stateReason = reason
+}
+
+// enter transitions the daemon to the transient state st and returns a function
+// which restores the previous state, unless the state changed in the meantime.
+func (s *daemonStatus) enter(st daemonState) func() {
+	s.Lock()
+	defer s.Unlock()
+
+	prev, prevReason := s.state, s.stateReason
+	s.setStateLocked(st, "")
+
+	return func() {
+		s.Lock()
+		defer s.Unlock()
+
+		if s.state == st {
+			s.setStateLocked(prev, prevReason)
+		}
+	}
+}
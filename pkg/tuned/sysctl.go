@@ -0,0 +1,250 @@
+package tuned
+
+import (
+	"bufio"         // bufio.NewScanner()
+	"fmt"           // Sprintf()
+	"io/ioutil"     // ioutil.ReadFile()
+	"os"            // os.Open()
+	"path/filepath" // filepath.Glob()
+	"sort"          // sort.Strings()
+	"strings"       // strings.Fields()
+
+	"gopkg.in/yaml.v2"
+	"k8s.io/klog"
+
+	"github.com/openshift/openshift-tuned/pkg/profile"
+)
+
+// Types
+// sysctlConflict is a sysctl set by the recommended profile which is also managed
+// by another agent on the node.
+type sysctlConflict struct {
+	Key          string `json:"key"`
+	ProfileValue string `json:"profileValue"`
+	CurrentValue string `json:"currentValue,omitempty"`
+	// the other agents managing the sysctl, e.g. a sysctl.d file
+	Writers []string `json:"writers"`
+	// false if the value of the other agents prevails
+	TunedOverrides bool `json:"tunedOverrides"`
+}
+
+// Global variables
+var (
+	// sysctl.d(5) directories in order of precedence; files of the same name in
+	// earlier directories override the later ones
+	sysctlDDirs = []string{"/etc/sysctl.d", "/run/sysctl.d", "/usr/local/lib/sysctl.d", "/usr/lib/sysctl.d", "/lib/sysctl.d"}
+	// namespaced sysctls the kubelet always allows pods to set
+	kubeletSafeSysctls = []string{
+		"kernel.shm_rmid_forced",
+		"net.ipv4.ip_local_port_range",
+		"net.ipv4.tcp_syncookies",
+		"net.ipv4.ping_group_range",
+		"net.ipv4.ip_unprivileged_port_start",
+	}
+)
+
+// Constants
+const (
+	procSys         = "/proc/sys"
+	sysctlConf      = "/etc/sysctl.conf"
+	tunedMainConf   = "tuned-main.conf" // in ProfilesDir
+	kubeletConfFile = "/etc/kubernetes/kubelet.conf"
+)
+
+// Functions
+// sysctlValueNormalize normalizes whitespace in sysctl value v.
+func sysctlValueNormalize(v string) string {
+	return strings.Join(strings.Fields(v), " ")
+}
+
+// sysctlRead returns the current value of sysctl key.
+func sysctlRead(key string) (string, error) {
+	data, err := ioutil.ReadFile(filepath.Join(procSys, strings.Replace(key, ".", "/", -1)))
+	if err != nil {
+		return "", err
+	}
+	return sysctlValueNormalize(string(data)), nil
+}
+
+// sysctlDSettings returns the sysctls set by the sysctl.d(5) configuration files:
+// key -> the file setting it last and the value set.
+func sysctlDSettings() map[string][2]string {
+	var files []string
+
+	settings := map[string][2]string{}
+	byName := map[string]string{}
+	for _, dir := range sysctlDDirs {
+		matches, _ := filepath.Glob(filepath.Join(dir, "*.conf"))
+		for _, match := range matches {
+			if _, ok := byName[filepath.Base(match)]; !ok {
+				byName[filepath.Base(match)] = match
+			}
+		}
+	}
+	for _, file := range byName {
+		files = append(files, file)
+	}
+	sort.Slice(files, func(i, j int) bool { return filepath.Base(files[i]) < filepath.Base(files[j]) })
+	files = append(files, sysctlConf)
+
+	for _, file := range files {
+		f, err := os.Open(file)
+		if err != nil {
+			continue
+		}
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if len(line) == 0 || line[0] == '#' || line[0] == ';' {
+				continue
+			}
+			i := strings.Index(line, "=")
+			if i < 0 {
+				continue
+			}
+			key := profile.SysctlKey(strings.TrimPrefix(strings.TrimSpace(line[:i]), "-"))
+			settings[key] = [2]string{file, sysctlValueNormalize(line[i+1:])}
+		}
+		f.Close()
+	}
+	return settings
+}
+
+// kubeletUnsafeSysctls returns the allowedUnsafeSysctls patterns of the kubelet
+// configuration; pods may set these in their own namespaces.
+func kubeletUnsafeSysctls() []string {
+	var config struct {
+		AllowedUnsafeSysctls []string `yaml:"allowedUnsafeSysctls"`
+	}
+
+	data, err := ioutil.ReadFile(kubeletConfFile)
+	if err != nil {
+		return nil
+	}
+	if err = yaml.Unmarshal(data, &config); err != nil {
+		klog.V(1).Infof("failed to parse %q: %v", kubeletConfFile, err)
+		return nil
+	}
+	return config.AllowedUnsafeSysctls
+}
+
+// sysctlPatternMatch returns true if sysctl key matches kubelet pattern, e.g. "net.core.*".
+func sysctlPatternMatch(pattern, key string) bool {
+	pattern = profile.SysctlKey(pattern)
+	if strings.HasSuffix(pattern, "*") {
+		return strings.HasPrefix(key, strings.TrimSuffix(pattern, "*"))
+	}
+	return pattern == key
+}
+
+// tunedReapplySysctl returns true if tuned re-applies the sysctl.d settings after
+// applying a profile (reapply_sysctl in tuned-main.conf, enabled by default).
+func (c *Controller) tunedReapplySysctl() bool {
+	data, err := ioutil.ReadFile(filepath.Join(c.opts.ProfilesDir, tunedMainConf))
+	if err != nil {
+		return true
+	}
+	for _, section := range profile.ParseSections("[main]\n" + string(data)) {
+		if v, ok := section.Options["reapply_sysctl"]; ok {
+			switch strings.ToLower(v) {
+			case "0", "false", "no", "off":
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// sysctlConflictsCheck returns the sysctls of tuned profile profileName (and the
+// profiles it includes) which are also managed by sysctl.d files or which pods may
+// set in their namespaces via the kubelet allowedUnsafeSysctls.
+func (c *Controller) sysctlConflictsCheck(profileName string) ([]sysctlConflict, error) {
+	var conflicts []sysctlConflict
+
+	chain, err := profile.ChainLoad(c.store, profileName)
+	if err != nil {
+		return nil, err
+	}
+	sysctls := profile.ChainSysctls(chain)
+	if len(sysctls) == 0 {
+		return nil, nil
+	}
+	sysctlD := sysctlDSettings()
+	unsafe := kubeletUnsafeSysctls()
+	reapply := c.tunedReapplySysctl()
+
+	keys := make([]string, 0, len(sysctls))
+	for key := range sysctls {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		value := sysctlValueNormalize(sysctls[key])
+		if strings.Contains(value, "${") {
+			// Variables are expanded by tuned, we cannot check these
+			continue
+		}
+		conflict := sysctlConflict{Key: key, ProfileValue: value, TunedOverrides: true}
+		if s, ok := sysctlD[key]; ok && s[1] != value {
+			conflict.Writers = append(conflict.Writers, fmt.Sprintf("%s (%s)", s[0], s[1]))
+			if reapply {
+				// tuned re-applies the sysctl.d settings after its own
+				conflict.TunedOverrides = false
+			}
+		}
+		for _, pattern := range kubeletSafeSysctls {
+			if pattern == key {
+				conflict.Writers = append(conflict.Writers, "pods via kubelet safe sysctls")
+			}
+		}
+		for _, pattern := range unsafe {
+			if sysctlPatternMatch(pattern, key) {
+				conflict.Writers = append(conflict.Writers, "pods via kubelet allowedUnsafeSysctls "+pattern)
+				break
+			}
+		}
+		if len(conflict.Writers) == 0 {
+			continue
+		}
+		conflict.CurrentValue, _ = sysctlRead(key)
+		conflicts = append(conflicts, conflict)
+	}
+
+	return conflicts, nil
+}
+
+// sysctlConflictsUpdate reports the sysctl conflicts of tuned profile profileName
+// via the log and the API.
+func (c *Controller) sysctlConflictsUpdate(profileName string) {
+	conflicts, err := c.sysctlConflictsCheck(profileName)
+	if err != nil {
+		klog.Errorf("failed to check the sysctls of profile %q for conflicts: %v", profileName, err)
+		return
+	}
+	for _, sc := range conflicts {
+		verdict := "tuned overrides it"
+		if !sc.TunedOverrides {
+			verdict = "tuned does not override it"
+		}
+		klog.Warningf("profile %q sets sysctl %s=%q (currently %q), also managed by %s; %s",
+			profileName, sc.Key, sc.ProfileValue, sc.CurrentValue, strings.Join(sc.Writers, ", "), verdict)
+	}
+	c.status.setSysctlConflicts(conflicts)
+}
+
+// sysctlApply writes sysctls directly, without reloading tuned.
+func (c *Controller) sysctlApply(sysctls map[string]string) error {
+	keys := make([]string, 0, len(sysctls))
+	for key := range sysctls {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		value := sysctlValueNormalize(sysctls[key])
+		klog.V(1).Infof("setting sysctl %s=%q", key, value)
+		if err := c.priv.WriteSetting(filepath.Join(procSys, strings.Replace(key, ".", "/", -1)), value); err != nil {
+			return fmt.Errorf("failed to set sysctl %s=%q: %v", key, value, err)
+		}
+	}
+	return nil
+}
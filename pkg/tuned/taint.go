@@ -0,0 +1,99 @@
+package tuned
+
+import (
+	"fmt"     // Errorf()
+	"strings" // strings.LastIndex()
+	"time"    // time.Since()
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/rest"
+)
+
+// Constants
+const (
+	// time the daemon must stay Degraded before the node is tainted, so that a
+	// profile switch in progress does not taint it
+	degradedTaintGrace = 10 * time.Second
+)
+
+// Functions
+// taintParse parses a taint "key[=value]:effect", see Options.DegradedTaint.
+func taintParse(s string) (*corev1.Taint, error) {
+	if len(s) == 0 {
+		return nil, nil
+	}
+	i := strings.LastIndex(s, ":")
+	if i <= 0 {
+		return nil, fmt.Errorf("invalid taint %q, expected key[=value]:effect", s)
+	}
+	t := &corev1.Taint{Effect: corev1.TaintEffect(s[i+1:])}
+	switch t.Effect {
+	case corev1.TaintEffectNoSchedule, corev1.TaintEffectPreferNoSchedule, corev1.TaintEffectNoExecute:
+	default:
+		return nil, fmt.Errorf("invalid effect %q of taint %q, expected %s, %s or %s", t.Effect, s,
+			corev1.TaintEffectNoSchedule, corev1.TaintEffectPreferNoSchedule, corev1.TaintEffectNoExecute)
+	}
+	kv := strings.SplitN(s[:i], "=", 2)
+	t.Key = kv[0]
+	if len(kv) == 2 {
+		t.Value = kv[1]
+	}
+	if len(t.Key) == 0 {
+		return nil, fmt.Errorf("invalid taint %q, the key is empty", s)
+	}
+	return t, nil
+}
+
+// degradedTaintWanted returns whether the node should carry opts.DegradedTaint:
+// true if the daemon has been Degraded for degradedTaintGrace, false once it is
+// Stable.  ok is false in the other states, which keep the taint as it is.
+func (c *Controller) degradedTaintWanted() (wanted bool, ok bool) {
+	c.status.RLock()
+	defer c.status.RUnlock()
+
+	switch c.status.state {
+	case stateDegraded:
+		return true, time.Since(c.status.stateSince) >= degradedTaintGrace
+	case stateStable:
+		return false, true
+	}
+	return false, false
+}
+
+// nodeTaintSet adds taint to node nodeName if present, or removes it.  Returns
+// true if the node was changed.
+func nodeTaintSet(client rest.Interface, nodeName string, taint corev1.Taint, present bool) (bool, error) {
+	var (
+		taints []corev1.Taint
+		found  bool
+	)
+
+	node := &corev1.Node{}
+	if err := client.Get().Resource("nodes").Name(nodeName).Do().Into(node); err != nil {
+		return false, fmt.Errorf("failed to get node %q: %v", nodeName, err)
+	}
+	for _, t := range node.Spec.Taints {
+		if t.Key == taint.Key && t.Effect == taint.Effect {
+			found = true
+			if !present {
+				continue
+			}
+		}
+		taints = append(taints, t)
+	}
+	if found == present {
+		return false, nil
+	}
+	if present {
+		now := metav1.Now()
+		taint.TimeAdded = &now
+		taints = append(taints, taint)
+	}
+	node.Spec.Taints = taints
+	// The update fails if the node changed since it was read; retried by the caller
+	if err := client.Put().Resource("nodes").Name(nodeName).Body(node).Do().Error(); err != nil {
+		return false, fmt.Errorf("failed to update the taints of node %q: %v", nodeName, err)
+	}
+	return true, nil
+}
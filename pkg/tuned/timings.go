@@ -0,0 +1,99 @@
+package tuned
+
+import (
+	"bytes" // bytes.Buffer
+	"sort"  // sort.Strings()
+	"sync"  // sync.Mutex
+	"time"  // time.Duration
+
+	"github.com/openshift/openshift-tuned/pkg/metrics"
+)
+
+// Types
+// stageTimings records the durations of the reconcile stages, i.e. of the spans
+// of the reconcile traces: "extract", "recommend", "hash", "sysctl", "reload",
+// "apply" (until tuned reports the profile applied) and "reconcile" itself.
+type stageTimings struct {
+	sync.Mutex
+	histograms map[string]*metrics.Histogram
+	stages     map[string]*stageTiming
+}
+
+// stageTiming is the timing of a reconcile stage served by the /timings API.
+type stageTiming struct {
+	Stage       string    `json:"stage"`
+	Count       uint64    `json:"count"`
+	LastSeconds float64   `json:"lastSeconds"`
+	MeanSeconds float64   `json:"meanSeconds"`
+	MaxSeconds  float64   `json:"maxSeconds"`
+	Last        time.Time `json:"last"`
+}
+
+// Functions
+// observe records duration d of stage name.
+func (t *stageTimings) observe(name string, d time.Duration) {
+	t.Lock()
+	defer t.Unlock()
+
+	if t.histograms == nil {
+		t.histograms = map[string]*metrics.Histogram{}
+		t.stages = map[string]*stageTiming{}
+	}
+	h, ok := t.histograms[name]
+	if !ok {
+		h = metrics.NewHistogram(metrics.DurationBounds)
+		t.histograms[name] = h
+		t.stages[name] = &stageTiming{Stage: name}
+	}
+	seconds := d.Seconds()
+	h.Observe(seconds)
+
+	st := t.stages[name]
+	st.Count = h.Count
+	st.LastSeconds = seconds
+	st.MeanSeconds = h.Sum / float64(h.Count)
+	if seconds > st.MaxSeconds {
+		st.MaxSeconds = seconds
+	}
+	st.Last = time.Now()
+}
+
+// names returns the names of the stages observed so far, sorted.
+func (t *stageTimings) names() []string {
+	var names []string
+	for name := range t.stages {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// timingsGet returns the timings of the reconcile stages served by the /timings API.
+func (c *Controller) timingsGet() []stageTiming {
+	t := &c.timings
+	t.Lock()
+	defer t.Unlock()
+
+	timings := []stageTiming{}
+	for _, name := range t.names() {
+		timings = append(timings, *t.stages[name])
+	}
+	return timings
+}
+
+// timingMetricsCollect writes the reconcile stage duration metrics.
+func (c *Controller) timingMetricsCollect(buf *bytes.Buffer) {
+	var samples []metrics.HistogramSample
+
+	t := &c.timings
+	t.Lock()
+	for _, name := range t.names() {
+		samples = append(samples, metrics.HistogramSample{
+			Labels:    map[string]string{"stage": name},
+			Histogram: t.histograms[name].Copy(),
+		})
+	}
+	t.Unlock()
+
+	metrics.WriteHistogram(buf, "stage_duration_seconds", "Duration of the stages of reconciling the tuned profile.", samples...)
+}
@@ -0,0 +1,94 @@
+package tuned
+
+import (
+	"bytes"         // bytes.Buffer
+	"path/filepath" // filepath.Join()
+	"runtime"       // runtime.Version()
+
+	"k8s.io/klog"
+
+	"github.com/openshift/openshift-tuned/pkg/metrics"
+	"github.com/openshift/openshift-tuned/pkg/process"
+)
+
+// Types
+// versionResponse is the response of the /version API.
+type versionResponse struct {
+	Version       string           `json:"version"`
+	GitCommit     string           `json:"gitCommit,omitempty"`
+	GoVersion     string           `json:"goVersion"`
+	TunedVersion  string           `json:"tunedVersion,omitempty"`
+	TunedFeatures process.Features `json:"tunedFeatures"`
+	// effective option values after applying the configuration file
+	Flags map[string]string `json:"flags,omitempty"`
+	// states of the feature gates, see opts.FeatureGates
+	FeatureGates map[string]bool `json:"featureGates,omitempty"`
+}
+
+// Constants
+const (
+	// tuned profile selection mode file, see process.Features.ProfileMode
+	profileModeFile = "profile_mode"
+)
+
+// Functions
+// tunedVersionDetect detects the version of tuned and adapts the way tuned is run
+// to it.  If the version cannot be detected, the latest tuned is assumed.
+func (c *Controller) tunedVersionDetect() error {
+	if c.opts.MockTuned {
+		c.tunedFeatures = process.FeaturesFor(nil)
+		return nil
+	}
+
+	v, err := process.TunedVersion(c.opts.ExecTimeout)
+	c.tunedVersion = v
+	c.tunedFeatures = process.FeaturesFor(v)
+	if r, ok := c.runner.Unwrap().(*process.ExecRunner); ok {
+		r.Features = c.tunedFeatures
+	}
+	if err != nil {
+		return err
+	}
+	klog.Infof("detected tuned %s, features: %+v", v, c.tunedFeatures)
+	return nil
+}
+
+// tunedProfileModeWrite makes tuned select the recommended profile.  tuned releases
+// with profile modes stick to the last manually selected profile otherwise.
+func (c *Controller) tunedProfileModeWrite() error {
+	if !c.tunedFeatures.ProfileMode {
+		return nil
+	}
+	return c.priv.WriteFile(filepath.Join(c.opts.ProfilesDir, profileModeFile), []byte("auto\n"))
+}
+
+func (c *Controller) versionGet() versionResponse {
+	r := versionResponse{
+		Version:       c.opts.Version,
+		GitCommit:     c.opts.GitCommit,
+		GoVersion:     runtime.Version(),
+		TunedFeatures: c.tunedFeatures,
+		Flags:         c.opts.Flags,
+	}
+	if c.features != nil {
+		r.FeatureGates = c.features.Map()
+	}
+	if c.tunedVersion != nil {
+		r.TunedVersion = c.tunedVersion.String()
+	}
+	return r
+}
+
+// buildInfoMetricsCollect writes the build info metric.
+func (c *Controller) buildInfoMetricsCollect(buf *bytes.Buffer) {
+	v := c.versionGet()
+	metrics.Write(buf, "build_info", "gauge", "Build information of the daemon and the tuned version it runs, always 1.", metrics.Sample{
+		Labels: map[string]string{
+			"version":       v.Version,
+			"git_commit":    v.GitCommit,
+			"go_version":    v.GoVersion,
+			"tuned_version": v.TunedVersion,
+		},
+		Value: 1,
+	})
+}
@@ -0,0 +1,190 @@
+package tuned
+
+import (
+	"fmt"           // Errorf()
+	"os"            // os.Stat()
+	"path/filepath" // filepath.Clean()
+	"strings"       // strings.SplitN()
+	"time"          // time.Now()
+
+	"github.com/fsnotify/fsnotify"
+	"k8s.io/klog"
+
+	"github.com/openshift/openshift-tuned/pkg/hooks"
+	"github.com/openshift/openshift-tuned/pkg/profile"
+)
+
+// Types
+// watchFile is a file/directory watched for changes and the action taken when
+// it changes.
+type watchFile struct {
+	path   string
+	action string
+	// for watchActionHook
+	hook *hooks.Hook
+}
+
+// Constants
+// Actions taken when a watched file changes, see watchFilesParse().
+const (
+	// re-extract the tuned profiles; only removals trigger it, as a ConfigMap
+	// volume update removes the old data
+	watchActionExtract = "extract"
+	// reload tuned even if the profile content did not change
+	watchActionReload = "reload"
+	// reload tuned if the active profile includes the changed variables file
+	watchActionVariables = "variables"
+	// run a hook, "hook:<hook spec>"
+	watchActionHook = "hook"
+)
+
+// Functions
+// watchFilesParse parses the watched files "path[=action]"; the default action
+// is watchActionExtract.
+func watchFilesParse(specs []string) ([]watchFile, error) {
+	var watches []watchFile
+
+	for _, spec := range specs {
+		parts := strings.SplitN(spec, "=", 2)
+		w := watchFile{path: filepath.Clean(parts[0]), action: watchActionExtract}
+		if len(parts) == 2 {
+			w.action = parts[1]
+		}
+		switch {
+		case w.action == watchActionExtract, w.action == watchActionReload, w.action == watchActionVariables:
+		case strings.HasPrefix(w.action, watchActionHook+":"):
+			h, err := hooks.New(strings.TrimPrefix(w.action, watchActionHook+":"))
+			if err != nil {
+				return nil, fmt.Errorf("invalid action of watched file %q: %v", w.path, err)
+			}
+			w.action = watchActionHook
+			w.hook = h
+		default:
+			return nil, fmt.Errorf("unknown action %q of watched file %q, expected %s, %s, %s or %s:<hook>",
+				w.action, w.path, watchActionExtract, watchActionReload, watchActionVariables, watchActionHook)
+		}
+		watches = append(watches, w)
+	}
+	return watches, nil
+}
+
+// watchAdd watches path and, if it is a directory, all directories below it.
+func watchAdd(wFs *fsnotify.Watcher, path string) error {
+	if err := wFs.Add(path); err != nil {
+		return err
+	}
+	fi, err := os.Stat(path)
+	if err != nil || !fi.IsDir() {
+		return nil
+	}
+	return filepath.Walk(path, func(p string, fi os.FileInfo, err error) error {
+		if err != nil {
+			// Removed while walking
+			return nil
+		}
+		if fi.IsDir() && p != path {
+			if err := wFs.Add(p); err != nil {
+				klog.V(1).Infof("not watching directory %q: %v", p, err)
+			}
+		}
+		return nil
+	})
+}
+
+// watchDirEvent keeps the recursive watches up to date: directories created in
+// a watched directory are watched, removed ones are forgotten.
+func watchDirEvent(wFs *fsnotify.Watcher, ev fsnotify.Event) {
+	if ev.Op&fsnotify.Create == fsnotify.Create {
+		if fi, err := os.Lstat(ev.Name); err == nil && fi.IsDir() {
+			klog.V(2).Infof("watching new directory %q", ev.Name)
+			if err := watchAdd(wFs, ev.Name); err != nil {
+				klog.V(1).Infof("not watching directory %q: %v", ev.Name, err)
+			}
+		}
+	}
+	if ev.Op&(fsnotify.Remove|fsnotify.Rename) != 0 {
+		// Fails for files and directories not watched
+		wFs.Remove(ev.Name)
+	}
+}
+
+// watchMatch returns the watched file event ev is about: ev.Name itself or the
+// watched directory holding it, possibly in a subdirectory.
+func (c *Controller) watchMatch(ev fsnotify.Event) *watchFile {
+	name := filepath.Clean(ev.Name)
+	for i := range c.watches {
+		w := &c.watches[i]
+		if name == w.path || strings.HasPrefix(name, w.path+string(filepath.Separator)) {
+			return w
+		}
+	}
+	return nil
+}
+
+// profileIncludesVariables returns true if tuned profile profileName (or a profile
+// it includes) reads variables from file path.
+func (c *Controller) profileIncludesVariables(profileName string, path string) bool {
+	chain, err := profile.ChainLoad(c.store, profileName)
+	if err != nil {
+		// Cannot tell, assume it does
+		return true
+	}
+	for _, conf := range chain {
+		if include, ok := conf["variables"]["include"]; ok && filepath.Clean(strings.TrimSpace(include)) == path {
+			return true
+		}
+	}
+	return false
+}
+
+// watchEvent takes the action of the watched file event ev is about.
+func (c *Controller) watchEvent(tuned *tunedState, ev fsnotify.Event) {
+	tuned.lastFsEvent = time.Now()
+	w := c.watchMatch(ev)
+	if w == nil || w.action == watchActionExtract {
+		// Profile sources and watched files without an explicit action.  Ignore
+		// Write and Create events, wait for the removal of the old ConfigMap to
+		// trigger reload
+		if ev.Op&fsnotify.Remove == fsnotify.Remove {
+			klog.V(1).Infof("remove event on: %s", ev.Name)
+			tuned.change.cfg = true
+		}
+		return
+	}
+	if ev.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Remove|fsnotify.Rename) == 0 {
+		return
+	}
+	klog.V(1).Infof("%s event on %s, action %s", ev.Op, ev.Name, w.action)
+
+	switch w.action {
+	case watchActionReload:
+		tuned.change.force = true
+
+	case watchActionVariables:
+		active, err := c.store.ActiveProfile()
+		if err != nil || c.profileIncludesVariables(active, w.path) {
+			tuned.change.force = true
+			return
+		}
+		klog.V(1).Infof("active profile %q does not include variables file %q, not reloading tuned", active, w.path)
+
+	case watchActionHook:
+		// Run once the burst of events settled, see watchHooksFlush()
+		for _, h := range tuned.watchHooks {
+			if h == w.hook {
+				return
+			}
+		}
+		tuned.watchHooks = append(tuned.watchHooks, w.hook)
+	}
+}
+
+// watchHooksFlush queues the hooks of the changed watched files to run once
+// there were no filesystem events for Options.WatchQuiescence.
+func (c *Controller) watchHooksFlush(tuned *tunedState) {
+	if len(tuned.watchHooks) == 0 || time.Since(tuned.lastFsEvent) < c.opts.WatchQuiescence {
+		return
+	}
+	c.hooksQueueWatched(tuned.watchHooks)
+	tuned.watchHooks = nil
+}
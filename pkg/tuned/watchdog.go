@@ -0,0 +1,124 @@
+package tuned
+
+import (
+	"fmt"         // Errorf()
+	"net/http"    // http.ResponseWriter
+	"runtime"     // runtime.Stack()
+	"sync/atomic" // atomic.LoadInt64()
+	"time"        // time.Now()
+
+	"k8s.io/klog"
+
+	"github.com/openshift/openshift-tuned/pkg/systemd"
+)
+
+// Types
+// watchdog detects a stuck event loop, e.g. blocked on a hung exec or file write.
+type watchdog struct {
+	// time of the last event loop tick in UnixNano; 0 if the event loop is not running
+	lastTick int64
+	// 1 if the event loop is stuck
+	stalled int32
+}
+
+// Constants
+const (
+	goroutineDumpMax = 1 << 20
+)
+
+// Functions
+// tick records that the event loop is alive.
+func (w *watchdog) tick() {
+	atomic.StoreInt64(&w.lastTick, time.Now().UnixNano())
+}
+
+// disarm stops watching the event loop, e.g. while it is not running between retries.
+func (w *watchdog) disarm() {
+	atomic.StoreInt64(&w.lastTick, 0)
+	atomic.StoreInt32(&w.stalled, 0)
+}
+
+// check returns an error if the event loop has not ticked within timeout.
+func (w *watchdog) check(timeout time.Duration) error {
+	last := atomic.LoadInt64(&w.lastTick)
+	if last == 0 {
+		return nil
+	}
+	if since := time.Since(time.Unix(0, last)); since > timeout {
+		return fmt.Errorf("event loop has not ticked for %v", since.Round(time.Second))
+	}
+	return nil
+}
+
+// goroutineDump logs the stacks of all goroutines.
+func goroutineDump() {
+	buf := make([]byte, goroutineDumpMax)
+	n := runtime.Stack(buf, true)
+	klog.Errorf("goroutine dump:\n%s", buf[:n])
+}
+
+// watchdogRun checks the event loop until stop is closed.  A stuck event loop is
+// reported once with a goroutine dump and fails the /healthz endpoint until it
+// recovers.
+func (c *Controller) watchdogRun(stop <-chan struct{}) {
+	ticker := time.NewTicker(c.opts.WatchdogTimeout / 2)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+		err := c.watchdog.check(c.opts.WatchdogTimeout)
+		if err == nil {
+			if atomic.CompareAndSwapInt32(&c.watchdog.stalled, 1, 0) {
+				klog.Infof("watchdog: event loop recovered")
+			}
+			continue
+		}
+		if atomic.CompareAndSwapInt32(&c.watchdog.stalled, 0, 1) {
+			klog.Errorf("watchdog: %v", err)
+			goroutineDump()
+		}
+	}
+}
+
+// sdWatchdogRun pings the systemd watchdog every interval until stop is closed,
+// as long as the event loop is not stuck.
+func (c *Controller) sdWatchdogRun(interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+		if c.opts.WatchdogTimeout > 0 && c.watchdog.check(c.opts.WatchdogTimeout) != nil {
+			// Let systemd restart us
+			continue
+		}
+		if _, err := systemd.Notify(systemd.Watchdog); err != nil {
+			klog.Errorf("%s", err.Error())
+		}
+	}
+}
+
+// sdNotify notifies systemd of state when running as a systemd service.
+func sdNotify(state string) {
+	if _, err := systemd.Notify(state); err != nil {
+		klog.Errorf("%s", err.Error())
+	}
+}
+
+func (c *Controller) apiHealthzHandler(w http.ResponseWriter, r *http.Request) {
+	if c.opts.WatchdogTimeout > 0 {
+		if err := c.watchdog.check(c.opts.WatchdogTimeout); err != nil {
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+	}
+	fmt.Fprintf(w, "ok\n")
+}
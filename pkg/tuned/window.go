@@ -0,0 +1,75 @@
+package tuned
+
+import (
+	"time" // time.Now()
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/klog"
+
+	"github.com/openshift/openshift-tuned/pkg/schedule"
+)
+
+// Constants
+const (
+	// node annotation overriding Options.MaintenanceWindow
+	nodeAnnotationMaintenanceWindow = "tuned.openshift.io/maintenance-window"
+)
+
+// Functions
+// windowUpdate sets the maintenance window from the annotation of node.
+func (c *Controller) windowUpdate(tuned *tunedState, node *corev1.Node) {
+	spec := node.Annotations[nodeAnnotationMaintenanceWindow]
+	if tuned.nodeWindow != nil && tuned.nodeWindow.String() == spec {
+		return
+	}
+	if len(spec) == 0 {
+		if tuned.nodeWindow != nil {
+			klog.Infof("node maintenance window removed")
+			tuned.nodeWindow = nil
+			c.status.setMaintenanceWindow(c.window)
+		}
+		return
+	}
+	w, err := schedule.Parse(spec)
+	if err != nil {
+		klog.Errorf("ignoring the %s annotation: %v", nodeAnnotationMaintenanceWindow, err)
+		return
+	}
+	klog.Infof("node maintenance window: %s", spec)
+	tuned.nodeWindow = w
+	c.status.setMaintenanceWindow(w)
+}
+
+// windowCurrent returns the effective maintenance window, nil if there is none.
+func (c *Controller) windowCurrent(tuned *tunedState) *schedule.Window {
+	if tuned.nodeWindow != nil {
+		return tuned.nodeWindow
+	}
+	return c.window
+}
+
+// windowOpen returns true if disruptive reloads may be executed now, i.e. there
+// is no maintenance window, the window is open or an immediate apply was
+// requested via the control socket.
+func (c *Controller) windowOpen(tuned *tunedState) bool {
+	w := c.windowCurrent(tuned)
+	return w == nil || tuned.applyNow || w.Contains(time.Now())
+}
+
+// windowQueue queues a disruptive reload until the maintenance window opens.
+func (c *Controller) windowQueue(tuned *tunedState, profileName string, force bool) {
+	tuned.change.queued = true
+	tuned.change.force = tuned.change.force || force
+	if c.status.reloadQueued() {
+		return
+	}
+	next := c.windowCurrent(tuned).Next(time.Now())
+	klog.Infof("queueing the reload of profile %q until the maintenance window opens at %v", profileName, next)
+	c.status.setReloadQueued(true)
+}
+
+// windowDequeue drops the queued reload, e.g. as it is executed.
+func (c *Controller) windowDequeue(tuned *tunedState) {
+	tuned.applyNow = false
+	c.status.setReloadQueued(false)
+}
@@ -0,0 +1,35 @@
+package tuned
+
+import (
+	"sync" // sync.WaitGroup
+)
+
+// Types
+// workers are the goroutines started by a changeWatcher() iteration, e.g. the
+// informers and the control socket accept loop.  They run until stop is closed;
+// stopWait() makes sure none of them outlives the iteration, so that a retry
+// does not pile up goroutines.
+type workers struct {
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+// Functions
+func newWorkers() *workers {
+	return &workers{stop: make(chan struct{})}
+}
+
+// run runs f in a goroutine; f must return once stop is closed.
+func (w *workers) run(f func(stop <-chan struct{})) {
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+		f(w.stop)
+	}()
+}
+
+// stopWait closes stop and waits for the goroutines to return.
+func (w *workers) stopWait() {
+	close(w.stop)
+	w.wg.Wait()
+}
@@ -0,0 +1,124 @@
+package tuned
+
+import (
+	"context"       // context.WithCancel()
+	"io/ioutil"     // ioutil.WriteFile()
+	"os"            // os.MkdirAll(), os.RemoveAll()
+	"path/filepath" // filepath.Join()
+	"runtime"       // runtime.NumGoroutine()
+	"testing"
+	"time" // time.Now()
+)
+
+// testController returns a standalone Controller running the mock tuned in dir
+// with a recommend.d rule selecting the system profile "test".
+func testController(t *testing.T, dir string) *Controller {
+	opts := DefaultOptions()
+	opts.NodeName = "test"
+	opts.MockTuned = true
+	opts.Standalone = true
+	opts.ProfilesDir = filepath.Join(dir, "etc")
+	opts.SystemProfilesDir = filepath.Join(dir, "sys")
+	opts.ActiveProfileFile = filepath.Join(opts.ProfilesDir, "active_profile")
+	opts.RunDir = filepath.Join(dir, "run")
+	opts.Socket = filepath.Join(opts.RunDir, "openshift-tuned.sock")
+	opts.SupportConfigMap = false
+	opts.OperandConfigMap = ""
+	opts.AuditLog = ""
+	opts.WatchdogTimeout = 0
+	// Do not touch the host
+	opts.RealtimeGating = false
+	opts.RetryInitial = time.Second
+	opts.RetryMax = time.Second
+
+	for _, d := range []string{opts.ProfilesDir, filepath.Join(opts.SystemProfilesDir, "recommend.d"), filepath.Join(opts.SystemProfilesDir, "test"), opts.RunDir} {
+		if err := os.MkdirAll(d, 0700); err != nil {
+			t.Fatal(err)
+		}
+	}
+	files := map[string]string{
+		filepath.Join(opts.SystemProfilesDir, "recommend.d", "50-test.conf"): "[test]\n",
+		filepath.Join(opts.SystemProfilesDir, "test", "tuned.conf"):          "[main]\nsummary=test\n",
+	}
+	for path, data := range files {
+		if err := ioutil.WriteFile(path, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return New(opts)
+}
+
+// waitRetried waits for retryLoop() to have counted retries errors and to run
+// tuned again.
+func waitRetried(t *testing.T, c *Controller, retries int) {
+	deadline := time.Now().Add(30 * time.Second)
+	for time.Now().Before(deadline) {
+		c.status.RLock()
+		errs := 0
+		for _, n := range c.status.errorCounts {
+			errs += n
+		}
+		c.status.RUnlock()
+		if errs == retries && c.runner.Pid() != 0 {
+			return
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	t.Fatalf("tuned not running after %d retries", retries)
+}
+
+// goroutinesSettle returns the number of goroutines once it stops decreasing.
+func goroutinesSettle() int {
+	n := runtime.NumGoroutine()
+	for i := 0; i < 20; i++ {
+		time.Sleep(100 * time.Millisecond)
+		m := runtime.NumGoroutine()
+		if m >= n {
+			return m
+		}
+		n = m
+	}
+	return n
+}
+
+func TestChangeWatcherRetryGoroutines(t *testing.T) {
+	const (
+		retries = 5
+		// goroutines which may come and go independently of the iterations,
+		// e.g. timers and the mock tuned
+		tolerance = 3
+	)
+	dir, err := ioutil.TempDir("", "workers")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	c := testController(t, dir)
+	ctx, cancel := context.WithCancel(context.Background())
+	runErr := make(chan error, 1)
+	go func() {
+		runErr <- c.Run(ctx)
+	}()
+	defer func() {
+		cancel()
+		select {
+		case <-runErr:
+		case <-time.After(30 * time.Second):
+			t.Errorf("Run() did not return")
+		}
+	}()
+
+	waitRetried(t, c, 0)
+	baseline := goroutinesSettle()
+
+	for i := 1; i <= retries; i++ {
+		// Make changeWatcher() return an error and retryLoop() start another iteration
+		c.tunedExit <- true
+		waitRetried(t, c, i)
+	}
+
+	if n := goroutinesSettle(); n > baseline+tolerance {
+		buf := make([]byte, 1<<20)
+		t.Errorf("goroutines after %d retries = %d, baseline %d\n%s", retries, n, baseline, buf[:runtime.Stack(buf, true)])
+	}
+}